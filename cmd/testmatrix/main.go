@@ -0,0 +1,205 @@
+// Command testmatrix runs every scenario of the integration tests in tests/
+// against every Alpine version and user mode. A scenario is a top-level test.
+// Each combination is a cell that runs go test on its own, several at a time,
+// and a failing cell does not stop the others. The images of each version are
+// built once before any of its cells start.
+//
+// Results are written to the output directory as JUnit XML (junit.xml), a
+// JSON summary (summary.json) and the raw go test output of every cell, and
+// a pass/fail grid with a row per scenario is printed at the end:
+//
+//	go run ./cmd/testmatrix -versions 3.21,latest -user-modes system -parallel 2
+//
+// The command exits with status 1 if any cell failed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	versions := flag.String("versions", "3.19,3.20,3.21,latest", "comma-separated Alpine versions")
+	userModes := flag.String("user-modes", "system,virtual", "comma-separated user modes")
+	parallel := flag.Int("parallel", 2, "number of cells run at the same time")
+	run := flag.String("run", "", "only run tests matching this go test -run pattern")
+	dir := flag.String("dir", "tests", "directory of the tests module")
+	out := flag.String("out", "test-results", "directory the reports and logs are written to")
+	timeout := flag.Duration("timeout", 30*time.Minute, "go test timeout for each cell")
+	keepImages := flag.Bool("keep-images", false, "keep the test images after the run so later runs reuse them")
+	flag.Parse()
+
+	if len(split(*versions)) == 0 || len(split(*userModes)) == 0 || *parallel < 1 {
+		fmt.Fprintln(os.Stderr, "testmatrix: at least one version, one user mode and -parallel 1 are required")
+		os.Exit(2)
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "testmatrix: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r := runner{dir: *dir, run: *run, timeout: *timeout, logDir: *out}
+	summary := Summary{Started: time.Now()}
+	scenarios, err := r.Scenarios(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testmatrix: %v\n", err)
+		os.Exit(2)
+	}
+	if len(scenarios) == 0 {
+		fmt.Fprintf(os.Stderr, "testmatrix: no tests match %q\n", *run)
+		os.Exit(2)
+	}
+	summary.Scenarios = scenarios
+
+	buildErrs, built := buildVersions(ctx, r, split(*versions), *parallel)
+	cells := matrix(split(*versions), split(*userModes), scenarios)
+	summary.Cells = runCells(ctx, r, cells, buildErrs, *parallel)
+	summary.Elapsed = time.Since(summary.Started).Seconds()
+	summary.Passed = true
+	for _, result := range summary.Cells {
+		if result.Failed() {
+			summary.Passed = false
+		}
+	}
+
+	status := 0
+	if !summary.Passed {
+		status = 1
+	}
+	if !*keepImages {
+		if err := removeImages(built); err != nil {
+			fmt.Fprintf(os.Stderr, "testmatrix: %v\n", err)
+		}
+	}
+	if err := writeJUnit(filepath.Join(*out, "junit.xml"), summary); err != nil {
+		fmt.Fprintf(os.Stderr, "testmatrix: write JUnit report: %v\n", err)
+		status = 1
+	}
+	if err := writeJSON(filepath.Join(*out, "summary.json"), summary); err != nil {
+		fmt.Fprintf(os.Stderr, "testmatrix: write summary: %v\n", err)
+		status = 1
+	}
+
+	fmt.Println()
+	printGrid(os.Stdout, summary)
+	os.Exit(status)
+}
+
+// buildVersions builds the images of every version, at most parallel at
+// once. It returns the build error of each version that failed and the tags
+// of every image that was built.
+func buildVersions(ctx context.Context, r runner, versions []string, parallel int) (map[string]error, []string) {
+	errs := map[string]error{}
+	var built []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallel)
+
+	for _, version := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			fmt.Printf("▶ alpine-%s images\n", version)
+			tags, err := r.Build(ctx, version)
+
+			mu.Lock()
+			defer mu.Unlock()
+			built = append(built, tags...)
+			if err != nil {
+				errs[version] = err
+				fmt.Printf("✘ alpine-%s images: %v\n", version, err)
+				return
+			}
+			fmt.Printf("✔ alpine-%s images\n", version)
+		}()
+	}
+	wg.Wait()
+	return errs, built
+}
+
+// runCells runs the cells in order with at most parallel running at once
+// and returns their results in the same order. Cells of a version whose
+// images failed to build fail without running.
+func runCells(ctx context.Context, r runner, cells []Cell, buildErrs map[string]error, parallel int) []CellResult {
+	results := make([]CellResult, len(cells))
+	next := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < parallel; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := buildErrs[cells[i].AlpineVersion]; err != nil {
+					results[i] = CellResult{Cell: cells[i], Status: statusFail, Started: time.Now(), Error: err.Error()}
+					continue
+				}
+
+				fmt.Printf("▶ %s\n", cells[i].Name())
+				results[i] = r.Run(ctx, cells[i])
+
+				mark := "✔"
+				if results[i].Failed() {
+					mark = "✘"
+				}
+				fmt.Printf("%s %s (%s)\n", mark, cells[i].Name(), time.Duration(results[i].Elapsed*float64(time.Second)).Round(time.Second))
+			}
+		}()
+	}
+	for i := range cells {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// matrix returns every combination of version, user mode and scenario
+func matrix(versions, userModes, scenarios []string) []Cell {
+	var cells []Cell
+	for _, version := range versions {
+		for _, userMode := range userModes {
+			for _, scenario := range scenarios {
+				cells = append(cells, Cell{AlpineVersion: version, UserMode: userMode, Scenario: scenario})
+			}
+		}
+	}
+	return cells
+}
+
+// split parses a comma-separated flag, ignoring empty entries
+func split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// removeImages removes the images built by this run. Images that already
+// existed, such as those kept by an earlier run, are left alone.
+func removeImages(tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if output, err := exec.Command("docker", append([]string{"rmi", "-f"}, tags...)...).CombinedOutput(); err != nil {
+		return fmt.Errorf("remove test images: %w\n%s", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Summary is written as JSON after the run
+type Summary struct {
+	Passed    bool         `json:"passed"`
+	Started   time.Time    `json:"started"`
+	Elapsed   float64      `json:"elapsed_seconds"`
+	Scenarios []string     `json:"scenarios"` // Top-level tests, the rows of the grid
+	Cells     []CellResult `json:"cells"`
+}
+
+// writeJSON writes the summary to path
+func writeJSON(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// junitTestSuites is the root of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the tests of one version and user mode
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       float64         `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+// junitProperty is a name-value pair attached to a suite
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is one test or subtest
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+// junitMessage is the body of a failure or skip
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// columns groups the results by version and user mode, in order
+func columns(results []CellResult) [][]CellResult {
+	var groups [][]CellResult
+	index := map[string]int{}
+	for _, result := range results {
+		i, ok := index[result.Column()]
+		if !ok {
+			i = len(groups)
+			index[result.Column()] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], result)
+	}
+	return groups
+}
+
+// writeJUnit writes one test suite per version and user mode to path
+func writeJUnit(path string, summary Summary) error {
+	report := junitTestSuites{Time: summary.Elapsed}
+
+	for _, column := range columns(summary.Cells) {
+		first := column[0]
+		suite := junitTestSuite{
+			Name:      first.Column(),
+			Timestamp: first.Started.Format("2006-01-02T15:04:05"),
+			Properties: []junitProperty{
+				{Name: "alpine_version", Value: first.AlpineVersion},
+				{Name: "user_mode", Value: first.UserMode},
+			},
+		}
+
+		for _, result := range column {
+			suite.Time += result.Elapsed
+			for _, test := range result.Tests {
+				testCase := junitTestCase{ClassName: result.Column(), Name: test.Name, Time: test.Elapsed}
+				switch test.Status {
+				case statusFail:
+					testCase.Failure = &junitMessage{Message: "Failed", Text: test.Output}
+					suite.Failures++
+				case statusSkip:
+					testCase.Skipped = &junitMessage{Message: "Skipped", Text: test.Output}
+					suite.Skipped++
+				}
+				suite.TestCases = append(suite.TestCases, testCase)
+			}
+
+			// Failures outside of any test get a test case so they show up
+			if result.Error != "" {
+				suite.TestCases = append(suite.TestCases, junitTestCase{
+					ClassName: result.Column(),
+					Name:      result.Scenario + " (go test)",
+					Failure:   &junitMessage{Message: "go test failed", Text: result.Error},
+				})
+				suite.Failures++
+			}
+		}
+
+		suite.Tests = len(suite.TestCases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+// printGrid prints a row per scenario and a column per version and user mode
+func printGrid(w io.Writer, summary Summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	groups := columns(summary.Cells)
+
+	header := []string{"SCENARIO"}
+	for _, column := range groups {
+		header = append(header, strings.TrimPrefix(column[0].Column(), "alpine-"))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, scenario := range summary.Scenarios {
+		row := []string{scenario}
+		for _, column := range groups {
+			row = append(row, scenarioStatus(column, scenario))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	totals := []string{"RESULT"}
+	passed := 0
+	for _, column := range groups {
+		status := "PASS"
+		for _, result := range column {
+			if result.Failed() {
+				status = "FAIL"
+			} else {
+				passed++
+			}
+		}
+		totals = append(totals, status)
+	}
+	fmt.Fprintln(tw, strings.Join(totals, "\t"))
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d of %d cells passed in %s\n", passed, len(summary.Cells), time.Duration(summary.Elapsed*float64(time.Second)).Round(time.Second))
+	reported := map[string]bool{}
+	for _, result := range summary.Cells {
+		switch {
+		case result.Error == "" || reported[result.Error]:
+		case result.Log == "":
+			// Every cell of a version shares its build error
+			fmt.Fprintf(w, "alpine-%s: %s\n", result.AlpineVersion, result.Error)
+		default:
+			fmt.Fprintf(w, "%s: go test failed outside of a test, see %s\n", result.Name(), result.Log)
+		}
+		reported[result.Error] = true
+	}
+}
+
+// scenarioStatus returns the grid entry for a scenario in one column
+func scenarioStatus(column []CellResult, scenario string) string {
+	for _, result := range column {
+		if result.Scenario != scenario {
+			continue
+		}
+		if result.Failed() {
+			return "FAIL"
+		}
+		for _, test := range result.Tests {
+			if test.Name == scenario {
+				return strings.ToUpper(test.Status)
+			}
+		}
+	}
+	return "-"
+}
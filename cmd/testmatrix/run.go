@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Cell is one scenario run against one Alpine version and user mode
+type Cell struct {
+	AlpineVersion string `json:"alpine_version"`
+	UserMode      string `json:"user_mode"`
+	Scenario      string `json:"scenario"` // Top-level test
+}
+
+// Column identifies the version and user mode of the cell, e.g. alpine-3.21/system
+func (c Cell) Column() string {
+	return fmt.Sprintf("alpine-%s/%s", c.AlpineVersion, c.UserMode)
+}
+
+// Name identifies the cell in reports, e.g. alpine-3.21/system/TestQuotaTestSuite
+func (c Cell) Name() string {
+	return c.Column() + "/" + c.Scenario
+}
+
+// Test statuses, as reported by go test -json
+const (
+	statusPass = "pass"
+	statusFail = "fail"
+	statusSkip = "skip"
+)
+
+// TestResult is the outcome of one test or subtest in a cell
+type TestResult struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Elapsed float64 `json:"elapsed_seconds"`
+	Output  string  `json:"-"`
+}
+
+// CellResult is the outcome of running the tests for one cell
+type CellResult struct {
+	Cell
+	Status  string       `json:"status"`
+	Elapsed float64      `json:"elapsed_seconds"`
+	Started time.Time    `json:"started"`
+	Log     string       `json:"log"`             // Raw go test output
+	Error   string       `json:"error,omitempty"` // Failures outside of any test, such as build errors
+	Tests   []TestResult `json:"tests"`
+}
+
+// Failed reports whether anything in the cell failed
+func (r CellResult) Failed() bool {
+	return r.Status == statusFail
+}
+
+// testEvent is a line of go test -json output (see go doc test2json)
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// runner runs go test for a cell
+type runner struct {
+	dir     string        // Directory of the tests module
+	run     string        // Optional -run pattern
+	timeout time.Duration // go test -timeout for each cell
+	logDir  string        // Directory the raw output is written to
+}
+
+// Scenarios lists the top-level tests matching the -run pattern
+func (r runner) Scenarios(ctx context.Context) ([]string, error) {
+	// go test -list only matches the top-level part of a pattern
+	pattern, _, _ := strings.Cut(r.run, "/")
+	if pattern == "" {
+		pattern = "."
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "test", "-list", pattern, ".")
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("list tests: %w\n%s", err, output)
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "Test") {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// Build builds the test images for an Alpine version and returns the tags
+// of the images that did not exist before. Cells of the version reuse them.
+func (r runner) Build(ctx context.Context, version string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "go", "test", "-count=1", ".", "-args", "-build-images")
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "ALPINE_VERSION="+version)
+	output, err := cmd.CombinedOutput()
+
+	logPath := filepath.Join(r.logDir, fmt.Sprintf("alpine-%s-build.log", version))
+	if writeErr := os.WriteFile(logPath, output, 0644); writeErr != nil && err == nil {
+		err = writeErr
+	}
+
+	// Tags built before a failure are returned so they are still removed
+	var built []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if tag, ok := strings.CutPrefix(line, "built "); ok {
+			built = append(built, tag)
+		}
+	}
+	if err != nil {
+		return built, fmt.Errorf("build images: %w, see %s", err, logPath)
+	}
+	return built, nil
+}
+
+// pattern returns the -run pattern that selects the cell's scenario, keeping
+// any subtest part of the -run flag
+func (r runner) pattern(cell Cell) string {
+	pattern := "^" + regexp.QuoteMeta(cell.Scenario) + "$"
+	if _, subtests, found := strings.Cut(r.run, "/"); found {
+		pattern += "/" + subtests
+	}
+	return pattern
+}
+
+// Run runs the tests for one cell. Failures are recorded in the result.
+func (r runner) Run(ctx context.Context, cell Cell) CellResult {
+	result := CellResult{Cell: cell, Started: time.Now()}
+	result.Log = filepath.Join(r.logDir, strings.ReplaceAll(cell.Name(), "/", "-")+".log")
+
+	logFile, err := os.Create(result.Log)
+	if err != nil {
+		result.Status = statusFail
+		result.Error = fmt.Sprintf("create log: %v", err)
+		return result
+	}
+	defer logFile.Close()
+
+	// The images were built by Build; main removes them once every cell
+	// has finished
+	args := []string{"test", "-json", "-count=1", "-timeout", r.timeout.String(),
+		"-run", r.pattern(cell), ".", "-args", "-keep-images"}
+
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(),
+		"ALPINE_VERSION="+cell.AlpineVersion,
+		"USER_MODE="+cell.UserMode,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(logFile, &stderr)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		result.Status = statusFail
+		result.Error = err.Error()
+		return result
+	}
+
+	if err := cmd.Start(); err != nil {
+		result.Status = statusFail
+		result.Error = fmt.Sprintf("start go test: %v", err)
+		return result
+	}
+	tests, packageOutput := parseEvents(io.TeeReader(stdout, logFile))
+	waitErr := cmd.Wait()
+
+	result.Elapsed = time.Since(result.Started).Seconds()
+	result.Tests = tests
+	result.Status = statusPass
+	for _, test := range tests {
+		if test.Status == statusFail {
+			result.Status = statusFail
+		}
+	}
+
+	// A non-zero exit without a failed test means the package itself
+	// failed, for example to build or because of a timeout
+	if waitErr != nil && result.Status != statusFail {
+		result.Status = statusFail
+		result.Error = strings.TrimSpace(packageOutput + stderr.String())
+		if result.Error == "" {
+			result.Error = waitErr.Error()
+		}
+	}
+	return result
+}
+
+// parseEvents reads go test -json output and returns every test in the
+// order it started, along with the output that belongs to no test
+func parseEvents(reader io.Reader) ([]TestResult, string) {
+	var order []string
+	tests := map[string]*TestResult{}
+	var packageOutput strings.Builder
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Not an event, e.g. output printed before the tests started
+			packageOutput.WriteString(scanner.Text() + "\n")
+			continue
+		}
+
+		if event.Test == "" {
+			if event.Action == "output" || event.Action == "build-output" {
+				packageOutput.WriteString(event.Output)
+			}
+			continue
+		}
+
+		test, ok := tests[event.Test]
+		if !ok {
+			test = &TestResult{Name: event.Test}
+			tests[event.Test] = test
+			order = append(order, event.Test)
+		}
+		switch event.Action {
+		case "output":
+			test.Output += event.Output
+		case statusPass, statusFail, statusSkip:
+			test.Status = event.Action
+			test.Elapsed = event.Elapsed
+		}
+	}
+
+	results := make([]TestResult, 0, len(order))
+	for _, name := range order {
+		test := tests[name]
+		// Tests cut short by a panic or timeout never report a result
+		if test.Status == "" {
+			test.Status = statusFail
+		}
+		results = append(results, *test)
+	}
+	return results, packageOutput.String()
+}
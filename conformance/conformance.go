@@ -0,0 +1,621 @@
+// Package conformance checks that an FTP server behaves the way mini-ftp
+// users rely on. It only needs a way to log in, so it can verify any server:
+//
+//	conformance.Run(t, conformance.Options{
+//		Dial: func(t testing.TB) *goftp.Client { return srv.Client("alice") },
+//	})
+//
+// Each check runs as a subtest of t and works inside Options.Dir, which is
+// removed when the checks finish. The user must be able to write to its home
+// directory, and ChrootEscape expects the user to be chrooted to it.
+package conformance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+)
+
+// Dialer returns a new client logged in as the user under test. Every call
+// must return a separate client so that sessions are independent.
+type Dialer func(t testing.TB) *goftp.Client
+
+// Options configures Run
+type Options struct {
+	Dial          Dialer   // Required
+	Dir           string   // Working directory, relative to the user's home; defaults to "conformance"
+	LargeFileSize int      // Size of the file used by LargeFile; defaults to 16 MiB
+	Sessions      int      // Number of clients used by ConcurrentSessions; defaults to 4
+	Skip          []string // Names of checks to leave out, such as "ChrootEscape"
+}
+
+// check is one named conformance check
+type check struct {
+	name string
+	run  func(t *testing.T, s *session)
+}
+
+// checks lists every check in the order they run
+var checks = []check{
+	{"FileOperations", testFileOperations},
+	{"RenameFile", testRenameFile},
+	{"DirectoryOperations", testDirectoryOperations},
+	{"NestedDirectories", testNestedDirectories},
+	{"FilePermissions", testFilePermissions},
+	{"BinaryFile", testBinaryFile},
+	{"LargeFile", testLargeFile},
+	{"ResumeDownload", testResumeDownload},
+	{"ResumeUpload", testResumeUpload},
+	{"Append", testAppend},
+	{"SizeAndModTime", testSizeAndModTime},
+	{"UnicodeNames", testUnicodeNames},
+	{"ConcurrentSessions", testConcurrentSessions},
+	{"ChrootEscape", testChrootEscape},
+}
+
+// Checks returns the names of all checks, for use with Options.Skip
+func Checks() []string {
+	names := make([]string, len(checks))
+	for i, c := range checks {
+		names[i] = c.name
+	}
+	return names
+}
+
+// session is the state shared by the checks of one Run
+type session struct {
+	opts   Options
+	client *goftp.Client
+}
+
+// path joins name onto the working directory
+func (s *session) path(name ...string) string {
+	return path.Join(append([]string{s.opts.Dir}, name...)...)
+}
+
+// Run runs every check that is not skipped as a subtest of t
+func Run(t *testing.T, opts Options) {
+	t.Helper()
+
+	if opts.Dial == nil {
+		t.Fatal("conformance: Options.Dial is required")
+	}
+	if opts.Dir == "" {
+		opts.Dir = "conformance"
+	}
+	if opts.LargeFileSize == 0 {
+		opts.LargeFileSize = 16 << 20
+	}
+	if opts.Sessions == 0 {
+		opts.Sessions = 4
+	}
+	skip := map[string]bool{}
+	for _, name := range opts.Skip {
+		skip[name] = true
+	}
+
+	s := &session{opts: opts, client: opts.Dial(t)}
+	if _, err := s.client.Mkdir(opts.Dir); err != nil {
+		t.Fatalf("Failed to create working directory %s: %v", opts.Dir, err)
+	}
+	t.Cleanup(func() {
+		if err := removeAll(s.client, opts.Dir); err != nil {
+			t.Errorf("Failed to remove working directory %s: %v", opts.Dir, err)
+		}
+	})
+
+	for _, c := range checks {
+		if skip[c.name] {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) { c.run(t, s) })
+	}
+}
+
+// testFileOperations checks upload, download, and deletion
+func testFileOperations(t *testing.T, s *session) {
+	name := s.path("test-file.txt")
+	store(t, s.client, name, []byte("test file content"))
+	expectContent(t, s.client, name, []byte("test file content"))
+
+	if err := s.client.Delete(name); err != nil {
+		t.Fatalf("Failed to delete %s: %v", name, err)
+	}
+	if _, ok := lookup(t, s.client, s.opts.Dir, "test-file.txt"); ok {
+		t.Errorf("%s should be deleted", name)
+	}
+}
+
+// testRenameFile checks that a renamed file keeps its content
+func testRenameFile(t *testing.T, s *session) {
+	from, to := s.path("rename-test.txt"), s.path("renamed-file.txt")
+	store(t, s.client, from, []byte("rename test"))
+
+	if err := s.client.Rename(from, to); err != nil {
+		t.Fatalf("Failed to rename %s: %v", from, err)
+	}
+	if _, ok := lookup(t, s.client, s.opts.Dir, "rename-test.txt"); ok {
+		t.Errorf("%s should no longer exist", from)
+	}
+	expectContent(t, s.client, to, []byte("rename test"))
+}
+
+// testDirectoryOperations checks directory creation, renaming, and deletion
+func testDirectoryOperations(t *testing.T, s *session) {
+	from, to := s.path("test-dir"), s.path("renamed-dir")
+
+	created, err := s.client.Mkdir(from)
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", from, err)
+	}
+	if created != "/"+from {
+		t.Errorf("Mkdir returned %q, want %q", created, "/"+from)
+	}
+
+	if err := s.client.Rename(from, to); err != nil {
+		t.Fatalf("Failed to rename %s: %v", from, err)
+	}
+	if info, ok := lookup(t, s.client, s.opts.Dir, "renamed-dir"); !ok || !info.IsDir() {
+		t.Errorf("%s should be a directory", to)
+	}
+
+	if err := s.client.Rmdir(to); err != nil {
+		t.Fatalf("Failed to remove %s: %v", to, err)
+	}
+	if _, ok := lookup(t, s.client, s.opts.Dir, "renamed-dir"); ok {
+		t.Errorf("%s should be deleted", to)
+	}
+}
+
+// testNestedDirectories checks files several directories deep and that
+// renaming a directory moves everything below it
+func testNestedDirectories(t *testing.T, s *session) {
+	if _, err := s.client.Mkdir(s.path("missing", "child")); err == nil {
+		t.Errorf("Creating a directory inside a missing one should fail")
+	}
+
+	for _, dir := range []string{"a", "a/b", "a/b/c", "a/b/c/d"} {
+		if _, err := s.client.Mkdir(s.path(dir)); err != nil {
+			t.Fatalf("Failed to create %s: %v", s.path(dir), err)
+		}
+	}
+	store(t, s.client, s.path("a/b/c/d/leaf.txt"), []byte("leaf"))
+	store(t, s.client, s.path("a/b/middle.txt"), []byte("middle"))
+
+	if info, ok := lookup(t, s.client, s.path("a/b"), "c"); !ok || !info.IsDir() {
+		t.Errorf("%s should list c as a directory", s.path("a/b"))
+	}
+	if _, ok := lookup(t, s.client, s.path("a/b/c/d"), "leaf.txt"); !ok {
+		t.Errorf("%s should list leaf.txt", s.path("a/b/c/d"))
+	}
+
+	if err := s.client.Rmdir(s.path("a/b")); err == nil {
+		t.Errorf("Removing a directory that is not empty should fail")
+	}
+
+	if err := s.client.Rename(s.path("a/b"), s.path("moved")); err != nil {
+		t.Fatalf("Failed to rename %s: %v", s.path("a/b"), err)
+	}
+	expectContent(t, s.client, s.path("moved/c/d/leaf.txt"), []byte("leaf"))
+	expectContent(t, s.client, s.path("moved/middle.txt"), []byte("middle"))
+
+	for _, dir := range []string{"moved", "a"} {
+		if err := removeAll(s.client, s.path(dir)); err != nil {
+			t.Errorf("Failed to remove %s: %v", s.path(dir), err)
+		}
+	}
+}
+
+// testFilePermissions checks that uploads are readable and writable by their owner
+func testFilePermissions(t *testing.T, s *session) {
+	name := s.path("perm-test.txt")
+	store(t, s.client, name, []byte("permission test"))
+
+	info, ok := lookup(t, s.client, s.opts.Dir, "perm-test.txt")
+	if !ok {
+		t.Fatalf("%s should be listed", name)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o600 != 0o600 {
+		t.Errorf("%s has mode %v, want a regular file the owner can read and write", name, info.Mode())
+	}
+
+	// Overwriting replaces the content rather than appending to it
+	store(t, s.client, name, []byte("new"))
+	expectContent(t, s.client, name, []byte("new"))
+}
+
+// testBinaryFile checks that every byte value and line ending survives a round trip
+func testBinaryFile(t *testing.T, s *session) {
+	var content []byte
+	for i := 0; i < 4; i++ {
+		for b := 0; b < 256; b++ {
+			content = append(content, byte(b))
+		}
+	}
+	content = append(content, "dos\r\nunix\nmac\rtrailing\r"...)
+
+	name := s.path("binary.bin")
+	store(t, s.client, name, content)
+	expectContent(t, s.client, name, content)
+}
+
+// testLargeFile checks a transfer of Options.LargeFileSize bytes
+func testLargeFile(t *testing.T, s *session) {
+	content := randomBytes(s.opts.LargeFileSize, 1)
+	name := s.path("large.bin")
+	store(t, s.client, name, content)
+
+	hash := sha256.New()
+	if err := s.client.Retrieve(name, hash); err != nil {
+		t.Fatalf("Failed to retrieve %s: %v", name, err)
+	}
+	if want := sha256.Sum256(content); !bytes.Equal(hash.Sum(nil), want[:]) {
+		t.Errorf("%s came back with different content", name)
+	}
+
+	if size := fileSize(t, s.client, name); size != int64(len(content)) {
+		t.Errorf("SIZE %s = %d, want %d", name, size, len(content))
+	}
+}
+
+// testResumeDownload checks RETR after REST
+func testResumeDownload(t *testing.T, s *session) {
+	content := randomBytes(64<<10, 2)
+	name := s.path("resume-download.bin")
+	store(t, s.client, name, content)
+
+	offset := len(content) / 3
+	got := transfer(t, s.client, nil, fmt.Sprintf("REST %d", offset), "RETR "+name)
+	if !bytes.Equal(got, content[offset:]) {
+		t.Errorf("RETR after REST %d returned %d bytes, want the last %d", offset, len(got), len(content)-offset)
+	}
+}
+
+// testResumeUpload checks STOR after REST
+func testResumeUpload(t *testing.T, s *session) {
+	content := randomBytes(64<<10, 3)
+	name := s.path("resume-upload.bin")
+
+	offset := len(content) / 2
+	store(t, s.client, name, content[:offset])
+	transfer(t, s.client, content[offset:], fmt.Sprintf("REST %d", offset), "STOR "+name)
+	expectContent(t, s.client, name, content)
+}
+
+// testAppend checks APPE on existing and new files
+func testAppend(t *testing.T, s *session) {
+	name := s.path("append.txt")
+	store(t, s.client, name, []byte("hello"))
+	transfer(t, s.client, []byte(", world"), "APPE "+name)
+	expectContent(t, s.client, name, []byte("hello, world"))
+
+	created := s.path("append-new.txt")
+	transfer(t, s.client, []byte("created by APPE"), "APPE "+created)
+	expectContent(t, s.client, created, []byte("created by APPE"))
+}
+
+// testSizeAndModTime checks the SIZE and MDTM commands
+func testSizeAndModTime(t *testing.T, s *session) {
+	name := s.path("size-mdtm.txt")
+	before := time.Now()
+	store(t, s.client, name, []byte("twenty bytes of data"))
+
+	if size := fileSize(t, s.client, name); size != 20 {
+		t.Errorf("SIZE %s = %d, want 20", name, size)
+	}
+
+	code, msg := command(t, s.client, "MDTM "+name)
+	if code != 213 {
+		t.Fatalf("MDTM %s returned %d %s", name, code, msg)
+	}
+	msg = strings.TrimSpace(msg)
+	if len(msg) < 14 {
+		t.Fatalf("MDTM %s returned %q, want YYYYMMDDHHMMSS", name, msg)
+	}
+	modified, err := time.Parse("20060102150405", msg[:14])
+	if err != nil {
+		t.Fatalf("MDTM %s returned %q: %v", name, msg, err)
+	}
+	// MDTM is in UTC and the server's clock may drift a little
+	if diff := modified.Sub(before.UTC().Truncate(time.Second)); diff < -time.Minute || diff > 10*time.Minute {
+		t.Errorf("MDTM %s = %v, want a time close to the upload at %v", name, modified, before.UTC())
+	}
+
+	if code, _ := command(t, s.client, "SIZE "+s.path("missing.txt")); code/100 != 5 {
+		t.Errorf("SIZE of a missing file returned %d, want a 5xx error", code)
+	}
+}
+
+// testUnicodeNames checks UTF-8 file and directory names
+func testUnicodeNames(t *testing.T, s *session) {
+	dir := "répertoire-ünïcødé"
+	if _, err := s.client.Mkdir(s.path(dir)); err != nil {
+		t.Fatalf("Failed to create %s: %v", s.path(dir), err)
+	}
+
+	for _, name := range []string{"café.txt", "日本語.txt", "Ελληνικά.txt", "emoji-🚀.txt"} {
+		content := []byte("content of " + name)
+		store(t, s.client, s.path(dir, name), content)
+		expectContent(t, s.client, s.path(dir, name), content)
+		if _, ok := lookup(t, s.client, s.path(dir), name); !ok {
+			t.Errorf("%s should list %q", s.path(dir), name)
+		}
+	}
+
+	if err := removeAll(s.client, s.path(dir)); err != nil {
+		t.Errorf("Failed to remove %s: %v", s.path(dir), err)
+	}
+}
+
+// testConcurrentSessions checks uploads and downloads from several clients at once
+func testConcurrentSessions(t *testing.T, s *session) {
+	clients := make([]*goftp.Client, s.opts.Sessions)
+	for i := range clients {
+		clients[i] = s.opts.Dial(t)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(clients))
+	for i, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := s.path(fmt.Sprintf("session-%d.bin", i))
+			content := randomBytes(256<<10, int64(100+i))
+
+			if err := client.Store(name, bytes.NewReader(content)); err != nil {
+				errs <- fmt.Errorf("session %d: store %s: %w", i, name, err)
+				return
+			}
+			var buf bytes.Buffer
+			if err := client.Retrieve(name, &buf); err != nil {
+				errs <- fmt.Errorf("session %d: retrieve %s: %w", i, name, err)
+				return
+			}
+			if !bytes.Equal(buf.Bytes(), content) {
+				errs <- fmt.Errorf("session %d: %s came back with different content", i, name)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	for i := range clients {
+		if _, ok := lookup(t, s.client, s.opts.Dir, fmt.Sprintf("session-%d.bin", i)); !ok {
+			t.Errorf("session-%d.bin should be listed", i)
+		}
+	}
+}
+
+// testChrootEscape checks that paths pointing above the home directory stay inside it
+func testChrootEscape(t *testing.T, s *session) {
+	root := names(t, s.client, "/")
+	for _, dir := range []string{"..", "../..", "/..", "/../../..", "./../.", s.opts.Dir + "/../../.."} {
+		if got := names(t, s.client, dir); strings.Join(got, "/") != strings.Join(root, "/") {
+			t.Errorf("Listing %q returned %v, want the home directory %v", dir, got, root)
+		}
+	}
+
+	for _, name := range []string{
+		"/etc/passwd",
+		"../etc/passwd",
+		"../../../../etc/passwd",
+		"/../../../../etc/passwd",
+		s.opts.Dir + "/../../../etc/passwd",
+		"..%2f..%2f..%2fetc%2fpasswd",
+		`..\..\..\etc\passwd`,
+		"/proc/self/environ",
+	} {
+		if err := s.client.Retrieve(name, io.Discard); err == nil {
+			t.Errorf("Retrieving %q should fail", name)
+		}
+	}
+
+	// Changing to a parent of the home directory stays at its root
+	raw := openRaw(t, s.client)
+	if code, msg, err := raw.SendCommand("CWD ../../.."); err != nil || code/100 == 4 {
+		t.Fatalf("CWD ../../.. returned %d %s: %v", code, msg, err)
+	}
+	code, msg, err := raw.SendCommand("PWD")
+	if err != nil || code != 257 {
+		t.Fatalf("PWD returned %d %s: %v", code, msg, err)
+	}
+	if !strings.HasPrefix(msg, `"/"`) {
+		t.Errorf("PWD after CWD ../../.. returned %s, want \"/\"", msg)
+	}
+
+	// Writes and renames through .. land in the home directory if they are allowed
+	store(t, s.client, s.path("escape.txt"), []byte("escape"))
+	if err := s.client.Store("../../escape-upload.txt", strings.NewReader("escape")); err == nil {
+		expectContent(t, s.client, "/escape-upload.txt", []byte("escape"))
+		s.client.Delete("/escape-upload.txt")
+	}
+	if err := s.client.Rename(s.path("escape.txt"), "../../../escape-rename.txt"); err == nil {
+		expectContent(t, s.client, "/escape-rename.txt", []byte("escape"))
+		s.client.Delete("/escape-rename.txt")
+	}
+}
+
+// store uploads content to name
+func store(t testing.TB, client *goftp.Client, name string, content []byte) {
+	t.Helper()
+	if err := client.Store(name, bytes.NewReader(content)); err != nil {
+		t.Fatalf("Failed to store %s: %v", name, err)
+	}
+}
+
+// expectContent downloads name and compares it with want
+func expectContent(t testing.TB, client *goftp.Client, name string, want []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := client.Retrieve(name, &buf); err != nil {
+		t.Fatalf("Failed to retrieve %s: %v", name, err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		if len(want) > 64 {
+			t.Errorf("%s has %d bytes that differ from the %d uploaded", name, buf.Len(), len(want))
+		} else {
+			t.Errorf("%s contains %q, want %q", name, buf.String(), want)
+		}
+	}
+}
+
+// lookup finds name in the listing of dir
+func lookup(t testing.TB, client *goftp.Client, dir, name string) (os.FileInfo, bool) {
+	t.Helper()
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to list %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == name {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// names returns the sorted names listed in dir
+func names(t testing.TB, client *goftp.Client, dir string) []string {
+	t.Helper()
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to list %s: %v", dir, err)
+	}
+	var list []string
+	for _, entry := range entries {
+		list = append(list, entry.Name())
+	}
+	sort.Strings(list)
+	return list
+}
+
+// removeAll deletes dir and everything below it
+func removeAll(client *goftp.Client, dir string) error {
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		if entry.IsDir() {
+			err = removeAll(client, name)
+		} else {
+			err = client.Delete(name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return client.Rmdir(dir)
+}
+
+// randomBytes returns size reproducible pseudo-random bytes
+func randomBytes(size int, seed int64) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(content)
+	return content
+}
+
+// openRaw opens a raw control connection that is closed with the test
+func openRaw(t testing.TB, client *goftp.Client) goftp.RawConn {
+	t.Helper()
+	raw, err := client.OpenRawConn()
+	if err != nil {
+		t.Fatalf("Failed to open a raw connection: %v", err)
+	}
+	t.Cleanup(func() { raw.Close() })
+	return raw
+}
+
+// command sends a single command in binary mode and returns the reply
+func command(t testing.TB, client *goftp.Client, cmd string) (int, string) {
+	t.Helper()
+	raw := openRaw(t, client)
+	if code, msg, err := raw.SendCommand("TYPE I"); err != nil || code != 200 {
+		t.Fatalf("TYPE I returned %d %s: %v", code, msg, err)
+	}
+	code, msg, err := raw.SendCommand("%s", cmd)
+	if err != nil {
+		t.Fatalf("%s failed: %v", cmd, err)
+	}
+	return code, msg
+}
+
+// fileSize returns the reply to SIZE name
+func fileSize(t testing.TB, client *goftp.Client, name string) int64 {
+	t.Helper()
+	code, msg := command(t, client, "SIZE "+name)
+	if code != 213 {
+		t.Fatalf("SIZE %s returned %d %s", name, code, msg)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(msg), 10, 64)
+	if err != nil {
+		t.Fatalf("SIZE %s returned %q: %v", name, msg, err)
+	}
+	return size
+}
+
+// transfer runs a data command in binary mode on a raw connection. The
+// commands before it, such as REST, must be accepted with a 3xx reply. upload
+// is sent for STOR and APPE, and the received data is returned otherwise.
+func transfer(t testing.TB, client *goftp.Client, upload []byte, commands ...string) []byte {
+	t.Helper()
+	raw := openRaw(t, client)
+	if code, msg, err := raw.SendCommand("TYPE I"); err != nil || code != 200 {
+		t.Fatalf("TYPE I returned %d %s: %v", code, msg, err)
+	}
+
+	last := len(commands) - 1
+	for _, cmd := range commands[:last] {
+		if code, msg, err := raw.SendCommand("%s", cmd); err != nil || code/100 != 3 {
+			t.Fatalf("%s returned %d %s: %v", cmd, code, msg, err)
+		}
+	}
+
+	getConn, err := raw.PrepareDataConn()
+	if err != nil {
+		t.Fatalf("Failed to prepare a data connection: %v", err)
+	}
+	if code, msg, err := raw.SendCommand("%s", commands[last]); err != nil || code/100 != 1 {
+		t.Fatalf("%s returned %d %s: %v", commands[last], code, msg, err)
+	}
+	conn, err := getConn()
+	if err != nil {
+		t.Fatalf("Failed to open the data connection for %s: %v", commands[last], err)
+	}
+
+	var received []byte
+	if upload != nil {
+		_, err = conn.Write(upload)
+	} else {
+		received, err = io.ReadAll(conn)
+	}
+	if err != nil {
+		conn.Close()
+		t.Fatalf("Data transfer for %s failed: %v", commands[last], err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Failed to close the data connection for %s: %v", commands[last], err)
+	}
+
+	if code, msg, err := raw.ReadResponse(); err != nil || code/100 != 2 {
+		t.Fatalf("%s finished with %d %s: %v", commands[last], code, msg, err)
+	}
+	return received
+}
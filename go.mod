@@ -0,0 +1,8 @@
+module github.com/shawn636/mini-ftp
+
+go 1.23.4
+
+require (
+	github.com/secsy/goftp v0.0.0-20200609142545-aa2de14babf4
+	gopkg.in/yaml.v3 v3.0.1
+)
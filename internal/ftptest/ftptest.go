@@ -0,0 +1,106 @@
+// Package ftptest holds helpers for tests that start mini-ftp. They are
+// shared by the testserver package and the repository's own test suites,
+// whose module path is below this module's so they may import it.
+package ftptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FreePortRange finds count consecutive ports that are free on host, or on
+// every interface when host is empty. Passive ports are advertised by the
+// server itself, so they must be published on the same numbers inside and
+// outside the container.
+//
+// The ports are released before FreePortRange returns, so another process
+// can take them before the container binds them. Callers should pick a new
+// range and retry when the bind fails.
+func FreePortRange(host string, count int) (int, int, error) {
+	for attempt := 0; attempt < 50; attempt++ {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return 0, 0, fmt.Errorf("find a free port: %w", err)
+		}
+		base := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+
+		if base+count-1 > 65535 {
+			continue
+		}
+		if portsFree(host, base, count) {
+			return base, base + count - 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("no %d consecutive free ports on %s", count, host)
+}
+
+// PortTaken reports whether Docker output shows that a published port could
+// not be bound because another process holds it
+func PortTaken(output string) bool {
+	return strings.Contains(output, "port is already allocated") ||
+		strings.Contains(output, "address already in use")
+}
+
+// portsFree reports whether every port in the range can be bound
+func portsFree(host string, base, count int) bool {
+	var listeners []net.Listener
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	for port := base; port < base+count; port++ {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return false
+		}
+		listeners = append(listeners, listener)
+	}
+	return true
+}
+
+// SelfSignedCert returns a PEM certificate and key valid for host for a day
+func SelfSignedCert(host string) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate TLS key: %w", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create TLS certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode TLS key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,196 @@
+/*
+ * access.so - Drops connections from denied addresses before the greeting
+ *
+ * Loaded into vsftpd with LD_PRELOAD. The IP access lists are checked by
+ * check_access when a client logs in, which is after vsftpd has sent its
+ * 220 banner. vsftpd has no hook before that, so the listener's accept is
+ * wrapped instead:
+ *
+ *   accept  Connections from an address that cannot log in as anyone are
+ *           closed before vsftpd sees them. vsftpd treats the failed accept
+ *           like any other and waits for the next client.
+ *
+ * The rules are read from ACCESS_RULES (written by set_access_rules) on each
+ * connection. Each line is "allow <cidr>" or "deny <cidr>". Deny rules always
+ * win, and when there are allow rules the address must match one of them.
+ * check_access still decides per user once the client logs in.
+ */
+
+#define _GNU_SOURCE
+#include <arpa/inet.h>
+#include <dlfcn.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/types.h>
+#include <time.h>
+#include <unistd.h>
+
+#define DEFAULT_RULES_FILE "/etc/vsftpd/access/connect"
+
+/* vsftpd keeps the control connection on stdin */
+#define COMMAND_FD 0
+
+/* vsftpd runs in the background, so write straight to the container log */
+#define LOG_TARGET "/proc/1/fd/1"
+
+/* --- Real Functions --- */
+static int (*real_accept)(int, struct sockaddr *, socklen_t *);
+static int (*real_accept4)(int, struct sockaddr *, socklen_t *, int);
+
+#define RESOLVE(name) \
+  do { \
+    if (!real_##name) \
+      *(void **)&real_##name = dlsym(RTLD_NEXT, #name); \
+  } while (0)
+
+/* --- Addresses --- */
+struct address {
+  int family;
+  unsigned char bytes[16];
+};
+
+/* Stores the peer of fd in client, unwrapping IPv4-mapped addresses from a
+ * dual-stack listener */
+static int peer_address(int fd, struct address *client) {
+  struct sockaddr_storage peer;
+  socklen_t length = sizeof(peer);
+  if (getpeername(fd, (struct sockaddr *)&peer, &length) != 0)
+    return -1;
+
+  if (peer.ss_family == AF_INET) {
+    client->family = AF_INET;
+    memcpy(client->bytes, &((struct sockaddr_in *)&peer)->sin_addr, 4);
+    return 0;
+  }
+  if (peer.ss_family == AF_INET6) {
+    const struct in6_addr *ip6 = &((struct sockaddr_in6 *)&peer)->sin6_addr;
+    if (IN6_IS_ADDR_V4MAPPED(ip6)) {
+      client->family = AF_INET;
+      memcpy(client->bytes, &ip6->s6_addr[12], 4);
+    } else {
+      client->family = AF_INET6;
+      memcpy(client->bytes, ip6->s6_addr, 16);
+    }
+    return 0;
+  }
+  return -1;
+}
+
+/* Reports whether client is inside cidr. Blocks of the other address family
+ * never match, like in cidr_match. */
+static int cidr_match(const struct address *client, const char *cidr) {
+  char network[INET6_ADDRSTRLEN];
+  const char *slash = strchr(cidr, '/');
+  size_t length = slash ? (size_t)(slash - cidr) : strlen(cidr);
+  if (length >= sizeof(network))
+    return 0;
+  memcpy(network, cidr, length);
+  network[length] = '\0';
+
+  struct address base;
+  base.family = strchr(network, ':') ? AF_INET6 : AF_INET;
+  if (base.family != client->family || inet_pton(base.family, network, base.bytes) != 1)
+    return 0;
+
+  long bits = base.family == AF_INET ? 32 : 128;
+  long prefix = slash ? strtol(slash + 1, NULL, 10) : bits;
+  if (prefix < 0 || prefix > bits)
+    return 0;
+
+  size_t whole = (size_t)prefix / 8;
+  if (memcmp(client->bytes, base.bytes, whole) != 0)
+    return 0;
+  if (prefix % 8 == 0)
+    return 1;
+
+  unsigned char mask = (unsigned char)(0xff << (8 - prefix % 8));
+  return (client->bytes[whole] & mask) == (base.bytes[whole] & mask);
+}
+
+/* --- Rules --- */
+/* Returns the reason client may not connect, or NULL when it may */
+static const char *check_rules(const struct address *client, char *reason, size_t size) {
+  const char *path = getenv("ACCESS_RULES");
+  if (!path || !*path)
+    path = DEFAULT_RULES_FILE;
+
+  FILE *file = fopen(path, "re");
+  if (!file)
+    return NULL;
+
+  int have_allow = 0, allowed = 0, denied = 0;
+  char kind[16], cidr[64];
+  while (!denied && fscanf(file, "%15s %63s", kind, cidr) == 2) {
+    if (strcmp(kind, "deny") == 0 && cidr_match(client, cidr)) {
+      snprintf(reason, size, "address matches deny rule %s", cidr);
+      denied = 1;
+    } else if (strcmp(kind, "allow") == 0) {
+      have_allow = 1;
+      allowed = allowed || cidr_match(client, cidr);
+    }
+  }
+  fclose(file);
+
+  if (denied)
+    return reason;
+  if (have_allow && !allowed) {
+    snprintf(reason, size, "address is not in any allow list");
+    return reason;
+  }
+  return NULL;
+}
+
+static void log_rejection(const struct address *client, const char *reason) {
+  char ip[INET6_ADDRSTRLEN];
+  if (!inet_ntop(client->family, client->bytes, ip, sizeof(ip)))
+    return;
+
+  char timestamp[32];
+  time_t now = time(NULL);
+  strftime(timestamp, sizeof(timestamp), "%Y-%m-%d %H:%M:%S", localtime(&now));
+
+  int fd = open(LOG_TARGET, O_WRONLY | O_APPEND | O_CLOEXEC);
+  if (fd < 0)
+    return;
+  dprintf(fd, "\033[33m[%s] [WARN] 🚫 Rejected connection from %s: %s\033[0m\n", timestamp, ip, reason);
+  close(fd);
+}
+
+/* Closes fd when its peer is denied. Sessions accept their own data
+ * connections, and those are left alone: a session already has the control
+ * connection on stdin, and the rules are not visible inside its chroot. */
+static int filter(int fd) {
+  if (fd < 0)
+    return fd;
+
+  struct sockaddr_storage control;
+  socklen_t length = sizeof(control);
+  if (getpeername(COMMAND_FD, (struct sockaddr *)&control, &length) == 0)
+    return fd;
+
+  struct address client;
+  char reason[128];
+  if (peer_address(fd, &client) != 0 || !check_rules(&client, reason, sizeof(reason)))
+    return fd;
+
+  log_rejection(&client, reason);
+  close(fd);
+  errno = ECONNABORTED;
+  return -1;
+}
+
+/* --- Interposed Calls --- */
+int accept(int sockfd, struct sockaddr *addr, socklen_t *addrlen) {
+  RESOLVE(accept);
+  return filter(real_accept(sockfd, addr, addrlen));
+}
+
+int accept4(int sockfd, struct sockaddr *addr, socklen_t *addrlen, int flags) {
+  RESOLVE(accept4);
+  return filter(real_accept4(sockfd, addr, addrlen, flags));
+}
@@ -0,0 +1,197 @@
+/*
+ * pasv.so - Picks the passive address for each vsftpd session
+ *
+ * Loaded into vsftpd with LD_PRELOAD. vsftpd has a single pasv_address, set
+ * server-wide or per user, so LAN and WAN sessions of the same user would
+ * share one. Instead each session picks its own from the passive address
+ * map written by pasv_address_watch:
+ *
+ *   chroot  When the session logs in, the first CIDR in the map that holds
+ *           the client's address decides the session's passive address.
+ *           The map is not visible after the chroot, so it is read here.
+ *   reply   The address in "227 Entering Passive Mode" replies is replaced
+ *           with the session's address. Sessions that matched nothing, and
+ *           IPv6 clients, which use EPSV, keep vsftpd's reply.
+ *
+ * TLS sessions send replies from a process that never chroots, so there the
+ * address is picked on the first PASV reply instead.
+ */
+
+#define _GNU_SOURCE
+#include <arpa/inet.h>
+#include <dlfcn.h>
+#include <netinet/in.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <sys/types.h>
+#include <unistd.h>
+
+#define DEFAULT_MAP_FILE "/etc/vsftpd/pasv/map"
+
+/* vsftpd keeps the control connection on stdin */
+#define COMMAND_FD 0
+
+#define PASV_REPLY "227 "
+
+static int chosen;
+static int have_address;
+static struct in_addr address;
+
+/* --- Real Functions --- */
+static ssize_t (*real_write)(int, const void *, size_t);
+static int (*real_chroot)(const char *);
+
+#define RESOLVE(name) \
+  do { \
+    if (!real_##name) \
+      *(void **)&real_##name = dlsym(RTLD_NEXT, #name); \
+  } while (0)
+
+/* --- Address Map --- */
+/* Stores the client's IPv4 address in client, unwrapping mapped addresses
+ * from a dual-stack listener */
+static int client_address(struct in_addr *client) {
+  struct sockaddr_storage peer;
+  socklen_t length = sizeof(peer);
+  if (getpeername(COMMAND_FD, (struct sockaddr *)&peer, &length) != 0)
+    return -1;
+
+  if (peer.ss_family == AF_INET) {
+    *client = ((struct sockaddr_in *)&peer)->sin_addr;
+    return 0;
+  }
+  if (peer.ss_family == AF_INET6) {
+    const struct in6_addr *ip6 = &((struct sockaddr_in6 *)&peer)->sin6_addr;
+    if (IN6_IS_ADDR_V4MAPPED(ip6)) {
+      memcpy(client, &ip6->s6_addr[12], sizeof(*client));
+      return 0;
+    }
+  }
+  return -1;
+}
+
+/* Reports whether client is inside cidr. IPv6 blocks never match, since
+ * only IPv4 clients send PASV. */
+static int cidr_match(struct in_addr client, const char *cidr) {
+  char network[INET_ADDRSTRLEN];
+  const char *slash = strchr(cidr, '/');
+  size_t length = slash ? (size_t)(slash - cidr) : strlen(cidr);
+  if (length >= sizeof(network))
+    return 0;
+  memcpy(network, cidr, length);
+  network[length] = '\0';
+
+  struct in_addr base;
+  if (inet_pton(AF_INET, network, &base) != 1)
+    return 0;
+
+  long prefix = slash ? strtol(slash + 1, NULL, 10) : 32;
+  if (prefix < 0 || prefix > 32)
+    return 0;
+
+  uint32_t mask = prefix == 0 ? 0 : htonl(~0U << (32 - prefix));
+  return (client.s_addr & mask) == (base.s_addr & mask);
+}
+
+static void choose_address(void) {
+  if (chosen)
+    return;
+
+  const char *path = getenv("PASV_MAP");
+  if (!path || !*path)
+    path = DEFAULT_MAP_FILE;
+
+  FILE *file = fopen(path, "re");
+  if (!file)
+    return;
+  chosen = 1;
+
+  struct in_addr client;
+  if (client_address(&client) != 0) {
+    fclose(file);
+    return;
+  }
+
+  char cidr[64], mapped[64];
+  while (fscanf(file, "%63s %63s", cidr, mapped) == 2) {
+    if (cidr_match(client, cidr)) {
+      have_address = inet_pton(AF_INET, mapped, &address) == 1;
+      break;
+    }
+  }
+  fclose(file);
+}
+
+/* --- Reply Rewriting --- */
+/* Returns the length of buf with the PASV address replaced, written to out,
+ * or 0 when buf is not a PASV reply or the session keeps vsftpd's address */
+static size_t rewrite_reply(const void *buf, size_t count, char *out, size_t size) {
+  const char *text = buf;
+  if (count < strlen(PASV_REPLY) || count >= size || memcmp(text, PASV_REPLY, strlen(PASV_REPLY)) != 0)
+    return 0;
+
+  choose_address();
+  if (!have_address)
+    return 0;
+
+  char reply[256];
+  memcpy(reply, text, count);
+  reply[count] = '\0';
+
+  char *start = strchr(reply, '(');
+  char *end = start ? strchr(start, ')') : NULL;
+  unsigned int h1, h2, h3, h4, p1, p2;
+  if (!end || sscanf(start, "(%u,%u,%u,%u,%u,%u)", &h1, &h2, &h3, &h4, &p1, &p2) != 6)
+    return 0;
+
+  const unsigned char *ip = (const unsigned char *)&address.s_addr;
+  int written = snprintf(out, size, "%.*s(%u,%u,%u,%u,%u,%u)%s", (int)(start - reply), reply,
+                         ip[0], ip[1], ip[2], ip[3], p1, p2, end + 1);
+  if (written < 0 || (size_t)written >= size)
+    return 0;
+  return (size_t)written;
+}
+
+/* --- Interposed Calls --- */
+int chroot(const char *path) {
+  RESOLVE(chroot);
+  choose_address();
+  return real_chroot(path);
+}
+
+ssize_t write(int fd, const void *buf, size_t count) {
+  RESOLVE(write);
+
+  char out[256];
+  size_t length = fd == COMMAND_FD ? rewrite_reply(buf, count, out, sizeof(out)) : 0;
+  if (!length)
+    return real_write(fd, buf, count);
+
+  for (size_t done = 0; done < length;) {
+    ssize_t sent = real_write(fd, out + done, length - done);
+    if (sent < 0)
+      return sent;
+    done += (size_t)sent;
+  }
+  return (ssize_t)count;
+}
+
+/* TLS control connections write replies with SSL_write. The symbol only
+ * resolves when vsftpd is linked against OpenSSL, which is the only case
+ * where it is called. */
+int SSL_write(void *ssl, const void *buf, int num) {
+  static int (*real_ssl_write)(void *, const void *, int);
+  if (!real_ssl_write)
+    *(void **)&real_ssl_write = dlsym(RTLD_NEXT, "SSL_write");
+
+  char out[256];
+  size_t length = num > 0 ? rewrite_reply(buf, (size_t)num, out, sizeof(out)) : 0;
+  if (!length)
+    return real_ssl_write(ssl, buf, num);
+
+  int sent = real_ssl_write(ssl, out, (int)length);
+  return sent == (int)length ? num : sent;
+}
@@ -0,0 +1,434 @@
+/*
+ * quota.so - Enforces mini-ftp disk quotas inside vsftpd sessions
+ *
+ * Loaded into vsftpd with LD_PRELOAD. vsftpd has no quota support and no
+ * hook before a transfer, so the checks sit on the calls it makes itself:
+ *
+ *   open   A file opened for writing inside a quota scope is refused with
+ *          EDQUOT once the scope is full. Otherwise it gets a byte budget,
+ *          the space left in the scope when it was opened.
+ *   write  A write past the budget fails with EDQUOT, so an upload stops at
+ *          the limit instead of finishing first.
+ *   reply  vsftpd answers those failures with 553 or 451. The next reply is
+ *          rewritten to a 552 reply of the same length, so it also passes
+ *          through vsftpd's length-prefixed IPC to its SSL process unchanged.
+ *
+ * Scopes are read from QUOTA_SCOPES (written by quota_guard) when a session
+ * chroots, since the file is not visible afterwards. Each line is
+ * "<quota_bytes> <directory>". A directory's usage is the size of every file
+ * below it, except inside other scopes. A quota of 0 only marks a directory
+ * that does not count towards the scope around it.
+ *
+ * Walking a large tree on every upload is slow, so each session caches the
+ * usage of a scope for QUOTA_INTERVAL seconds and keeps it up to date with
+ * its own writes, truncations and deletes. Changes made by other sessions
+ * are picked up when the cache expires, and a rename drops the cache.
+ */
+
+#define _GNU_SOURCE
+#include <dirent.h>
+#include <dlfcn.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <limits.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <time.h>
+#include <unistd.h>
+
+#define DEFAULT_SCOPES_FILE "/etc/vsftpd/quotas/.scopes"
+#define MAX_SCOPES 1024
+#define MAX_FDS 1024
+#define MAX_DEPTH 64
+#define DEFAULT_CACHE_SECONDS 5
+
+/* --- Reply Rewriting --- */
+#define CREATE_FAILED "553 Could not create file."
+#define CREATE_QUOTA "552 Storage quota exceeded"
+#define WRITE_FAILED "451 Failure writing to local file."
+#define WRITE_QUOTA "552 Disk quota exceeded, aborting."
+
+_Static_assert(sizeof(CREATE_FAILED) == sizeof(CREATE_QUOTA), "replies must keep their length");
+_Static_assert(sizeof(WRITE_FAILED) == sizeof(WRITE_QUOTA), "replies must keep their length");
+
+static const struct {
+  const char *from;
+  const char *to;
+} replies[] = {
+  {CREATE_FAILED, CREATE_QUOTA},
+  {WRITE_FAILED, WRITE_QUOTA},
+};
+
+struct scope {
+  dev_t dev;
+  ino_t ino;
+  long long quota;
+  int cached;
+  long long used;   /* Usage when cached, plus this session's changes */
+  time_t measured;  /* When used was last walked, on CLOCK_MONOTONIC */
+};
+
+struct budget {
+  int tracked;
+  int append;
+  long long limit; /* Largest size the file may reach */
+  long long size;  /* Size the file is counted with in its scope's usage */
+  struct scope *scope;
+};
+
+static struct scope scopes[MAX_SCOPES];
+static int scope_count;
+static pid_t loaded_pid;
+static long cache_seconds = DEFAULT_CACHE_SECONDS;
+
+static struct budget budgets[MAX_FDS];
+
+/* Set when a call failed because of a quota, until the reply is rewritten */
+static int quota_failed;
+
+/* --- Real Functions --- */
+static int (*real_open)(const char *, int, ...);
+static int (*real_openat)(int, const char *, int, ...);
+static ssize_t (*real_write)(int, const void *, size_t);
+static int (*real_close)(int);
+static int (*real_chroot)(const char *);
+static int (*real_ftruncate)(int, off_t);
+static int (*real_unlink)(const char *);
+static int (*real_rename)(const char *, const char *);
+
+#define RESOLVE(name) \
+  do { \
+    if (!real_##name) \
+      *(void **)&real_##name = dlsym(RTLD_NEXT, #name); \
+  } while (0)
+
+/* --- Scopes --- */
+static void load_scopes(void) {
+  const char *path = getenv("QUOTA_SCOPES");
+  if (!path || !*path)
+    path = DEFAULT_SCOPES_FILE;
+
+  /* Inside a chroot the file is gone; keep what the session loaded */
+  FILE *file = fopen(path, "re");
+  if (!file)
+    return;
+
+  scope_count = 0;
+  loaded_pid = getpid();
+
+  const char *interval = getenv("QUOTA_INTERVAL");
+  long seconds = interval ? strtol(interval, NULL, 10) : 0;
+  cache_seconds = seconds > 0 ? seconds : DEFAULT_CACHE_SECONDS;
+
+  char *line = NULL;
+  size_t size = 0;
+  while (getline(&line, &size, file) > 0 && scope_count < MAX_SCOPES) {
+    char *dir;
+    long long quota = strtoll(line, &dir, 10);
+    if (dir == line || quota < 0)
+      continue;
+    dir += strspn(dir, " \t");
+    dir[strcspn(dir, "\r\n")] = '\0';
+
+    struct stat st;
+    if (!*dir || stat(dir, &st) != 0 || !S_ISDIR(st.st_mode))
+      continue;
+
+    scopes[scope_count] = (struct scope){.dev = st.st_dev, .ino = st.st_ino, .quota = quota};
+    scope_count++;
+  }
+
+  free(line);
+  fclose(file);
+}
+
+static struct scope *scope_of(const struct stat *st) {
+  for (int i = 0; i < scope_count; i++) {
+    if (scopes[i].dev == st->st_dev && scopes[i].ino == st->st_ino)
+      return &scopes[i];
+  }
+  return NULL;
+}
+
+/* Finds the scope a new file at path belongs to by walking up from its
+ * directory, and stores a path to the scope's root in root */
+static struct scope *find_scope(const char *path, char *root, size_t size) {
+  const char *slash = strrchr(path, '/');
+  int written;
+  if (!slash)
+    written = snprintf(root, size, ".");
+  else if (slash == path)
+    written = snprintf(root, size, "/");
+  else
+    written = snprintf(root, size, "%.*s", (int)(slash - path), path);
+  if (written < 0 || (size_t)written >= size)
+    return NULL;
+
+  struct stat top;
+  if (stat("/", &top) != 0)
+    return NULL;
+
+  for (int depth = 0; depth < MAX_DEPTH; depth++) {
+    struct stat st;
+    if (stat(root, &st) != 0)
+      return NULL;
+
+    struct scope *scope = scope_of(&st);
+    if (scope)
+      return scope;
+    if (st.st_dev == top.st_dev && st.st_ino == top.st_ino)
+      return NULL;
+
+    size_t length = strlen(root);
+    if (length + 4 >= size)
+      return NULL;
+    strcpy(root + length, "/..");
+  }
+  return NULL;
+}
+
+/* Sums the size of the files below dirfd, skipping nested scopes. Takes
+ * ownership of dirfd. */
+static long long usage(int dirfd, int depth) {
+  DIR *dir = fdopendir(dirfd);
+  if (!dir) {
+    real_close(dirfd);
+    return 0;
+  }
+
+  long long total = 0;
+  struct dirent *entry;
+  while ((entry = readdir(dir)) != NULL) {
+    if (strcmp(entry->d_name, ".") == 0 || strcmp(entry->d_name, "..") == 0)
+      continue;
+
+    struct stat st;
+    if (fstatat(dirfd, entry->d_name, &st, AT_SYMLINK_NOFOLLOW) != 0)
+      continue;
+
+    if (S_ISREG(st.st_mode)) {
+      total += st.st_size;
+    } else if (S_ISDIR(st.st_mode) && depth < MAX_DEPTH && !scope_of(&st)) {
+      int fd = real_openat(dirfd, entry->d_name, O_RDONLY | O_DIRECTORY | O_NOFOLLOW | O_CLOEXEC);
+      if (fd >= 0)
+        total += usage(fd, depth + 1);
+    }
+  }
+
+  closedir(dir);
+  return total;
+}
+
+static time_t now(void) {
+  struct timespec ts;
+  clock_gettime(CLOCK_MONOTONIC, &ts);
+  return ts.tv_sec;
+}
+
+/* Returns the usage of the scope rooted at root, walking it only when the
+ * cached usage has expired */
+static long long scope_usage(struct scope *scope, const char *root) {
+  if (scope->cached && now() - scope->measured < cache_seconds)
+    return scope->used;
+
+  int rootfd = real_open(root, O_RDONLY | O_DIRECTORY | O_CLOEXEC);
+  scope->used = rootfd >= 0 ? usage(rootfd, 0) : 0;
+  scope->measured = now();
+  scope->cached = 1;
+  return scope->used;
+}
+
+/* Moves a tracked file to a new size in its scope's cached usage */
+static void resize(struct budget *budget, long long size) {
+  if (budget->scope->cached)
+    budget->scope->used += size - budget->size;
+  budget->size = size;
+}
+
+/* --- Interposed Calls --- */
+static int quota_open(const char *path, int flags, mode_t mode) {
+  RESOLVE(open);
+  RESOLVE(openat);
+  RESOLVE(close);
+
+  if (!(flags & (O_WRONLY | O_RDWR)) || !path)
+    return real_open(path, flags, mode);
+
+  if (loaded_pid != getpid())
+    load_scopes();
+
+  char root[PATH_MAX];
+  struct scope *scope = scope_count ? find_scope(path, root, sizeof(root)) : NULL;
+  if (!scope || scope->quota == 0)
+    return real_open(path, flags, mode);
+
+  long long used = scope_usage(scope, root);
+  if (used >= scope->quota) {
+    quota_failed = 1;
+    errno = EDQUOT;
+    return -1;
+  }
+
+  /* The current size is already part of the usage */
+  struct stat st;
+  long long existing = stat(path, &st) == 0 && S_ISREG(st.st_mode) ? st.st_size : 0;
+
+  int fd = real_open(path, flags, mode);
+  if (fd >= 0 && fd < MAX_FDS) {
+    budgets[fd].tracked = 1;
+    budgets[fd].append = (flags & O_APPEND) != 0;
+    budgets[fd].limit = existing + (scope->quota - used);
+    budgets[fd].size = existing;
+    budgets[fd].scope = scope;
+    if (flags & O_TRUNC)
+      resize(&budgets[fd], 0);
+  }
+  return fd;
+}
+
+int open(const char *path, int flags, ...) {
+  mode_t mode = 0;
+  if (flags & (O_CREAT | O_TMPFILE)) {
+    va_list args;
+    va_start(args, flags);
+    mode = va_arg(args, int);
+    va_end(args);
+  }
+  return quota_open(path, flags, mode);
+}
+
+int openat(int dirfd, const char *path, int flags, ...) {
+  mode_t mode = 0;
+  if (flags & (O_CREAT | O_TMPFILE)) {
+    va_list args;
+    va_start(args, flags);
+    mode = va_arg(args, int);
+    va_end(args);
+  }
+
+  /* vsftpd opens uploads with open(); only paths open() could see are checked */
+  if (dirfd == AT_FDCWD || path[0] == '/')
+    return quota_open(path, flags, mode);
+
+  RESOLVE(openat);
+  return real_openat(dirfd, path, flags, mode);
+}
+
+int creat(const char *path, mode_t mode) {
+  return quota_open(path, O_CREAT | O_WRONLY | O_TRUNC, mode);
+}
+
+int close(int fd) {
+  RESOLVE(close);
+  if (fd >= 0 && fd < MAX_FDS)
+    budgets[fd].tracked = 0;
+  return real_close(fd);
+}
+
+/* vsftpd truncates a file it overwrites after opening it */
+int ftruncate(int fd, off_t length) {
+  RESOLVE(ftruncate);
+  int result = real_ftruncate(fd, length);
+  if (result == 0 && fd >= 0 && fd < MAX_FDS && budgets[fd].tracked)
+    resize(&budgets[fd], length);
+  return result;
+}
+
+int unlink(const char *path) {
+  RESOLVE(unlink);
+
+  struct stat st;
+  char root[PATH_MAX];
+  struct scope *scope = NULL;
+  if (scope_count && lstat(path, &st) == 0 && S_ISREG(st.st_mode))
+    scope = find_scope(path, root, sizeof(root));
+
+  int result = real_unlink(path);
+  if (result == 0 && scope && scope->cached)
+    scope->used -= st.st_size;
+  return result;
+}
+
+/* A rename can move files between scopes, so every scope is walked again */
+int rename(const char *from, const char *to) {
+  RESOLVE(rename);
+  int result = real_rename(from, to);
+  if (result == 0) {
+    for (int i = 0; i < scope_count; i++)
+      scopes[i].cached = 0;
+  }
+  return result;
+}
+
+int chroot(const char *path) {
+  RESOLVE(chroot);
+  load_scopes();
+  return real_chroot(path);
+}
+
+/* Returns buf with a quota failure reply rewritten into out, or NULL */
+static const void *rewrite_reply(const void *buf, size_t count, char *out, size_t size) {
+  if (!quota_failed || count > size)
+    return NULL;
+
+  for (size_t i = 0; i < sizeof(replies) / sizeof(replies[0]); i++) {
+    size_t length = strlen(replies[i].from);
+    if (count >= length && memcmp(buf, replies[i].from, length) == 0) {
+      memcpy(out, buf, count);
+      memcpy(out, replies[i].to, length);
+      quota_failed = 0;
+      return out;
+    }
+  }
+
+  /* Any other reply means the failure was answered some other way */
+  const char *text = buf;
+  if (count >= 4 && text[0] >= '1' && text[0] <= '5' && text[3] == ' ')
+    quota_failed = 0;
+  return NULL;
+}
+
+ssize_t write(int fd, const void *buf, size_t count) {
+  RESOLVE(write);
+
+  if (fd >= 0 && fd < MAX_FDS && budgets[fd].tracked) {
+    long long position;
+    struct stat st;
+    if (budgets[fd].append)
+      position = fstat(fd, &st) == 0 ? st.st_size : 0;
+    else
+      position = lseek(fd, 0, SEEK_CUR);
+
+    if (position >= 0 && position + (long long)count > budgets[fd].limit) {
+      quota_failed = 1;
+      errno = EDQUOT;
+      return -1;
+    }
+
+    ssize_t written = real_write(fd, buf, count);
+    if (written > 0 && position >= 0 && position + written > budgets[fd].size)
+      resize(&budgets[fd], position + written);
+    return written;
+  }
+
+  char out[256];
+  const void *reply = rewrite_reply(buf, count, out, sizeof(out));
+  return real_write(fd, reply ? reply : buf, count);
+}
+
+/* TLS control connections write replies with SSL_write. The symbol only
+ * resolves when vsftpd is linked against OpenSSL, which is the only case
+ * where it is called. */
+int SSL_write(void *ssl, const void *buf, int num) {
+  static int (*real_ssl_write)(void *, const void *, int);
+  if (!real_ssl_write)
+    *(void **)&real_ssl_write = dlsym(RTLD_NEXT, "SSL_write");
+
+  char out[256];
+  const void *reply = num > 0 ? rewrite_reply(buf, (size_t)num, out, sizeof(out)) : NULL;
+  return real_ssl_write(ssl, reply ? reply : buf, num);
+}
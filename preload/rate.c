@@ -0,0 +1,217 @@
+/*
+ * rate.so - Throttles uploads and downloads of vsftpd sessions separately
+ *
+ * Loaded into vsftpd with LD_PRELOAD. vsftpd's local_max_rate applies one
+ * rate to both directions, so the user's rates are applied here instead:
+ *
+ *   open      vsftpd opens the user's file in user_config_dir once the user
+ *             has logged in, before the session chroots. The user's rates
+ *             are read from RATE_DIR (written by set_rate_limit) then.
+ *   read      Reads from a data connection count towards the upload rate.
+ *   write     Writes to a data connection, and files sent with sendfile,
+ *             count towards the download rate.
+ *
+ * Each call moves at most a tenth of a second's worth of data and then sleeps
+ * until the connection is back under its rate, so transfers stay smooth.
+ * The control connection and vsftpd's own sockets between its processes are
+ * never throttled. Each file in RATE_DIR holds "<upload> <download>" in bytes
+ * per second, where 0 leaves a direction unlimited.
+ */
+
+#define _GNU_SOURCE
+#include <dlfcn.h>
+#include <errno.h>
+#include <fcntl.h>
+#include <limits.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/sendfile.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <sys/types.h>
+#include <time.h>
+#include <unistd.h>
+
+#define DEFAULT_USER_CONFIG_DIR "/etc/vsftpd/users"
+#define DEFAULT_RATE_DIR "/etc/vsftpd/rates"
+#define MAX_FDS 1024
+
+/* vsftpd keeps the control connection on stdin */
+#define COMMAND_FD 0
+
+/* Smallest amount moved per call, so low rates still make progress */
+#define MIN_CHUNK 4096
+
+enum direction { UPLOAD, DOWNLOAD };
+
+struct meter {
+  ino_t ino;
+  struct timespec start;
+  long long bytes;
+};
+
+/* Bytes per second for each direction, 0 when unlimited */
+static long long rates[2];
+static struct meter meters[2][MAX_FDS];
+
+/* --- Real Functions --- */
+static int (*real_open)(const char *, int, ...);
+static ssize_t (*real_read)(int, void *, size_t);
+static ssize_t (*real_write)(int, const void *, size_t);
+static ssize_t (*real_sendfile)(int, int, off_t *, size_t);
+
+#define RESOLVE(name) \
+  do { \
+    if (!real_##name) \
+      *(void **)&real_##name = dlsym(RTLD_NEXT, #name); \
+  } while (0)
+
+/* --- Rates --- */
+/* Loads the rates of the user whose config file vsftpd is opening */
+static void load_rates(const char *path) {
+  const char *dir = getenv("USER_CONFIG_DIR");
+  if (!dir || !*dir)
+    dir = DEFAULT_USER_CONFIG_DIR;
+
+  size_t length = strlen(dir);
+  if (strncmp(path, dir, length) != 0 || path[length] != '/')
+    return;
+  const char *name = path + length + 1;
+  if (!*name || strchr(name, '/'))
+    return;
+
+  const char *rate_dir = getenv("RATE_DIR");
+  if (!rate_dir || !*rate_dir)
+    rate_dir = DEFAULT_RATE_DIR;
+
+  char rate_file[PATH_MAX];
+  int written = snprintf(rate_file, sizeof(rate_file), "%s/%s", rate_dir, name);
+  if (written < 0 || (size_t)written >= sizeof(rate_file))
+    return;
+
+  rates[UPLOAD] = rates[DOWNLOAD] = 0;
+  FILE *file = fopen(rate_file, "re");
+  if (!file)
+    return;
+
+  long long upload, download;
+  if (fscanf(file, "%lld %lld", &upload, &download) == 2 && upload >= 0 && download >= 0) {
+    rates[UPLOAD] = upload;
+    rates[DOWNLOAD] = download;
+  }
+  fclose(file);
+}
+
+/* --- Throttling --- */
+/* Returns the meter for a data connection, or NULL when fd is not one or
+ * the direction is unlimited. A new connection on the same fd starts over. */
+static struct meter *meter_of(int fd, enum direction direction) {
+  if (!rates[direction] || fd == COMMAND_FD || fd < 0 || fd >= MAX_FDS)
+    return NULL;
+
+  struct stat st;
+  if (fstat(fd, &st) != 0 || !S_ISSOCK(st.st_mode))
+    return NULL;
+
+  struct sockaddr_storage local;
+  socklen_t length = sizeof(local);
+  if (getsockname(fd, (struct sockaddr *)&local, &length) != 0 ||
+      (local.ss_family != AF_INET && local.ss_family != AF_INET6))
+    return NULL;
+
+  struct meter *meter = &meters[direction][fd];
+  if (meter->ino != st.st_ino) {
+    meter->ino = st.st_ino;
+    meter->bytes = 0;
+    clock_gettime(CLOCK_MONOTONIC, &meter->start);
+  }
+  return meter;
+}
+
+/* Limits a call to a tenth of a second's worth of data */
+static size_t chunk(enum direction direction, size_t count) {
+  size_t limit = (size_t)(rates[direction] / 10);
+  if (limit < MIN_CHUNK)
+    limit = MIN_CHUNK;
+  return count < limit ? count : limit;
+}
+
+/* Counts moved bytes and sleeps until the connection is under its rate */
+static void account(struct meter *meter, enum direction direction, ssize_t moved) {
+  if (moved <= 0)
+    return;
+  meter->bytes += moved;
+
+  double due = (double)meter->bytes / (double)rates[direction];
+  struct timespec now;
+  clock_gettime(CLOCK_MONOTONIC, &now);
+  double elapsed = (double)(now.tv_sec - meter->start.tv_sec) + (double)(now.tv_nsec - meter->start.tv_nsec) / 1e9;
+  if (elapsed >= due)
+    return;
+
+  double wait = due - elapsed;
+  struct timespec delay = {(time_t)wait, (long)((wait - (double)(time_t)wait) * 1e9)};
+  while (nanosleep(&delay, &delay) != 0 && errno == EINTR)
+    ;
+}
+
+/* --- Interposed Calls --- */
+int open(const char *path, int flags, ...) {
+  RESOLVE(open);
+
+  mode_t mode = 0;
+  if (flags & (O_CREAT | O_TMPFILE)) {
+    va_list args;
+    va_start(args, flags);
+    mode = va_arg(args, mode_t);
+    va_end(args);
+  }
+
+  if (!(flags & (O_WRONLY | O_RDWR)))
+    load_rates(path);
+  return real_open(path, flags, mode);
+}
+
+ssize_t read(int fd, void *buf, size_t count) {
+  RESOLVE(read);
+
+  struct meter *meter = meter_of(fd, UPLOAD);
+  if (!meter)
+    return real_read(fd, buf, count);
+
+  ssize_t moved = real_read(fd, buf, chunk(UPLOAD, count));
+  int saved = errno;
+  account(meter, UPLOAD, moved);
+  errno = saved;
+  return moved;
+}
+
+ssize_t write(int fd, const void *buf, size_t count) {
+  RESOLVE(write);
+
+  struct meter *meter = meter_of(fd, DOWNLOAD);
+  if (!meter)
+    return real_write(fd, buf, count);
+
+  ssize_t moved = real_write(fd, buf, chunk(DOWNLOAD, count));
+  int saved = errno;
+  account(meter, DOWNLOAD, moved);
+  errno = saved;
+  return moved;
+}
+
+ssize_t sendfile(int out_fd, int in_fd, off_t *offset, size_t count) {
+  RESOLVE(sendfile);
+
+  struct meter *meter = meter_of(out_fd, DOWNLOAD);
+  if (!meter)
+    return real_sendfile(out_fd, in_fd, offset, count);
+
+  ssize_t moved = real_sendfile(out_fd, in_fd, offset, chunk(DOWNLOAD, count));
+  int saved = errno;
+  account(meter, DOWNLOAD, moved);
+  errno = saved;
+  return moved;
+}
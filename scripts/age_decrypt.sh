@@ -0,0 +1,53 @@
+#!/usr/bin/env bash
+# age_decrypt - Decrypts an age-encrypted config value
+#
+# Usage: age_decrypt <username> <field> < ciphertext
+#
+# Prints the plaintext of an ASCII-armored age ciphertext, decrypted with the
+# identity in AGE_KEY_FILE. Errors name the user and field, so a broken
+# value in config.yaml is easy to find.
+#
+# The plaintext is printed byte for byte, without a newline. A single
+# trailing newline is dropped, so values encrypted with echo work too. Any
+# other line break is an error, since passwords cannot contain one.
+
+AGE_KEY_FILE="${AGE_KEY_FILE:-/etc/ftp/age.key}"
+
+NAME="$1"
+FIELD="$2"
+
+# --- Error Handling ---
+if [ -z "$NAME" ] || [ -z "$FIELD" ]; then
+  log ERROR "Usage: age_decrypt <username> <field> < ciphertext" >&2
+  exit 1
+fi
+
+if [ ! -r "$AGE_KEY_FILE" ]; then
+  log ERROR "❌ Cannot decrypt $FIELD for user '$NAME': key file $AGE_KEY_FILE is missing or unreadable." >&2
+  exit 1
+fi
+
+# --- Decrypt ---
+ERROR_FILE="$(mktemp)"
+trap 'rm -f "$ERROR_FILE"' EXIT
+
+# Command substitution strips every trailing newline, so a sentinel keeps them
+if ! PLAINTEXT="$(age -d -i "$AGE_KEY_FILE" 2>"$ERROR_FILE" && printf x)"; then
+  log ERROR "❌ Cannot decrypt $FIELD for user '$NAME': $(head -n 1 "$ERROR_FILE" | sed 's/^age: error: //')" >&2
+  exit 1
+fi
+PLAINTEXT="${PLAINTEXT%x}"
+PLAINTEXT="${PLAINTEXT%$'\n'}"
+
+if [ -z "$PLAINTEXT" ]; then
+  log ERROR "❌ Cannot decrypt $FIELD for user '$NAME': the decrypted value is empty." >&2
+  exit 1
+fi
+
+if [[ "$PLAINTEXT" == *$'\n'* || "$PLAINTEXT" == *$'\r'* ]]; then
+  log ERROR "❌ Cannot decrypt $FIELD for user '$NAME': the decrypted value contains a line break." >&2
+  exit 1
+fi
+
+printf "%s" "$PLAINTEXT"
+exit 0
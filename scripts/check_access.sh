@@ -0,0 +1,92 @@
+#!/usr/bin/env bash
+# check_access - Decides whether a user may log in from a remote address
+#
+# Usage: check_access [username] [remote_ip]
+#
+# When run by pam_exec the username and address are taken from PAM_USER and
+# PAM_RHOST. Deny rules always win. If the user has its own allow list it
+# replaces the server allow list; an empty allow list permits any address.
+# Logins are also rejected once the user reaches its session limit.
+
+ACCESS_DIR="${ACCESS_DIR:-/etc/vsftpd/access}"
+
+# pam_exec runs with an empty environment, so make sure our helpers resolve
+export PATH="${PATH:-/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin}"
+
+NAME="${1:-$PAM_USER}"
+REMOTE_IP="${2:-$PAM_RHOST}"
+
+# pam_exec discards stdout, so write straight to the container log
+LOG_TARGET="/dev/stdout"
+if [ -n "$PAM_TYPE" ]; then
+  LOG_TARGET="/proc/1/fd/1"
+fi
+
+reject() {
+  log WARN "🚫 Rejected login for '$NAME' from $REMOTE_IP: $1" >> "$LOG_TARGET"
+  exit 1
+}
+
+# --- Error Handling ---
+if [ -z "$NAME" ] || [ -z "$REMOTE_IP" ]; then
+  log ERROR "Usage: check_access <username> <remote_ip>" >> "$LOG_TARGET"
+  exit 1
+fi
+
+# Drop any IPv6 zone index, and unwrap IPv4 clients reported in IPv4-mapped
+# form by a dual-stack listener
+REMOTE_IP="${REMOTE_IP%%\%*}"
+if [[ "${REMOTE_IP,,}" =~ ^::ffff:[0-9.]+$ ]]; then
+  REMOTE_IP="${REMOTE_IP:7}"
+fi
+
+# --- Collect Rules ---
+read_rules() {
+  local kind="$1" file="$2"
+  [ -f "$file" ] || return 0
+  awk -v kind="$kind" '$1 == kind { print $2 }' "$file"
+}
+
+GLOBAL_FILE="$ACCESS_DIR/global"
+USER_FILE="$ACCESS_DIR/users/$NAME"
+
+DENY_CIDRS="$(read_rules deny "$GLOBAL_FILE") $(read_rules deny "$USER_FILE")"
+ALLOW_CIDRS="$(read_rules allow "$USER_FILE")"
+if [ -z "$ALLOW_CIDRS" ]; then
+  ALLOW_CIDRS="$(read_rules allow "$GLOBAL_FILE")"
+fi
+
+# --- Deny Rules ---
+for cidr in $DENY_CIDRS; do
+  if cidr_match "$REMOTE_IP" "$cidr"; then
+    reject "address matches deny rule $cidr"
+  fi
+done
+
+# --- Allow Rules ---
+if [ -n "$ALLOW_CIDRS" ]; then
+  ALLOWED=false
+  for cidr in $ALLOW_CIDRS; do
+    if cidr_match "$REMOTE_IP" "$cidr"; then
+      ALLOWED=true
+      break
+    fi
+  done
+
+  if [ "$ALLOWED" = false ]; then
+    reject "address is not in the allow list"
+  fi
+fi
+
+# --- Session Limit ---
+SESSION_FILE="$ACCESS_DIR/sessions/$NAME"
+if [ -f "$SESSION_FILE" ]; then
+  MAX_SESSIONS="$(cat "$SESSION_FILE")"
+  ACTIVE_SESSIONS="$(user_sessions "$NAME" | wc -l)"
+
+  if [ "$MAX_SESSIONS" -gt 0 ] && [ "$ACTIVE_SESSIONS" -ge "$MAX_SESSIONS" ]; then
+    reject "user already has $ACTIVE_SESSIONS of $MAX_SESSIONS sessions"
+  fi
+fi
+
+exit 0
@@ -0,0 +1,152 @@
+#!/usr/bin/env bash
+# check_ldap - Authenticates a user against the LDAP directory
+#
+# Usage: check_ldap [username] < password
+#
+# Called by check_password for users that are not in the virtual user
+# database. The user is looked up with LDAP_USER_FILTER, must match
+# LDAP_GROUP_FILTER when one is set, and must bind with its own password.
+# In both filters %u is replaced by the username and %d by the user's DN.
+# On the first successful login a home is created under /ftp, named after
+# the LDAP_HOME_ATTRIBUTE value, owned by the virtual service account, and
+# shared with the writable admins. Each home is registered in LDAP_HOMES to
+# the first LDAP user that gets it, so users whose attributes end in the same
+# name are rejected instead of sharing a home.
+
+LDAP_CONFIG="${LDAP_CONFIG:-/etc/vsftpd/ldap/ldap.conf}"
+FTP_ROOT="${FTP_ROOT:-/ftp}"
+LDAP_HOMES="${LDAP_HOMES:-$FTP_ROOT/.ldap-homes}"
+
+# pam_exec runs with an empty environment, so make sure our helpers resolve
+export PATH="${PATH:-/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin}"
+
+NAME="${1:-$PAM_USER}"
+
+# pam_exec discards stdout, so write straight to the container log
+LOG_TARGET="/dev/stdout"
+if [ -n "$PAM_TYPE" ]; then
+  LOG_TARGET="/proc/1/fd/1"
+fi
+
+reject() {
+  log WARN "🚫 LDAP login failed for '$NAME': $1" >> "$LOG_TARGET"
+  exit 1
+}
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  echo "Usage: check_ldap <username> < password" >&2
+  exit 1
+fi
+
+if [ ! -f "$LDAP_CONFIG" ]; then
+  exit 1
+fi
+. "$LDAP_CONFIG"
+
+# An empty password would be an unauthenticated bind, which always succeeds
+IFS= read -r -d '' PASSWORD
+if [ -z "$PASSWORD" ]; then
+  exit 1
+fi
+
+# --- Helpers ---
+# Escapes a value for use inside an LDAP filter (RFC 4515)
+escape_filter() {
+  local value="$1"
+  value="${value//\\/\\5c}"
+  value="${value//\*/\\2a}"
+  value="${value//(/\\28}"
+  value="${value//)/\\29}"
+  printf "%s" "$value"
+}
+
+# Replaces %u and %d in a filter
+expand_filter() {
+  local filter="$1"
+  filter="${filter//%u/$(escape_filter "$NAME")}"
+  filter="${filter//%d/$(escape_filter "$USER_DN")}"
+  printf "%s" "$filter"
+}
+
+# Searches the directory as the configured bind DN
+ldap_search() {
+  local bind_opts=()
+  if [ -n "$LDAP_BIND_DN" ]; then
+    bind_opts=(-D "$LDAP_BIND_DN" -y "$LDAP_BIND_PASSWORD_FILE")
+  fi
+  ldapsearch -LLL -x -o ldif-wrap=no -o nettimeout=5 -H "$LDAP_URL" "${bind_opts[@]}" -b "$LDAP_BASE_DN" "$@"
+}
+
+# --- Find User ---
+USER_DN=""
+if ! ENTRY="$(ldap_search "$(expand_filter "$LDAP_USER_FILTER")" dn "$LDAP_HOME_ATTRIBUTE" 2>&1)"; then
+  reject "search failed: $ENTRY"
+fi
+
+if [ "$(echo "$ENTRY" | grep -c '^dn: ')" -ne 1 ]; then
+  exit 1
+fi
+USER_DN="$(echo "$ENTRY" | sed -n 's/^dn: //p')"
+HOME_VALUE="$(echo "$ENTRY" | awk -v attr="$LDAP_HOME_ATTRIBUTE" \
+  'tolower($1) == tolower(attr) ":" { sub(/^[^:]*: /, ""); print; exit }')"
+
+# --- Check Group ---
+if [ -n "$LDAP_GROUP_FILTER" ]; then
+  if ! GROUPS_FOUND="$(ldap_search "$(expand_filter "$LDAP_GROUP_FILTER")" dn 2>&1)"; then
+    reject "group search failed: $GROUPS_FOUND"
+  fi
+  if ! echo "$GROUPS_FOUND" | grep -q '^dn: '; then
+    reject "not in the allowed group"
+  fi
+fi
+
+# --- Verify Password ---
+PASSWORD_FILE="$(mktemp)"
+trap 'rm -f "$PASSWORD_FILE"' EXIT
+printf "%s" "$PASSWORD" > "$PASSWORD_FILE"
+
+if ! ldapwhoami -x -o nettimeout=5 -H "$LDAP_URL" -D "$USER_DN" -y "$PASSWORD_FILE" >/dev/null 2>&1; then
+  exit 1
+fi
+
+# --- Create Home ---
+# Absolute paths such as homeDirectory values keep only their last component
+HOME_NAME="$(basename "${HOME_VALUE:-$NAME}")"
+if [[ ! "$HOME_NAME" =~ ^[a-zA-Z0-9_][a-zA-Z0-9_.@-]*$ ]]; then
+  reject "invalid home '$HOME_VALUE' from attribute $LDAP_HOME_ATTRIBUTE"
+fi
+
+# Config users keep their homes to themselves
+if [ "$HOME_NAME" != "$NAME" ] && user_account "$HOME_NAME" >/dev/null; then
+  reject "home '$HOME_NAME' belongs to another user"
+fi
+
+# The registry lives next to the homes so it survives a new container
+exec 9>>"$LDAP_HOMES"
+flock 9
+chmod 600 "$LDAP_HOMES"
+HOME_OWNER="$(awk -v home="$HOME_NAME" '$1 == home { print $2; exit }' "$LDAP_HOMES")"
+if [ -z "$HOME_OWNER" ]; then
+  echo "$HOME_NAME $NAME" >> "$LDAP_HOMES"
+elif [ "$HOME_OWNER" != "$NAME" ]; then
+  reject "home '$HOME_NAME' belongs to LDAP user '$HOME_OWNER'"
+fi
+
+FTP_DIR="$FTP_ROOT/$HOME_NAME"
+if [ ! -d "$FTP_DIR" ]; then
+  mkdir -p "$FTP_DIR"
+  chown "$LDAP_SERVICE_ACCOUNT:$LDAP_SERVICE_ACCOUNT" "$FTP_DIR"
+  chmod 755 "$FTP_DIR"
+  if ! share_home "$FTP_DIR" >> "$LOG_TARGET"; then
+    exit 1
+  fi
+  log INFO "📂 Created home $FTP_DIR for LDAP user '$NAME'" >> "$LOG_TARGET"
+fi
+
+if ! set_user_option "$NAME" guest_username "$LDAP_SERVICE_ACCOUNT" >> "$LOG_TARGET" || \
+  ! set_user_option "$NAME" local_root "$FTP_DIR" >> "$LOG_TARGET"; then
+  exit 1
+fi
+
+exit 0
@@ -0,0 +1,51 @@
+#!/usr/bin/env bash
+# check_password - Verifies a virtual user's password
+#
+# Usage: check_password [username] < password
+#
+# Run by pam_exec with expose_authtok, which passes the password on stdin
+# followed by a NUL byte. The password is hashed with the salt stored in
+# VIRTUAL_USER_DB and compared with the stored hash. Users that are not in
+# the database are passed on to check_ldap when LDAP is configured.
+
+VIRTUAL_USER_DB="${VIRTUAL_USER_DB:-/etc/vsftpd/virtual_users}"
+
+# pam_exec runs with an empty environment, so make sure our helpers resolve
+export PATH="${PATH:-/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin}"
+
+NAME="${1:-$PAM_USER}"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  echo "Usage: check_password <username> < password" >&2
+  exit 1
+fi
+
+IFS= read -r -d '' PASSWORD
+if [ -z "$PASSWORD" ]; then
+  exit 1
+fi
+
+HASH="$(awk -F: -v name="$NAME" '$1 == name { print $2; exit }' "$VIRTUAL_USER_DB" 2>/dev/null)"
+if [ -z "$HASH" ]; then
+  printf "%s\0" "$PASSWORD" | check_ldap "$NAME"
+  exit $?
+fi
+
+# --- Verify ---
+# Hashes look like $<id>$<salt>$<digest>. Users created here use SHA-512,
+# imported users may bring MD5, SHA-256 or Apache MD5 hashes.
+SALT="$(echo "$HASH" | cut -d'$' -f3)"
+case "$(echo "$HASH" | cut -d'$' -f2)" in
+  1) CANDIDATE="$(printf "%s\n" "$PASSWORD" | mkpasswd -m md5 -S "$SALT")" ;;
+  5) CANDIDATE="$(printf "%s\n" "$PASSWORD" | mkpasswd -m sha256 -S "$SALT")" ;;
+  6) CANDIDATE="$(printf "%s\n" "$PASSWORD" | mkpasswd -m sha512 -S "$SALT")" ;;
+  apr1) CANDIDATE="$(printf "%s\n" "$PASSWORD" | openssl passwd -apr1 -salt "$SALT" -stdin)" ;;
+  *) exit 1 ;;
+esac
+
+if [ "$CANDIDATE" != "$HASH" ]; then
+  exit 1
+fi
+
+exit 0
@@ -0,0 +1,149 @@
+#!/usr/bin/env bash
+# cidr_match - Checks whether an IP address falls inside a CIDR block
+#
+# Usage:
+#   cidr_match <ip> <cidr>        Exit 0 if <ip> is inside <cidr>, 1 if not
+#   cidr_match --validate <cidr>  Exit 0 if <cidr> is a valid CIDR block
+#
+# Both IPv4 and IPv6 are supported. An address never matches a block of the
+# other family. Exit code 2 is returned for malformed input.
+
+# --- Convert dotted IPv4 address to integer ---
+ip_to_int() {
+  local ip="$1"
+  local IFS=.
+  local -a octets
+
+  if [[ ! "$ip" =~ ^[0-9]{1,3}(\.[0-9]{1,3}){3}$ ]]; then
+    return 1
+  fi
+
+  read -r -a octets <<< "$ip"
+  for octet in "${octets[@]}"; do
+    if [ "$((10#$octet))" -gt 255 ]; then
+      return 1
+    fi
+  done
+
+  echo $(( (10#${octets[0]} << 24) | (10#${octets[1]} << 16) | (10#${octets[2]} << 8) | 10#${octets[3]} ))
+}
+
+# --- Expand IPv6 address to 32 lowercase hex digits ---
+ip6_to_hex() {
+  local ip="${1,,}"
+  local -a groups head tail
+  local hex=""
+
+  [[ "$ip" == *:* ]] || return 1
+  [[ "$ip" =~ ^[0-9a-f:.]+$ ]] || return 1
+
+  # Embedded IPv4 suffix, e.g. ::ffff:192.0.2.1
+  if [[ "$ip" == *.* ]]; then
+    local v4="${ip##*:}" v4_int
+    v4_int=$(ip_to_int "$v4") || return 1
+    ip="${ip%:*}:$(printf '%x:%x' $(( v4_int >> 16 )) $(( v4_int & 0xFFFF )))"
+  fi
+
+  if [[ "$ip" == *::* ]]; then
+    [[ "${ip#*::}" != *::* ]] || return 1
+    IFS=: read -r -a head <<< "${ip%%::*}"
+    IFS=: read -r -a tail <<< "${ip#*::}"
+    local missing=$(( 8 - ${#head[@]} - ${#tail[@]} ))
+    [ "$missing" -ge 1 ] || return 1
+    groups=("${head[@]}")
+    for _ in $(seq 1 "$missing"); do groups+=("0"); done
+    groups+=("${tail[@]}")
+  else
+    IFS=: read -r -a groups <<< "$ip"
+  fi
+
+  [ "${#groups[@]}" -eq 8 ] || return 1
+  for group in "${groups[@]}"; do
+    [[ "$group" =~ ^[0-9a-f]{1,4}$ ]] || return 1
+    hex+=$(printf '%04x' "0x$group")
+  done
+
+  echo "$hex"
+}
+
+# --- Split CIDR into family, network and prefix length ---
+parse_cidr() {
+  local cidr="$1"
+  local network="${cidr%/*}"
+  local prefix="" max network_value family
+
+  if network_value=$(ip_to_int "$network"); then
+    family=4
+    max=32
+  elif network_value=$(ip6_to_hex "$network"); then
+    family=6
+    max=128
+  else
+    return 1
+  fi
+
+  prefix="$max"
+  if [[ "$cidr" == */* ]]; then
+    prefix="${cidr#*/}"
+  fi
+
+  if [[ ! "$prefix" =~ ^[0-9]{1,3}$ ]] || [ "$prefix" -gt "$max" ]; then
+    return 1
+  fi
+
+  echo "$family $network_value $prefix"
+}
+
+# --- Compare the first <prefix> bits of two expanded IPv6 addresses ---
+ip6_prefix_match() {
+  local ip_hex="$1" network_hex="$2" prefix="$3"
+  local nibbles=$(( prefix / 4 )) bits=$(( prefix % 4 ))
+
+  [ "${ip_hex:0:$nibbles}" = "${network_hex:0:$nibbles}" ] || return 1
+  [ "$bits" -eq 0 ] && return 0
+
+  local mask=$(( (0xF << (4 - bits)) & 0xF ))
+  [ $(( 0x${ip_hex:$nibbles:1} & mask )) -eq $(( 0x${network_hex:$nibbles:1} & mask )) ]
+}
+
+# --- Validate Arguments ---
+if [ $# -ne 2 ]; then
+  echo "Usage: cidr_match <ip> <cidr> | cidr_match --validate <cidr>" >&2
+  exit 2
+fi
+
+if [ "$1" = "--validate" ]; then
+  parse_cidr "$2" >/dev/null || exit 2
+  exit 0
+fi
+
+read -r FAMILY NETWORK PREFIX <<< "$(parse_cidr "$2")"
+[ -n "$FAMILY" ] || exit 2
+
+if IP_INT=$(ip_to_int "$1"); then
+  IP_FAMILY=4
+elif IP_HEX=$(ip6_to_hex "$1"); then
+  IP_FAMILY=6
+else
+  exit 2
+fi
+
+# Addresses never match blocks of the other family
+[ "$IP_FAMILY" = "$FAMILY" ] || exit 1
+
+# --- Compare masked addresses ---
+if [ "$FAMILY" = "6" ]; then
+  ip6_prefix_match "$IP_HEX" "$NETWORK" "$PREFIX" && exit 0
+  exit 1
+fi
+
+if [ "$PREFIX" -eq 0 ]; then
+  exit 0
+fi
+
+MASK=$(( (0xFFFFFFFF << (32 - PREFIX)) & 0xFFFFFFFF ))
+if [ $(( IP_INT & MASK )) -eq $(( NETWORK & MASK )) ]; then
+  exit 0
+fi
+
+exit 1
@@ -0,0 +1,99 @@
+#!/usr/bin/env bash
+# create_group - Creates a shared folder for a group of FTP users
+#
+# Usage: create_group <name> <gid> <directory> <member>...
+#
+# The folder lives in $GROUP_ROOT/<name>, is owned by the group and has the
+# setgid bit, so every file in it stays group-owned. It is bind-mounted at
+# /ftp/<member>/<directory> because members are chrooted to their own trees.
+# Virtual users share a service account, so that account joins the group.
+# Bind mounts need the SYS_ADMIN capability. Pass an empty gid to use the
+# next free one.
+
+GROUP_ROOT="${GROUP_ROOT:-/ftp/.groups}"
+
+NAME="$1"
+GID="$2"
+DIRECTORY="$3"
+shift 3 2>/dev/null
+MEMBERS=("$@")
+
+# --- Error Handling ---
+if [ -z "$NAME" ] || [ -z "$DIRECTORY" ] || [ ${#MEMBERS[@]} -eq 0 ]; then
+  log ERROR "Usage: create_group <name> <gid> <directory> <member>..."
+  exit 1
+fi
+
+if [[ ! "$NAME" =~ ^[a-zA-Z_][a-zA-Z0-9_.-]*$ ]]; then
+  log ERROR "Invalid group name: '$NAME'. Allowed characters: a-z, A-Z, 0-9, ., -, _"
+  exit 1
+fi
+
+if [ -n "$GID" ] && [[ ! "$GID" =~ ^[0-9]+$ ]]; then
+  log ERROR "Invalid gid '$GID' for group '$NAME'. Expected a number."
+  exit 1
+fi
+
+if [[ ! "$DIRECTORY" =~ ^[a-zA-Z0-9_.-]+$ ]] || [ "$DIRECTORY" = "." ] || [ "$DIRECTORY" = ".." ]; then
+  log ERROR "Invalid directory '$DIRECTORY' for group '$NAME'. Expected a single folder name."
+  exit 1
+fi
+
+for member in "${MEMBERS[@]}"; do
+  if ! user_account "$member" >/dev/null; then
+    log ERROR "Group '$NAME' member '$member' does not exist"
+    exit 1
+  fi
+done
+
+# Every user already has a private group with its own name
+if getent group "$NAME" >/dev/null; then
+  log ERROR "Group '$NAME' already exists"
+  exit 1
+fi
+
+# --- Create Group ---
+if [ -z "$GID" ]; then
+  GID=$(($(getent group | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))
+fi
+
+log DEBUG "🔧 Creating group $NAME (GID: $GID)"
+if ! addgroup -g "$GID" "$NAME"; then
+  log ERROR "Failed to create group '$NAME'"
+  exit 1
+fi
+
+# --- Create Shared Folder ---
+SHARED_DIR="$GROUP_ROOT/$NAME"
+mkdir -p "$SHARED_DIR"
+chmod 711 "$GROUP_ROOT"
+if ! chown "root:$NAME" "$SHARED_DIR" || ! chmod 2775 "$SHARED_DIR"; then
+  log ERROR "Failed to set ownership for '$SHARED_DIR'"
+  exit 1
+fi
+
+# --- Add Members ---
+for member in "${MEMBERS[@]}"; do
+  account="$(user_account "$member")"
+  if ! addgroup "$account" "$NAME" 2>/dev/null && ! id -nG "$account" | grep -qw "$NAME"; then
+    log ERROR "Failed to add '$member' to group '$NAME'"
+    exit 1
+  fi
+
+  MOUNT_POINT="/ftp/$member/$DIRECTORY"
+  mkdir -p "$MOUNT_POINT"
+  if ! mountpoint -q "$MOUNT_POINT" && ! mount --bind "$SHARED_DIR" "$MOUNT_POINT"; then
+    log ERROR "Failed to mount '$SHARED_DIR' at '$MOUNT_POINT'."
+    log ERROR "Shared group folders need the SYS_ADMIN capability (cap_add: [SYS_ADMIN]),"
+    log ERROR "and on AppArmor hosts also security_opt: [apparmor:unconfined]."
+    exit 1
+  fi
+
+  # Let other members edit the files this user creates
+  if ! set_user_option "$member" local_umask 002; then
+    exit 1
+  fi
+done
+
+log INFO "👥 Group '$NAME' (GID: $GID) shares /$DIRECTORY with: ${MEMBERS[*]}"
+exit 0
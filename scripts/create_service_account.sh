@@ -0,0 +1,37 @@
+#!/usr/bin/env bash
+# create_service_account - Creates the Linux account that virtual users run as
+#
+# Usage: create_service_account <account>
+#
+# Service accounts cannot log in themselves and get the next free UID and GID.
+# Existing accounts are left unchanged.
+
+ACCOUNT="$1"
+
+# --- Error Handling ---
+if [ -z "$ACCOUNT" ]; then
+  log ERROR "Usage: create_service_account <account>"
+  exit 1
+fi
+
+if [[ ! "$ACCOUNT" =~ ^[a-z_][a-z0-9_-]*$ ]]; then
+  log ERROR "Invalid service account '$ACCOUNT'. Allowed characters: a-z, 0-9, -, _"
+  exit 1
+fi
+
+if id "$ACCOUNT" &>/dev/null; then
+  exit 0
+fi
+
+# --- Create Account ---
+NEXT_UID=$(($(getent passwd | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))
+NEXT_GID=$(($(getent group | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))
+
+log DEBUG "🔧 Creating service account $ACCOUNT (UID: $NEXT_UID, GID: $NEXT_GID)"
+if ! addgroup -g "$NEXT_GID" "$ACCOUNT" || \
+  ! adduser -D -H -h /ftp -s /sbin/nologin -u "$NEXT_UID" -G "$ACCOUNT" "$ACCOUNT"; then
+  log ERROR "Failed to create service account '$ACCOUNT'"
+  exit 1
+fi
+
+exit 0
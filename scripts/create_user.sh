@@ -1,9 +1,41 @@
 #!/usr/bin/env bash
 # create_user - Adds an FTP user to the system
+#
+# Usage: create_user [options] <username> <password>
+#
+# Options:
+#   --hash        <password> is a crypt hash ($1$, $5$ or $6$) stored as is
+#   --uid <uid>   UID for the user instead of the next free one
+#   --gid <gid>   Primary GID. An existing group with this GID is joined,
+#                 otherwise a group named after the user is created with it.
+#   --home <dir>  Name of the home directory under /ftp (default: username)
+#   --dry-run     Only validate, and report what would be created
+
+HASHED=false
+DRY_RUN=false
+USER_UID=""
+USER_GID=""
+HOME_NAME=""
+
+while [[ "$1" == --* ]]; do
+  case "$1" in
+    --hash) HASHED=true; shift ;;
+    --uid) USER_UID="$2"; shift 2 ;;
+    --gid) USER_GID="$2"; shift 2 ;;
+    --home) HOME_NAME="$2"; shift 2 ;;
+    --dry-run) DRY_RUN=true; shift ;;
+    --) shift; break ;;
+    *)
+      log ERROR "Unknown option: $1"
+      exit 1
+      ;;
+  esac
+done
 
 # Input arguments
 NAME="$1"
 PASS="$2"
+HOME_NAME="${HOME_NAME:-$NAME}"
 
 # --- Error Handling ---
 if [ -z "$NAME" ] || [ -z "$PASS" ]; then
@@ -16,7 +48,17 @@ if [[ "$NAME" =~ [^a-zA-Z0-9_.-] ]]; then
   exit 1
 fi
 
-FTP_DIR="/ftp/$NAME"
+if [[ "$HOME_NAME" =~ [^a-zA-Z0-9_.-] ]] || [[ "$HOME_NAME" =~ ^\.+$ ]]; then
+  log ERROR "Invalid home: '$HOME_NAME'. Allowed characters: a-z, A-Z, 0-9, ., -, _"
+  exit 1
+fi
+
+if [ "$HASHED" = "true" ] && [[ ! "$PASS" =~ ^\$(1|5|6)\$[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]+$ ]]; then
+  log ERROR "Unsupported password hash for user '$NAME'. Expected an MD5, SHA-256 or SHA-512 crypt hash (\$1\$, \$5\$ or \$6\$)."
+  exit 1
+fi
+
+FTP_DIR="/ftp/$HOME_NAME"
 
 # --- Check if user already exists ---
 if id "$NAME" &>/dev/null; then
@@ -24,26 +66,74 @@ if id "$NAME" &>/dev/null; then
   exit 1
 fi
 
+# Homes named after someone else must not take over an existing directory
+if [ "$HOME_NAME" != "$NAME" ] && [ -e "$FTP_DIR" ]; then
+  log ERROR "Home '$FTP_DIR' for user '$NAME' already exists"
+  exit 1
+fi
+
+# --- Check UID/GID ---
+if [ -n "$USER_UID" ]; then
+  if [[ ! "$USER_UID" =~ ^[0-9]+$ ]]; then
+    log ERROR "Invalid UID '$USER_UID' for user '$NAME'. Expected a number."
+    exit 1
+  fi
+  if getent passwd "$USER_UID" >/dev/null; then
+    log ERROR "UID $USER_UID for user '$NAME' is already in use"
+    exit 1
+  fi
+fi
+
+if [ -n "$USER_GID" ] && [[ ! "$USER_GID" =~ ^[0-9]+$ ]]; then
+  log ERROR "Invalid GID '$USER_GID' for user '$NAME'. Expected a number."
+  exit 1
+fi
+
+# An existing GID is joined, anything else gets a group named after the user
+GROUP=""
+if [ -n "$USER_GID" ]; then
+  GROUP="$(getent group "$USER_GID" | cut -d: -f1)"
+  if [ -n "$GROUP" ] && [ "$USER_GID" -lt 1000 ]; then
+    log ERROR "GID $USER_GID for user '$NAME' belongs to the system group '$GROUP'"
+    exit 1
+  fi
+fi
+
 # --- Check if group already exists ---
-if getent group "$NAME" >/dev/null; then
+if [ -z "$GROUP" ] && getent group "$NAME" >/dev/null; then
   log ERROR "Group '$NAME' already exists"
   exit 1
 fi
 
 # --- Generate UID/GID ---
-NEXT_UID=$(($(getent passwd | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))
-NEXT_GID=$(($(getent group | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))
+NEXT_UID="${USER_UID:-$(($(getent passwd | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))}"
+NEXT_GID="${USER_GID:-$(($(getent group | awk -F: '{print $3}' | sort -n | tail -n 1) + 1))}"
+
+if [ "$DRY_RUN" = "true" ]; then
+  log INFO "🔍 Would add user: $NAME (UID: ${USER_UID:-next free}, GID: ${USER_GID:-next free}${GROUP:+ ($GROUP)}, home: $FTP_DIR)"
+  exit 0
+fi
 
 # --- Create Group ---
-log DEBUG "🔧 Creating group $NAME (GID: $NEXT_GID)"
-if ! addgroup -g "$NEXT_GID" "$NAME"; then
-  log ERROR "Failed to create group '$NAME'"
-  exit 1
+if [ -z "$GROUP" ]; then
+  GROUP="$NAME"
+  log DEBUG "🔧 Creating group $GROUP (GID: $NEXT_GID)"
+  if ! addgroup -g "$NEXT_GID" "$GROUP"; then
+    log ERROR "Failed to create group '$GROUP'"
+    exit 1
+  fi
 fi
 
 # --- Create User ---
 log INFO "👤 Adding user: $NAME (UID: $NEXT_UID, GID: $NEXT_GID)"
-if ! printf "%s\n%s\n" "$PASS" "$PASS" | adduser -h "$FTP_DIR" -s /sbin/nologin -u "$NEXT_UID" -G "$NAME" "$NAME"; then
+if [ "$HASHED" = "true" ]; then
+  # The account starts locked and receives the imported hash unchanged
+  if ! adduser -D -h "$FTP_DIR" -s /sbin/nologin -u "$NEXT_UID" -G "$GROUP" "$NAME" || \
+    ! usermod -p "$PASS" "$NAME"; then
+    log ERROR "Failed to create user '$NAME'"
+    exit 1
+  fi
+elif ! printf "%s\n%s\n" "$PASS" "$PASS" | adduser -h "$FTP_DIR" -s /sbin/nologin -u "$NEXT_UID" -G "$GROUP" "$NAME"; then
   log ERROR "Failed to create user '$NAME'"
   exit 1
 fi
@@ -51,7 +141,7 @@ fi
 # --- Create FTP Directory ---
 log DEBUG "📂 Creating FTP directory at $FTP_DIR"
 mkdir -p "$FTP_DIR"
-if ! chown "$NAME:$NAME" "$FTP_DIR"; then
+if ! chown "$NAME:$GROUP" "$FTP_DIR"; then
   log ERROR "Failed to set ownership for '$FTP_DIR'"
   exit 1
 fi
@@ -61,5 +151,9 @@ if ! chmod 755 "$FTP_DIR"; then
   exit 1
 fi
 
+if ! share_home "$FTP_DIR"; then
+  exit 1
+fi
+
 log INFO "✅ User $NAME created successfully."
 exit 0
\ No newline at end of file
@@ -0,0 +1,125 @@
+#!/usr/bin/env bash
+# create_virtual_user - Adds an FTP user to the virtual user database
+#
+# Usage: create_virtual_user [options] <username> <password> [service_account]
+#
+# Virtual users have no Linux account of their own. Their password hash is
+# stored in VIRTUAL_USER_DB and checked by check_password at login, and their
+# sessions run as a shared service account (VIRTUAL_ACCOUNT by default),
+# which is created the first time it is used.
+#
+# Options:
+#   --hash        <password> is a crypt hash ($1$, $5$, $6$ or $apr1$) stored as is
+#   --home <dir>  Name of the home directory under /ftp (default: username)
+#   --dry-run     Only validate, and report what would be created
+
+VIRTUAL_USER_DB="${VIRTUAL_USER_DB:-/etc/vsftpd/virtual_users}"
+
+HASHED=false
+DRY_RUN=false
+HOME_NAME=""
+
+while [[ "$1" == --* ]]; do
+  case "$1" in
+    --hash) HASHED=true; shift ;;
+    --home) HOME_NAME="$2"; shift 2 ;;
+    --dry-run) DRY_RUN=true; shift ;;
+    --) shift; break ;;
+    *)
+      log ERROR "Unknown option: $1"
+      exit 1
+      ;;
+  esac
+done
+
+# Input arguments
+NAME="$1"
+PASS="$2"
+ACCOUNT="${3:-${VIRTUAL_ACCOUNT:-vftp}}"
+HOME_NAME="${HOME_NAME:-$NAME}"
+
+# --- Error Handling ---
+if [ -z "$NAME" ] || [ -z "$PASS" ]; then
+  log ERROR "Usage: create_virtual_user <username> <password> [service_account]"
+  exit 1
+fi
+
+if [[ ! "$NAME" =~ ^[a-zA-Z0-9_][a-zA-Z0-9_.@-]*$ ]]; then
+  log ERROR "Invalid username: '$NAME'. Allowed characters: a-z, A-Z, 0-9, ., -, _, @"
+  exit 1
+fi
+
+if [[ ! "$HOME_NAME" =~ ^[a-zA-Z0-9_][a-zA-Z0-9_.@-]*$ ]]; then
+  log ERROR "Invalid home: '$HOME_NAME'. Allowed characters: a-z, A-Z, 0-9, ., -, _, @"
+  exit 1
+fi
+
+# Apache htpasswd files default to $apr1$, which check_password also verifies
+if [ "$HASHED" = "true" ] && [[ ! "$PASS" =~ ^\$(1|5|6|apr1)\$[./0-9A-Za-z]{1,16}\$[./0-9A-Za-z]+$ ]]; then
+  log ERROR "Unsupported password hash for user '$NAME'. Expected an MD5, SHA-256, SHA-512 or Apache MD5 crypt hash (\$1\$, \$5\$, \$6\$ or \$apr1\$)."
+  exit 1
+fi
+
+FTP_DIR="/ftp/$HOME_NAME"
+
+# --- Check if user already exists ---
+if [ -f "$VIRTUAL_USER_DB" ] && awk -F: -v name="$NAME" '$1 == name { found = 1 } END { exit !found }' "$VIRTUAL_USER_DB"; then
+  log ERROR "User '$NAME' already exists"
+  exit 1
+fi
+
+# Homes named after someone else must not take over an existing directory
+if [ "$HOME_NAME" != "$NAME" ] && [ -e "$FTP_DIR" ]; then
+  log ERROR "Home '$FTP_DIR' for user '$NAME' already exists"
+  exit 1
+fi
+
+if [ "$DRY_RUN" = "true" ]; then
+  log INFO "🔍 Would add virtual user: $NAME (service account: $ACCOUNT, home: $FTP_DIR)"
+  exit 0
+fi
+
+# --- Create Service Account ---
+if ! create_service_account "$ACCOUNT"; then
+  exit 1
+fi
+
+# --- Add User ---
+log INFO "👤 Adding virtual user: $NAME (service account: $ACCOUNT)"
+HASH="$PASS"
+if [ "$HASHED" != "true" ]; then
+  HASH="$(printf "%s\n" "$PASS" | mkpasswd -m sha512)"
+fi
+if [ -z "$HASH" ]; then
+  log ERROR "Failed to hash the password for user '$NAME'"
+  exit 1
+fi
+
+touch "$VIRTUAL_USER_DB"
+chmod 600 "$VIRTUAL_USER_DB"
+echo "$NAME:$HASH:$ACCOUNT" >> "$VIRTUAL_USER_DB"
+
+# --- Create FTP Directory ---
+log DEBUG "📂 Creating FTP directory at $FTP_DIR"
+mkdir -p "$FTP_DIR"
+if ! chown "$ACCOUNT:$ACCOUNT" "$FTP_DIR"; then
+  log ERROR "Failed to set ownership for '$FTP_DIR'"
+  exit 1
+fi
+
+if ! chmod 755 "$FTP_DIR"; then
+  log ERROR "Failed to set permissions for '$FTP_DIR'"
+  exit 1
+fi
+
+if ! share_home "$FTP_DIR"; then
+  exit 1
+fi
+
+# vsftpd runs the session as the service account, rooted at the user's home
+if ! set_user_option "$NAME" guest_username "$ACCOUNT" || ! set_user_option "$NAME" local_root "$FTP_DIR"; then
+  exit 1
+fi
+
+log INFO "✅ User $NAME created successfully."
+exit 0
@@ -2,6 +2,9 @@
 
 echo "Reached entrypoint"
 
+# A restarted container keeps the marker and the admin list from its last run
+rm -f /var/run/ftp-ready /etc/vsftpd/admins
+
 # --- Logging Function ---
 log() {
   local level="$1"
@@ -13,7 +16,11 @@ log() {
 }
 
 # --- Verify Required Commands ---
-for cmd in parse_yaml log create_user; do
+for cmd in parse_yaml log create_user set_access_rules set_session_limit check_access cidr_match \
+  parse_size set_user_option set_rate_limit set_quota quota_usage quota_guard \
+  resolve_address pasv_address_watch create_group \
+  grant_admin share_home create_virtual_user check_password user_account user_sessions \
+  create_service_account check_ldap vault_read vault_sync set_password age_decrypt; do
   if ! command -v "$cmd" >/dev/null 2>&1; then
     echo "Error: '$cmd' command not found in PATH. Aborting."
     exit 1
@@ -43,11 +50,47 @@ if [ -n "$CONFIG_FILE" ] && [ -f "$CONFIG_FILE" ]; then
     log DEBUG "==========================="
     log DEBUG "YAML Configuration Details:"
     log DEBUG "Address: ${YAML_ADDRESS:-None}"
+    log DEBUG "Address Discovery URL: ${YAML_ADDRESS_DISCOVERY_URL:-None}"
+    log DEBUG "Address Refresh: ${YAML_ADDRESS_REFRESH:-None}"
+    log DEBUG "Internal Address: ${YAML_INTERNAL_ADDRESS:-None}"
+    log DEBUG "Internal CIDRs: ${YAML_INTERNAL_CIDRS:-None}"
+    log DEBUG "Listen: ${YAML_LISTEN:-None}"
+    log DEBUG "Listen Address: ${YAML_LISTEN_ADDRESS:-None}"
+    log DEBUG "Active Mode: ${YAML_ACTIVE_MODE:-None}"
+    log DEBUG "Allow FXP: ${YAML_ALLOW_FXP:-None}"
+    log DEBUG "Active Source Port: ${YAML_ACTIVE_SOURCE_PORT:-None}"
     log DEBUG "Min Port: ${YAML_MIN_PORT:-None}"
     log DEBUG "Max Port: ${YAML_MAX_PORT:-None}"
     log DEBUG "TLS Cert: ${YAML_TLS_CERT:-None}"
     log DEBUG "TLS Key: ${YAML_TLS_KEY:-None}"
+    log DEBUG "Allow CIDRs: ${YAML_ALLOW_CIDRS:-None}"
+    log DEBUG "Deny CIDRs: ${YAML_DENY_CIDRS:-None}"
+    log DEBUG "Max Clients: ${YAML_MAX_CLIENTS:-None}"
+    log DEBUG "Max Per IP: ${YAML_MAX_PER_IP:-None}"
+    log DEBUG "Idle Timeout: ${YAML_IDLE_TIMEOUT:-None}"
+    log DEBUG "Data Timeout: ${YAML_DATA_TIMEOUT:-None}"
+    log DEBUG "Max User Sessions: ${YAML_MAX_USER_SESSIONS:-None}"
+    log DEBUG "Max Upload Rate: ${YAML_MAX_UPLOAD_RATE:-None}"
+    log DEBUG "Max Download Rate: ${YAML_MAX_DOWNLOAD_RATE:-None}"
+    log DEBUG "Anonymous Enabled: ${YAML_ANON_ENABLED:-None}"
+    log DEBUG "Anonymous Root: ${YAML_ANON_ROOT:-None}"
+    log DEBUG "Anonymous Incoming Dir: ${YAML_ANON_INCOMING_DIR:-None}"
+    log DEBUG "Anonymous Max Rate: ${YAML_ANON_MAX_RATE:-None}"
+    log DEBUG "User Mode: ${YAML_USER_MODE:-None}"
+    log DEBUG "Virtual Account: ${YAML_VIRTUAL_ACCOUNT:-None}"
+    log DEBUG "LDAP URL: ${YAML_LDAP_URL:-None}"
+    log DEBUG "LDAP Bind DN: ${YAML_LDAP_BIND_DN:-None}"
+    log DEBUG "LDAP Base DN: ${YAML_LDAP_BASE_DN:-None}"
+    log DEBUG "LDAP User Filter: ${YAML_LDAP_USER_FILTER:-None}"
+    log DEBUG "LDAP Group Filter: ${YAML_LDAP_GROUP_FILTER:-None}"
+    log DEBUG "LDAP Home Attribute: ${YAML_LDAP_HOME_ATTRIBUTE:-None}"
+    log DEBUG "Vault Address: ${YAML_VAULT_ADDRESS:-None}"
+    log DEBUG "Vault Role ID: ${YAML_VAULT_ROLE_ID:-None}"
+    log DEBUG "Vault AppRole Path: ${YAML_VAULT_APPROLE_PATH:-None}"
+    log DEBUG "Vault Refresh: ${YAML_VAULT_REFRESH:-None}"
+    log DEBUG "Age Key File: ${YAML_AGE_KEY_FILE:-None}"
     log DEBUG "User Count: ${YAML_USER_COUNT:-0}"
+    log DEBUG "Group Count: ${YAML_GROUP_COUNT:-0}"
     log DEBUG "==========================="
   fi
 else
@@ -62,11 +105,409 @@ TLS_KEY="${TLS_KEY:-$YAML_TLS_KEY}"
 
 # --- Passive Mode Address ---
 ADDRESS="${ADDRESS:-${YAML_ADDRESS:-}}"
+INTERNAL_ADDRESS="${INTERNAL_ADDRESS:-${YAML_INTERNAL_ADDRESS:-}}"
+INTERNAL_CIDRS="${INTERNAL_CIDRS:-${YAML_INTERNAL_CIDRS:-}}"
+ADDRESS_DISCOVERY_URL="${ADDRESS_DISCOVERY_URL:-${YAML_ADDRESS_DISCOVERY_URL:-}}"
+ADDRESS_REFRESH="${ADDRESS_REFRESH:-${YAML_ADDRESS_REFRESH:-300}}"
+export ADDRESS INTERNAL_ADDRESS INTERNAL_CIDRS ADDRESS_DISCOVERY_URL ADDRESS_REFRESH
+
+if [[ "$ADDRESS" == *:* ]]; then
+  # PASV replies only carry IPv4 addresses; IPv6 clients use EPSV instead,
+  # which reuses the address of the control connection.
+  log WARN "🚧 Ignoring IPv6 passive address '$ADDRESS'. IPv6 clients connect through EPSV."
+  ADDRESS=""
+fi
+
+# Resolve auto and DNS addresses into the per-network passive address map
+if [ -n "$ADDRESS" ] || [ -n "$INTERNAL_ADDRESS" ]; then
+  if ! pasv_address_watch --once; then
+    exit 1
+  fi
+fi
+
+# vsftpd resolves DNS names itself, but auto must be replaced by its result
+if [ "$ADDRESS" = "auto" ]; then
+  ADDRESS="$(awk '$1 == "0.0.0.0/0" { print $2 }' /etc/vsftpd/pasv/map)"
+fi
 if [ -n "$ADDRESS" ]; then
   ADDR_OPT="-opasv_address=$ADDRESS"
 fi
 log INFO "🔧 Passive Mode Address: ${ADDRESS:-None}"
 
+# --- Listen Mode ---
+LISTEN="${LISTEN:-${YAML_LISTEN:-ipv4}}"
+LISTEN_ADDRESS="${LISTEN_ADDRESS:-${YAML_LISTEN_ADDRESS:-}}"
+LISTEN_OPT=""
+
+if [ "$LISTEN" != "ipv4" ] && [ ! -f /proc/net/if_inet6 ]; then
+  log ERROR "❌ Listen mode '$LISTEN' requires IPv6, but IPv6 is disabled in this container."
+  exit 1
+fi
+
+case "$LISTEN" in
+  ipv4)
+    if [ -n "$LISTEN_ADDRESS" ] && ! cidr_match --validate "$LISTEN_ADDRESS/32"; then
+      log ERROR "❌ Invalid listen_address '$LISTEN_ADDRESS'. Expected an IPv4 address in ipv4 mode."
+      exit 1
+    fi
+    LISTEN_OPT="-olisten=YES -olisten_ipv6=NO${LISTEN_ADDRESS:+ -olisten_address=$LISTEN_ADDRESS}"
+    ;;
+  ipv6)
+    if [ -n "$LISTEN_ADDRESS" ] && ! cidr_match --validate "$LISTEN_ADDRESS/128"; then
+      log ERROR "❌ Invalid listen_address '$LISTEN_ADDRESS'. Expected an IPv6 address in ipv6 mode."
+      exit 1
+    fi
+    LISTEN_OPT="-olisten=NO -olisten_ipv6=YES${LISTEN_ADDRESS:+ -olisten_address6=$LISTEN_ADDRESS}"
+
+    # A wildcard IPv6 socket also accepts IPv4 unless the kernel says otherwise
+    if [ -z "$LISTEN_ADDRESS" ] && [ "$(cat /proc/sys/net/ipv6/bindv6only 2>/dev/null)" != "1" ]; then
+      log WARN "🚧 IPv4 clients can still connect through IPv4-mapped addresses."
+      log WARN "🚧 Set the sysctl net.ipv6.bindv6only=1 on the container for an IPv6-only listener."
+    fi
+    ;;
+  dual)
+    # One IPv6 socket bound to :: serves both families
+    if [ -n "$LISTEN_ADDRESS" ] && [ "$LISTEN_ADDRESS" != "::" ]; then
+      log ERROR "❌ listen_address '$LISTEN_ADDRESS' is not supported in dual mode. Dual-stack always binds to ::."
+      exit 1
+    fi
+    if [ "$(cat /proc/sys/net/ipv6/bindv6only 2>/dev/null)" = "1" ]; then
+      log ERROR "❌ Dual-stack listening is unavailable because net.ipv6.bindv6only=1."
+      exit 1
+    fi
+    LISTEN_OPT="-olisten=NO -olisten_ipv6=YES"
+    ;;
+  *)
+    log ERROR "❌ Invalid listen mode '$LISTEN'. Expected ipv4, ipv6 or dual."
+    exit 1
+    ;;
+esac
+log INFO "🔧 Listen Mode: $LISTEN (${LISTEN_ADDRESS:-all addresses})"
+
+# --- Active Mode ---
+ACTIVE_MODE="${ACTIVE_MODE:-${YAML_ACTIVE_MODE:-enabled}}"
+ALLOW_FXP="${ALLOW_FXP:-${YAML_ALLOW_FXP:-false}}"
+ACTIVE_SOURCE_PORT="${ACTIVE_SOURCE_PORT:-${YAML_ACTIVE_SOURCE_PORT:-20}}"
+ACTIVE_OPTS=""
+
+case "$ACTIVE_MODE" in
+  enabled) ACTIVE_OPTS="-oport_enable=YES" ;;
+  disabled) ACTIVE_OPTS="-oport_enable=NO" ;;
+  *)
+    log ERROR "❌ Invalid active_mode '$ACTIVE_MODE'. Expected enabled or disabled."
+    exit 1
+    ;;
+esac
+
+# FXP lets clients move data between two servers, which also allows bouncing
+# connections to third-party hosts, so it stays off unless asked for
+case "${ALLOW_FXP,,}" in
+  true)
+    ACTIVE_OPTS="$ACTIVE_OPTS -oport_promiscuous=YES -opasv_promiscuous=YES"
+    log WARN "🚧 FXP is allowed. Clients may direct data connections to third-party addresses."
+    ;;
+  false) ACTIVE_OPTS="$ACTIVE_OPTS -oport_promiscuous=NO -opasv_promiscuous=NO" ;;
+  *)
+    log ERROR "❌ Invalid allow_fxp '$ALLOW_FXP'. Expected true or false."
+    exit 1
+    ;;
+esac
+
+if [[ ! "$ACTIVE_SOURCE_PORT" =~ ^[0-9]+$ ]] || [ "$ACTIVE_SOURCE_PORT" -lt 1 ] || [ "$ACTIVE_SOURCE_PORT" -gt 65535 ]; then
+  log ERROR "❌ Invalid active_source_port '$ACTIVE_SOURCE_PORT'. Expected a port between 1 and 65535."
+  exit 1
+fi
+ACTIVE_OPTS="$ACTIVE_OPTS -oftp_data_port=$ACTIVE_SOURCE_PORT"
+log INFO "🔧 Active Mode: $ACTIVE_MODE (source port $ACTIVE_SOURCE_PORT)"
+
+# --- IP Access Lists ---
+ALLOW_CIDRS="${ALLOW_CIDRS:-${YAML_ALLOW_CIDRS:-}}"
+DENY_CIDRS="${DENY_CIDRS:-${YAML_DENY_CIDRS:-}}"
+if ! set_access_rules --global "$ALLOW_CIDRS" "$DENY_CIDRS"; then
+  exit 1
+fi
+
+# --- Connection Limits ---
+MAX_CLIENTS="${MAX_CLIENTS:-${YAML_MAX_CLIENTS:-}}"
+MAX_PER_IP="${MAX_PER_IP:-${YAML_MAX_PER_IP:-}}"
+IDLE_TIMEOUT="${IDLE_TIMEOUT:-${YAML_IDLE_TIMEOUT:-}}"
+DATA_TIMEOUT="${DATA_TIMEOUT:-${YAML_DATA_TIMEOUT:-}}"
+MAX_USER_SESSIONS="${MAX_USER_SESSIONS:-${YAML_MAX_USER_SESSIONS:-}}"
+
+# Process titles carry each session's login name, which user_sessions counts
+LIMIT_OPTS="-osetproctitle_enable=YES"
+
+# Adds a numeric vsftpd option, failing on values that are not whole numbers
+add_limit_opt() {
+  local option="$1" value="$2"
+  [ -n "$value" ] || return 0
+
+  if [[ ! "$value" =~ ^[0-9]+$ ]]; then
+    log ERROR "❌ Invalid value '$value' for $option. Expected a whole number."
+    return 1
+  fi
+
+  LIMIT_OPTS="$LIMIT_OPTS -o$option=$value"
+  log INFO "🔧 Limit $option: $value"
+}
+
+if ! add_limit_opt max_clients "$MAX_CLIENTS" || \
+  ! add_limit_opt max_per_ip "$MAX_PER_IP" || \
+  ! add_limit_opt idle_session_timeout "$IDLE_TIMEOUT" || \
+  ! add_limit_opt data_connection_timeout "$DATA_TIMEOUT"; then
+  exit 1
+fi
+
+# Each client needs its own passive port while transferring
+PASV_PORT_COUNT=$((MAX_PORT - MIN_PORT + 1))
+if [[ "$MAX_CLIENTS" =~ ^[0-9]+$ ]] && [ "$MAX_CLIENTS" -gt "$PASV_PORT_COUNT" ]; then
+  log WARN "🚧 Passive port range $MIN_PORT-$MAX_PORT has $PASV_PORT_COUNT ports but max_clients is $MAX_CLIENTS."
+  log WARN "🚧 Concurrent transfers may fail once the passive ports are exhausted."
+fi
+
+# --- Bandwidth Limits ---
+# Server-wide defaults, applied to every user without its own rates
+MAX_UPLOAD_RATE="${MAX_UPLOAD_RATE:-${YAML_MAX_UPLOAD_RATE:-}}"
+MAX_DOWNLOAD_RATE="${MAX_DOWNLOAD_RATE:-${YAML_MAX_DOWNLOAD_RATE:-}}"
+
+# --- Anonymous Access ---
+ANON_ENABLED="${ANON_ENABLED:-${YAML_ANON_ENABLED:-false}}"
+ANON_ROOT="${ANON_ROOT:-${YAML_ANON_ROOT:-/srv/ftp}}"
+ANON_INCOMING_DIR="${ANON_INCOMING_DIR:-${YAML_ANON_INCOMING_DIR:-}}"
+ANON_MAX_RATE="${ANON_MAX_RATE:-${YAML_ANON_MAX_RATE:-}}"
+ANON_OPTS=""
+
+case "${ANON_ENABLED,,}" in
+  true)
+    if [[ "$ANON_ROOT" != /* ]]; then
+      log ERROR "❌ Invalid anonymous root '$ANON_ROOT'. Expected an absolute path."
+      exit 1
+    fi
+
+    # /ftp holds the user homes, so a root there could be some user's home
+    [ "$ANON_ROOT" = "/" ] || ANON_ROOT="${ANON_ROOT%/}"
+    if [ "$ANON_ROOT" = "/ftp" ] || [[ "$ANON_ROOT" == /ftp/* ]]; then
+      log ERROR "❌ Invalid anonymous root '$ANON_ROOT'. Expected a directory outside /ftp, which holds the user homes."
+      exit 1
+    fi
+
+    # Anonymous logins do not go through PAM, so check_access never sees them
+    if [ -n "${ALLOW_CIDRS// /}" ] || [ -n "${DENY_CIDRS// /}" ]; then
+      log ERROR "❌ Anonymous access cannot be enabled together with server allow_cidrs or deny_cidrs."
+      log ERROR "❌ IP access lists are checked at login, and anonymous logins skip that check."
+      exit 1
+    fi
+
+    mkdir -p "$ANON_ROOT"
+
+    # Anonymous users can only download world-readable files and never write
+    # outside the incoming directory
+    ANON_OPTS="-oanonymous_enable=YES -oanon_root=$ANON_ROOT -ono_anon_password=YES \
+    -oanon_world_readable_only=YES -oanon_mkdir_write_enable=NO -oanon_other_write_enable=NO"
+
+    if [ -n "$ANON_INCOMING_DIR" ]; then
+      if [[ "$ANON_INCOMING_DIR" == /* ]] || [[ "/$ANON_INCOMING_DIR/" == */../* ]]; then
+        log ERROR "❌ Invalid anonymous incoming_dir '$ANON_INCOMING_DIR'. Expected a path inside the anonymous root."
+        exit 1
+      fi
+
+      # Writable but not listable, and uploads are private to the server
+      mkdir -p "$ANON_ROOT/$ANON_INCOMING_DIR"
+      chown ftp:ftp "$ANON_ROOT/$ANON_INCOMING_DIR"
+      chmod 733 "$ANON_ROOT/$ANON_INCOMING_DIR"
+      ANON_OPTS="$ANON_OPTS -oanon_upload_enable=YES -oanon_umask=077"
+      log INFO "📥 Anonymous uploads are accepted in /$ANON_INCOMING_DIR"
+    else
+      ANON_OPTS="$ANON_OPTS -oanon_upload_enable=NO"
+    fi
+
+    if [ -n "$ANON_MAX_RATE" ]; then
+      if ! ANON_RATE_BYTES=$(parse_size "${ANON_MAX_RATE%/s}"); then
+        log ERROR "❌ Invalid anonymous max_rate '$ANON_MAX_RATE'. Expected a rate such as 5MiB/s."
+        exit 1
+      fi
+      ANON_OPTS="$ANON_OPTS -oanon_max_rate=$ANON_RATE_BYTES"
+      log INFO "🐢 Anonymous users are limited to $ANON_RATE_BYTES bytes/s"
+    fi
+
+    log INFO "🌍 Anonymous read-only access is enabled at $ANON_ROOT"
+    ;;
+  false) ANON_OPTS="-oanonymous_enable=NO" ;;
+  *)
+    log ERROR "❌ Invalid anonymous enabled '$ANON_ENABLED'. Expected true or false."
+    exit 1
+    ;;
+esac
+
+# --- User Mode ---
+# LDAP users are virtual users, so LDAP changes the default mode
+LDAP_URL="${LDAP_URL:-${YAML_LDAP_URL:-}}"
+DEFAULT_USER_MODE="system"
+if [ -n "$LDAP_URL" ]; then
+  DEFAULT_USER_MODE="virtual"
+fi
+
+USER_MODE="${USER_MODE:-${YAML_USER_MODE:-$DEFAULT_USER_MODE}}"
+VIRTUAL_ACCOUNT="${VIRTUAL_ACCOUNT:-${YAML_VIRTUAL_ACCOUNT:-vftp}}"
+USER_MODE_OPTS=""
+
+case "$USER_MODE" in
+  system) ;;
+  virtual)
+    if [[ ! "$VIRTUAL_ACCOUNT" =~ ^[a-z_][a-z0-9_-]*$ ]]; then
+      log ERROR "❌ Invalid virtual_account '$VIRTUAL_ACCOUNT'. Allowed characters: a-z, 0-9, -, _"
+      exit 1
+    fi
+
+    # Virtual users authenticate against the hashed user database and run as
+    # service accounts with the same privileges as system users
+    USER_MODE_OPTS="-opam_service_name=vsftpd-virtual -oguest_enable=YES -oguest_username=$VIRTUAL_ACCOUNT
+    -ovirtual_use_local_privs=YES"
+    ;;
+  *)
+    log ERROR "❌ Invalid user_mode '$USER_MODE'. Expected system or virtual."
+    exit 1
+    ;;
+esac
+export VIRTUAL_ACCOUNT
+log INFO "🔧 User Mode: $USER_MODE"
+
+# mini-ftp runs later through docker exec, without our environment
+{
+  printf "USER_MODE=%q\n" "$USER_MODE"
+  printf "VIRTUAL_ACCOUNT=%q\n" "$VIRTUAL_ACCOUNT"
+} > /etc/vsftpd/mini-ftp.conf
+
+# Creates an FTP user in the configured user mode
+add_user() {
+  local name="$1" password="$2" account="$3"
+
+  if [ "$USER_MODE" = "virtual" ]; then
+    create_virtual_user "$name" "$password" "$account"
+    return
+  fi
+
+  if [ -n "$account" ]; then
+    log WARN "🚧 Ignoring service_account for user '$name'. It only applies to virtual users."
+  fi
+  create_user "$name" "$password"
+}
+
+# --- LDAP Authentication ---
+LDAP_BIND_DN="${LDAP_BIND_DN:-${YAML_LDAP_BIND_DN:-}}"
+LDAP_BASE_DN="${LDAP_BASE_DN:-${YAML_LDAP_BASE_DN:-}}"
+LDAP_USER_FILTER="${LDAP_USER_FILTER:-${YAML_LDAP_USER_FILTER:-(uid=%u)}}"
+LDAP_GROUP_FILTER="${LDAP_GROUP_FILTER:-${YAML_LDAP_GROUP_FILTER:-}}"
+LDAP_HOME_ATTRIBUTE="${LDAP_HOME_ATTRIBUTE:-${YAML_LDAP_HOME_ATTRIBUTE:-uid}}"
+if [ -z "$LDAP_BIND_PASSWORD" ] && [ -n "$YAML_LDAP_BIND_PASSWORD_ENV" ]; then
+  LDAP_BIND_PASSWORD="$(printenv "$YAML_LDAP_BIND_PASSWORD_ENV")"
+fi
+
+if [ -n "$LDAP_URL" ]; then
+  if [ "$USER_MODE" != "virtual" ]; then
+    log ERROR "❌ LDAP authentication needs user_mode virtual, but user_mode is '$USER_MODE'."
+    exit 1
+  fi
+
+  if [[ ! "$LDAP_URL" =~ ^ldaps?:// ]]; then
+    log ERROR "❌ Invalid LDAP url '$LDAP_URL'. Expected ldap:// or ldaps://."
+    exit 1
+  fi
+
+  if [ -z "$LDAP_BASE_DN" ]; then
+    log ERROR "❌ LDAP base_dn is required when LDAP authentication is enabled."
+    exit 1
+  fi
+
+  if [ -n "$LDAP_BIND_DN" ] && [ -z "$LDAP_BIND_PASSWORD" ]; then
+    log ERROR "❌ Password for LDAP bind DN '$LDAP_BIND_DN' is missing or empty!"
+    exit 1
+  fi
+
+  if [[ ! "$LDAP_HOME_ATTRIBUTE" =~ ^[a-zA-Z][a-zA-Z0-9-]*$ ]]; then
+    log ERROR "❌ Invalid LDAP home_attribute '$LDAP_HOME_ATTRIBUTE'. Expected an attribute name."
+    exit 1
+  fi
+
+  if ! create_service_account "$VIRTUAL_ACCOUNT"; then
+    exit 1
+  fi
+
+  # check_ldap runs under pam_exec without our environment, so its settings
+  # are written to a root-only directory
+  mkdir -p /etc/vsftpd/ldap
+  chmod 700 /etc/vsftpd/ldap
+  printf "%s" "$LDAP_BIND_PASSWORD" > /etc/vsftpd/ldap/bind_password
+  chmod 600 /etc/vsftpd/ldap/bind_password
+  {
+    printf "LDAP_URL=%q\n" "$LDAP_URL"
+    printf "LDAP_BIND_DN=%q\n" "$LDAP_BIND_DN"
+    printf "LDAP_BIND_PASSWORD_FILE=%q\n" /etc/vsftpd/ldap/bind_password
+    printf "LDAP_BASE_DN=%q\n" "$LDAP_BASE_DN"
+    printf "LDAP_USER_FILTER=%q\n" "$LDAP_USER_FILTER"
+    printf "LDAP_GROUP_FILTER=%q\n" "$LDAP_GROUP_FILTER"
+    printf "LDAP_HOME_ATTRIBUTE=%q\n" "$LDAP_HOME_ATTRIBUTE"
+    printf "LDAP_SERVICE_ACCOUNT=%q\n" "$VIRTUAL_ACCOUNT"
+  } > /etc/vsftpd/ldap/ldap.conf
+  chmod 600 /etc/vsftpd/ldap/ldap.conf
+
+  log INFO "🔑 LDAP authentication: $LDAP_URL (base $LDAP_BASE_DN, filter $LDAP_USER_FILTER)"
+  if [ -n "$LDAP_GROUP_FILTER" ]; then
+    log INFO "🔑 LDAP users must match the group filter $LDAP_GROUP_FILTER"
+  fi
+fi
+
+# --- Vault Passwords ---
+VAULT_ADDR="${VAULT_ADDR:-${YAML_VAULT_ADDRESS:-}}"
+VAULT_ROLE_ID="${VAULT_ROLE_ID:-${YAML_VAULT_ROLE_ID:-}}"
+VAULT_APPROLE_PATH="${VAULT_APPROLE_PATH:-${YAML_VAULT_APPROLE_PATH:-approle}}"
+VAULT_REFRESH="${VAULT_REFRESH:-${YAML_VAULT_REFRESH:-300}}"
+if [ -z "$VAULT_TOKEN" ] && [ -n "$YAML_VAULT_TOKEN_ENV" ]; then
+  VAULT_TOKEN="$(printenv "$YAML_VAULT_TOKEN_ENV")"
+fi
+if [ -z "$VAULT_SECRET_ID" ] && [ -n "$YAML_VAULT_SECRET_ID_ENV" ]; then
+  VAULT_SECRET_ID="$(printenv "$YAML_VAULT_SECRET_ID_ENV")"
+fi
+export VAULT_ADDR VAULT_TOKEN VAULT_ROLE_ID VAULT_SECRET_ID VAULT_APPROLE_PATH VAULT_REFRESH
+
+if [ -n "$VAULT_ADDR" ]; then
+  if [[ ! "$VAULT_ADDR" =~ ^https?:// ]]; then
+    log ERROR "❌ Invalid Vault address '$VAULT_ADDR'. Expected http:// or https://."
+    exit 1
+  fi
+
+  if [ -z "$VAULT_TOKEN" ] && { [ -z "$VAULT_ROLE_ID" ] || [ -z "$VAULT_SECRET_ID" ]; }; then
+    log ERROR "❌ Vault needs a token or an AppRole role_id and secret_id."
+    exit 1
+  fi
+
+  if [[ ! "$VAULT_REFRESH" =~ ^[0-9]+$ ]] || [ "$VAULT_REFRESH" -eq 0 ]; then
+    log ERROR "❌ Invalid Vault refresh '$VAULT_REFRESH'. Expected a number of seconds."
+    exit 1
+  fi
+
+  # Fail early on bad credentials, and share one login across all users
+  if ! VAULT_SESSION_TOKEN="$(vault_read --login)"; then
+    exit 1
+  fi
+
+  # vault_sync reads the users to refresh from here
+  mkdir -p /etc/vsftpd/vault
+  chmod 700 /etc/vsftpd/vault
+  : > /etc/vsftpd/vault/users
+
+  if [ -n "$VAULT_TOKEN" ]; then
+    log INFO "🔑 Vault: $VAULT_ADDR (token, refresh every ${VAULT_REFRESH}s)"
+  else
+    log INFO "🔑 Vault: $VAULT_ADDR (AppRole at auth/$VAULT_APPROLE_PATH, refresh every ${VAULT_REFRESH}s)"
+  fi
+fi
+
+# --- Encrypted Values ---
+# password_age values are decrypted with this identity as users are created
+AGE_KEY_FILE="${AGE_KEY_FILE:-${YAML_AGE_KEY_FILE:-/etc/ftp/age.key}}"
+export AGE_KEY_FILE
+
 # --- TLS Check ---
 if [ -n "$TLS_CERT" ] || [ -n "$TLS_KEY" ]; then
   log INFO "🔒 TLS is enabled. Checking for cert/key files..."
@@ -94,6 +535,18 @@ else
   log WARN "🚧 TLS is not enabled. Proceeding without TLS."
 fi
 
+# Data connections must resume the control connection's TLS session unless
+# the clients in use cannot, such as Go's crypto/tls
+if [ -n "$TLS_OPT" ] && [ "${TLS_REQUIRE_REUSE,,}" = "false" ]; then
+  log WARN "🚧 TLS session reuse is not required. Data connections can be taken over by another client."
+  TLS_OPT="$TLS_OPT -orequire_ssl_reuse=NO"
+fi
+
+# Anonymous sessions follow the same TLS rules as local users
+if [ -n "$TLS_OPT" ] && [ "${ANON_ENABLED,,}" = "true" ]; then
+  TLS_OPT="${TLS_OPT/-oallow_anon_ssl=NO/-oallow_anon_ssl=YES} -oforce_anon_logins_ssl=YES -oforce_anon_data_ssl=YES"
+fi
+
 # --- User Setup ---
 
 # Create FTP_USER from Environment Variables if defined
@@ -120,7 +573,19 @@ if [ -n "$FTP_USER" ] && [ -n "$FTP_PASS" ]; then
     log DEBUG "  Final Password (last 5): ******${PASSWORD_PREVIEW}"
   fi
 
-  create_user "$FTP_USER" "$PASSWORD"
+  add_user "$FTP_USER" "$PASSWORD"
+
+  if ! set_session_limit "$FTP_USER" "$MAX_USER_SESSIONS"; then
+    exit 1
+  fi
+
+  if ! set_rate_limit "$FTP_USER" "$MAX_UPLOAD_RATE" "$MAX_DOWNLOAD_RATE"; then
+    exit 1
+  fi
+
+  if ! set_quota "$FTP_USER" "$FTP_QUOTA"; then
+    exit 1
+  fi
 else
   log INFO "🛑 No FTP_USER or FTP_PASS provided. Skipping environment-based user creation."
 fi
@@ -129,14 +594,63 @@ fi
 for i in $(seq 0 $((YAML_USER_COUNT - 1))); do
   eval "USERNAME=\$YAML_USER_${i}_NAME"
   eval "PASS_ENV=\$YAML_USER_${i}_PASS_ENV"
+  eval "USER_ALLOW_CIDRS=\$YAML_USER_${i}_ALLOW_CIDRS"
+  eval "USER_DENY_CIDRS=\$YAML_USER_${i}_DENY_CIDRS"
+  eval "USER_MAX_SESSIONS=\$YAML_USER_${i}_MAX_SESSIONS"
+  USER_MAX_SESSIONS="${USER_MAX_SESSIONS:-$MAX_USER_SESSIONS}"
+  eval "USER_MAX_UPLOAD_RATE=\$YAML_USER_${i}_MAX_UPLOAD_RATE"
+  eval "USER_MAX_DOWNLOAD_RATE=\$YAML_USER_${i}_MAX_DOWNLOAD_RATE"
+  USER_MAX_UPLOAD_RATE="${USER_MAX_UPLOAD_RATE:-$MAX_UPLOAD_RATE}"
+  USER_MAX_DOWNLOAD_RATE="${USER_MAX_DOWNLOAD_RATE:-$MAX_DOWNLOAD_RATE}"
+  eval "USER_QUOTA=\$YAML_USER_${i}_QUOTA"
+  eval "USER_ADMIN=\$YAML_USER_${i}_ADMIN"
+  eval "USER_READ_ONLY=\$YAML_USER_${i}_READ_ONLY"
+  eval "USER_SERVICE_ACCOUNT=\$YAML_USER_${i}_SERVICE_ACCOUNT"
+  eval "USER_VAULT_PATH=\$YAML_USER_${i}_VAULT_PATH"
+  eval "USER_VAULT_FIELD=\$YAML_USER_${i}_VAULT_FIELD"
+  eval "USER_PASSWORD_AGE=\$YAML_USER_${i}_PASSWORD_AGE"
 
   # Skip if the YAML username matches FTP_USER
   if [ "$USERNAME" = "$FTP_USER" ]; then
     continue
   fi
 
-  # Retrieve password from environment variable
-  PASSWORD="$(printenv "$PASS_ENV")"
+  # Each user takes its password from exactly one source
+  PASSWORD_SOURCES=0
+  for source in "$PASS_ENV" "$USER_VAULT_PATH" "$USER_PASSWORD_AGE"; do
+    if [ -n "$source" ]; then
+      PASSWORD_SOURCES=$((PASSWORD_SOURCES + 1))
+    fi
+  done
+  if [ "$PASSWORD_SOURCES" -gt 1 ]; then
+    log ERROR "❌ User '$USERNAME' sets more than one of password_env, password_vault and password_age. Use only one."
+    exit 1
+  fi
+
+  # Retrieve password from Vault, an encrypted value or an environment variable
+  if [ -n "$USER_VAULT_PATH" ]; then
+    if [ -z "$VAULT_ADDR" ]; then
+      log ERROR "❌ User '$USERNAME' uses password_vault, but no Vault address is configured."
+      exit 1
+    fi
+
+    if [[ ! "$USER_VAULT_PATH" =~ ^[a-zA-Z0-9_./@-]+$ ]]; then
+      log ERROR "❌ Invalid password_vault path '$USER_VAULT_PATH' for user '$USERNAME'."
+      exit 1
+    fi
+
+    if ! PASSWORD="$(VAULT_TOKEN="$VAULT_SESSION_TOKEN" vault_read "$USER_VAULT_PATH" "$USER_VAULT_FIELD")"; then
+      log ERROR "❌ Could not read the password for user '$USERNAME' from Vault."
+      exit 1
+    fi
+    echo "$USERNAME $USER_VAULT_PATH $USER_VAULT_FIELD" >> /etc/vsftpd/vault/users
+  elif [ -n "$USER_PASSWORD_AGE" ]; then
+    if ! PASSWORD="$(printf "%s\n" "$USER_PASSWORD_AGE" | age_decrypt "$USERNAME" password_age)"; then
+      exit 1
+    fi
+  else
+    PASSWORD="$(printenv "$PASS_ENV")"
+  fi
 
   if [ -z "$PASSWORD" ]; then
     log ERROR "❌ Password for user '$USERNAME' is missing or empty!"
@@ -149,20 +663,120 @@ for i in $(seq 0 $((YAML_USER_COUNT - 1))); do
     log DEBUG "----------------------------"
     log DEBUG "User [$i]:"
     log DEBUG "  Username: $USERNAME"
-    log DEBUG "  Env Variable: $PASS_ENV"
+    log DEBUG "  Env Variable: ${PASS_ENV:-None}"
+    log DEBUG "  Vault Path: ${USER_VAULT_PATH:-None}"
+    log DEBUG "  Encrypted Password: $([ -n "$USER_PASSWORD_AGE" ] && echo Yes || echo No)"
+    log DEBUG "  Allow CIDRs: ${USER_ALLOW_CIDRS:-None}"
+    log DEBUG "  Deny CIDRs: ${USER_DENY_CIDRS:-None}"
+    log DEBUG "  Max Sessions: ${USER_MAX_SESSIONS:-None}"
+    log DEBUG "  Max Upload Rate: ${USER_MAX_UPLOAD_RATE:-None}"
+    log DEBUG "  Max Download Rate: ${USER_MAX_DOWNLOAD_RATE:-None}"
+    log DEBUG "  Quota: ${USER_QUOTA:-None}"
+    log DEBUG "  Admin: ${USER_ADMIN:-false}"
+    log DEBUG "  Read Only: ${USER_READ_ONLY:-false}"
+    log DEBUG "  Service Account: ${USER_SERVICE_ACCOUNT:-Default}"
     log DEBUG "  Final Password (last 5): ******${PASSWORD_PREVIEW}"
   fi
 
   # Create user from YAML
   log INFO "👤 Creating user: $USERNAME"
-  create_user "$USERNAME" "$PASSWORD"
+  add_user "$USERNAME" "$PASSWORD" "$USER_SERVICE_ACCOUNT"
+
+  if ! set_access_rules "$USERNAME" "$USER_ALLOW_CIDRS" "$USER_DENY_CIDRS"; then
+    exit 1
+  fi
+
+  if ! set_session_limit "$USERNAME" "$USER_MAX_SESSIONS"; then
+    exit 1
+  fi
+
+  if ! set_rate_limit "$USERNAME" "$USER_MAX_UPLOAD_RATE" "$USER_MAX_DOWNLOAD_RATE"; then
+    exit 1
+  fi
+
+  if ! set_quota "$USERNAME" "$USER_QUOTA"; then
+    exit 1
+  fi
 done
 
+# --- Shared Group Folders ---
+# Groups are created once every member exists
+for i in $(seq 0 $((YAML_GROUP_COUNT - 1))); do
+  eval "GROUP_NAME=\$YAML_GROUP_${i}_NAME"
+  eval "GROUP_GID=\$YAML_GROUP_${i}_GID"
+  eval "GROUP_DIRECTORY=\$YAML_GROUP_${i}_DIRECTORY"
+  eval "GROUP_MEMBERS=\$YAML_GROUP_${i}_MEMBERS"
+  eval "GROUP_QUOTA=\$YAML_GROUP_${i}_QUOTA"
+
+  if [ "$LOG_LEVEL" = "DEBUG" ]; then
+    log DEBUG "----------------------------"
+    log DEBUG "Group [$i]:"
+    log DEBUG "  Name: $GROUP_NAME"
+    log DEBUG "  GID: ${GROUP_GID:-Auto}"
+    log DEBUG "  Directory: ${GROUP_DIRECTORY:-shared}"
+    log DEBUG "  Members: ${GROUP_MEMBERS:-None}"
+    log DEBUG "  Quota: ${GROUP_QUOTA:-None}"
+  fi
+
+  if ! create_group "$GROUP_NAME" "$GROUP_GID" "${GROUP_DIRECTORY:-shared}" $GROUP_MEMBERS; then
+    exit 1
+  fi
+
+  if ! set_quota --group "$GROUP_NAME" "$GROUP_QUOTA"; then
+    exit 1
+  fi
+done
+
+# --- Admin Access ---
+# Admins are granted access once every home and shared folder exists
+for i in $(seq 0 $((YAML_USER_COUNT - 1))); do
+  eval "USERNAME=\$YAML_USER_${i}_NAME"
+  eval "USER_ADMIN=\$YAML_USER_${i}_ADMIN"
+  eval "USER_READ_ONLY=\$YAML_USER_${i}_READ_ONLY"
+
+  if [ "$USER_ADMIN" != "true" ] && [ "$USER_READ_ONLY" = "true" ]; then
+    log WARN "🚧 Ignoring read_only for user '$USERNAME'. It only applies to admins."
+  fi
+
+  if [ "$USER_ADMIN" != "true" ] || [ "$USERNAME" = "$FTP_USER" ]; then
+    continue
+  fi
+
+  ADMIN_MODE=""
+  if [ "$USER_READ_ONLY" = "true" ]; then
+    ADMIN_MODE="--read-only"
+  fi
+
+  if ! grant_admin "$USERNAME" $ADMIN_MODE; then
+    exit 1
+  fi
+done
+
+# --- Passive Address Refresh ---
+if [ -n "$ADDRESS" ] || [ -n "$INTERNAL_ADDRESS" ]; then
+  pasv_address_watch &
+fi
+
+# --- Vault Password Refresh ---
+if [ -s /etc/vsftpd/vault/users ]; then
+  vault_sync &
+fi
+
+# --- Quota Enforcement ---
+if [ -n "$(ls -A /etc/vsftpd/quotas 2>/dev/null)" ]; then
+  quota_guard --once
+  quota_guard &
+fi
+
 log DEBUG "🔧 Passive Mode Port Range: $MIN_PORT - $MAX_PORT"
 PASV_PORT_OPTS="-opasv_min_port=$MIN_PORT -opasv_max_port=$MAX_PORT"
 
+# access.so drops denied clients before the greeting, rate.so throttles
+# uploads and downloads, quota.so enforces the quotas published by
+# quota_guard during uploads, and pasv.so picks each session's passive
+# address from the map
 log INFO "🚀 Starting vsftpd..."
-vsftpd $PASV_PORT_OPTS $ADDR_OPT $TLS_OPT /etc/vsftpd/vsftpd.conf
+LD_PRELOAD="/usr/lib/mini-ftp/access.so /usr/lib/mini-ftp/rate.so /usr/lib/mini-ftp/quota.so /usr/lib/mini-ftp/pasv.so" vsftpd $LISTEN_OPT $PASV_PORT_OPTS $ADDR_OPT $ACTIVE_OPTS $LIMIT_OPTS $ANON_OPTS $USER_MODE_OPTS $TLS_OPT /etc/vsftpd/vsftpd.conf
 
 [ -d /var/run/vsftpd ] || mkdir /var/run/vsftpd
 pgrep vsftpd | tail -n 1 > /var/run/vsftpd/vsftpd.pid
@@ -0,0 +1,72 @@
+#!/usr/bin/env bash
+# grant_admin - Gives a user access to every FTP home
+#
+# Usage: grant_admin <username> [--read-only]
+#
+# The admin is chrooted to /ftp instead of its own home, and every FTP command
+# it sends is logged. A writable admin is added to the ACLs of every user
+# home with share_home, so it can fix files in place without changing the
+# users' modes. Its account is kept in ADMIN_LIST so homes created later, such
+# as those of LDAP users on their first login, are shared as well. Virtual
+# users share through their service accounts, and homes that already belong
+# to the admin's account need no change. With --read-only the admin can
+# browse and download but never write.
+
+FTP_ROOT="${FTP_ROOT:-/ftp}"
+ADMIN_LIST="${ADMIN_LIST:-/etc/vsftpd/admins}"
+
+NAME="$1"
+MODE="$2"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  log ERROR "Usage: grant_admin <username> [--read-only]"
+  exit 1
+fi
+
+if ! ACCOUNT="$(user_account "$NAME")"; then
+  log ERROR "Admin user '$NAME' does not exist"
+  exit 1
+fi
+
+if [ -n "$MODE" ] && [ "$MODE" != "--read-only" ]; then
+  log ERROR "Invalid option '$MODE' for admin '$NAME'. Expected --read-only."
+  exit 1
+fi
+
+# --- Chroot and Audit Logging ---
+if ! set_user_option "$NAME" local_root "$FTP_ROOT" || ! set_user_option "$NAME" log_ftp_protocol YES; then
+  exit 1
+fi
+
+if [ "$MODE" = "--read-only" ]; then
+  if ! set_user_option "$NAME" write_enable NO; then
+    exit 1
+  fi
+  log INFO "🛡️ User '$NAME' is a read-only admin with access to $FTP_ROOT"
+  exit 0
+fi
+
+# --- Write Access ---
+if ! grep -qxF "$ACCOUNT" "$ADMIN_LIST" 2>/dev/null; then
+  echo "$ACCOUNT" >> "$ADMIN_LIST"
+fi
+
+# Shared group folders and other root-owned directories keep their own
+# permissions
+for home in "$FTP_ROOT"/*/; do
+  home="${home%/}"
+  [ -d "$home" ] || continue
+  owner="$(stat -c %U "$home")"
+
+  if [ "$owner" = "root" ] || [ "$owner" = "$ACCOUNT" ]; then
+    continue
+  fi
+
+  if ! share_home "$home" "$ACCOUNT"; then
+    exit 1
+  fi
+done
+
+log INFO "🛡️ User '$NAME' is an admin with access to $FTP_ROOT"
+exit 0
@@ -0,0 +1,231 @@
+#!/usr/bin/env bash
+# mini-ftp - Administration commands for a running mini-ftp container
+#
+# Usage: mini-ftp <command> [args]
+#
+# Run inside the container, e.g. `docker exec ftp mini-ftp user quota`.
+
+QUOTA_DIR="${QUOTA_DIR:-/etc/vsftpd/quotas}"
+MINI_FTP_CONF="${MINI_FTP_CONF:-/etc/vsftpd/mini-ftp.conf}"
+
+# The entrypoint records the user mode, which docker exec does not inherit
+if [ -f "$MINI_FTP_CONF" ]; then
+  . "$MINI_FTP_CONF"
+fi
+USER_MODE="${USER_MODE:-system}"
+export VIRTUAL_ACCOUNT
+
+usage() {
+  echo "Usage: mini-ftp <command> [args]"
+  echo
+  echo "Commands:"
+  echo "  user quota [username]   Show disk quota and current usage"
+  echo "  user import [--dry-run] [--format csv|htpasswd] <file>"
+  echo "                          Create users from a CSV or htpasswd file"
+  echo "  help                    Show this help message"
+  echo
+  echo "Password hashes for user import:"
+  echo "  \$1\$, \$5\$, \$6\$                Accepted in both user modes"
+  echo "  \$apr1\$ (htpasswd -m)        REJECTED in system mode, accepted in virtual mode"
+  echo "  \$2y\$ (bcrypt, htpasswd -B)  REJECTED in both user modes"
+}
+
+# --- user quota ---
+user_quota() {
+  local names=("$@")
+
+  if [ ${#names[@]} -eq 0 ]; then
+    for quota_file in "$QUOTA_DIR"/*; do
+      [ -f "$quota_file" ] && names+=("$(basename "$quota_file")")
+    done
+  fi
+
+  printf "%-24s %16s %16s %8s\n" "USER" "QUOTA_BYTES" "USAGE_BYTES" "USED"
+  for name in "${names[@]}"; do
+    local quota usage used
+    usage="$(quota_usage "$name")"
+
+    if [ -f "$QUOTA_DIR/$name" ]; then
+      quota="$(cat "$QUOTA_DIR/$name")"
+      used="$(awk -v u="$usage" -v q="$quota" 'BEGIN { printf "%.1f%%", (u / q) * 100 }')"
+    else
+      quota="unlimited"
+      used="-"
+    fi
+
+    printf "%-24s %16s %16s %8s\n" "$name" "$quota" "$usage" "$used"
+  done
+}
+
+# --- user import ---
+# CSV rows are username,password_hash,uid,gid,home,quota with an optional
+# header row, htpasswd rows are username:password_hash. Only the last
+# component of home is used, as the directory name under /ftp. Each row is
+# validated by create_user, or create_virtual_user in virtual mode, so bad
+# rows are reported and skipped while the rest are imported.
+clean_field() {
+  local value="$1"
+  value="${value#"${value%%[![:space:]]*}"}"
+  value="${value%"${value##*[![:space:]]}"}"
+  value="${value#\"}"
+  value="${value%\"}"
+  printf "%s" "$value"
+}
+
+user_import() {
+  local dry_run=false format="" file=""
+
+  while [ $# -gt 0 ]; do
+    case "$1" in
+      --dry-run) dry_run=true ;;
+      --format) format="$2"; shift ;;
+      -*)
+        echo "Unknown option: $1" >&2
+        return 1
+        ;;
+      *) file="$1" ;;
+    esac
+    shift
+  done
+
+  if [ -z "$file" ]; then
+    echo "Usage: mini-ftp user import [--dry-run] [--format csv|htpasswd] <file>" >&2
+    return 1
+  fi
+
+  if [ ! -r "$file" ]; then
+    echo "Cannot read $file" >&2
+    return 1
+  fi
+
+  # Hashes never contain commas, so the first row tells the formats apart
+  if [ -z "$format" ]; then
+    format="htpasswd"
+    if grep -v -e '^[[:space:]]*#' -e '^[[:space:]]*$' "$file" | head -n 1 | grep -q ','; then
+      format="csv"
+    fi
+  fi
+
+  case "$format" in
+    csv|htpasswd) ;;
+    *)
+      echo "Unknown format '$format'. Expected csv or htpasswd." >&2
+      return 1
+      ;;
+  esac
+
+  local esc=$'\033'
+  local line line_no=0 first_row=true imported=0 failed=0 quotas=false
+  local name hash uid gid home home_name quota error output
+  local -a cmd
+  local -A seen_names seen_uids seen_homes
+
+  while IFS= read -r line || [ -n "$line" ]; do
+    line_no=$((line_no + 1))
+    line="${line%$'\r'}"
+    [[ "$line" =~ ^[[:space:]]*(#|$) ]] && continue
+
+    uid="" gid="" home="" quota=""
+    if [ "$format" = "csv" ]; then
+      IFS=, read -r name hash uid gid home quota _ <<< "$line"
+      name="$(clean_field "$name")"
+      hash="$(clean_field "$hash")"
+      uid="$(clean_field "$uid")"
+      gid="$(clean_field "$gid")"
+      home="$(clean_field "$home")"
+      quota="$(clean_field "$quota")"
+
+      if [ "$first_row" = "true" ] && [ "${name,,}" = "username" ]; then
+        first_row=false
+        continue
+      fi
+    else
+      name="${line%%:*}"
+      hash="${line#*:}"
+      [[ "$line" == *:* ]] || hash=""
+    fi
+    first_row=false
+
+    # --- Validate Row ---
+    # Rows are only checked against the server one at a time, so homes that
+    # end in the same name would pass a dry run and then clash
+    home_name="$name"
+    [ -n "$home" ] && home_name="$(basename "$home")"
+
+    error=""
+    if [ -z "$name" ] || [ -z "$hash" ]; then
+      error="Missing username or password hash"
+    elif [ -n "${seen_names[$name]}" ]; then
+      error="Duplicate of line ${seen_names[$name]}"
+    elif [ -n "$uid" ] && [ "$USER_MODE" = "system" ] && [ -n "${seen_uids[$uid]}" ]; then
+      error="UID $uid is also used on line ${seen_uids[$uid]}"
+    elif [ -n "${seen_homes[$home_name]}" ]; then
+      error="Home /ftp/$home_name is also used on line ${seen_homes[$home_name]}"
+    elif [ -n "$quota" ] && [ "$quota" != "0" ] && ! parse_size "$quota" >/dev/null 2>&1; then
+      error="Invalid quota '$quota'. Expected a size such as 10GiB."
+    fi
+
+    if [ -z "$error" ]; then
+      seen_names[$name]="$line_no"
+      [ -n "$uid" ] && seen_uids[$uid]="$line_no"
+      seen_homes[$home_name]="$line_no"
+
+      if [ "$USER_MODE" = "virtual" ]; then
+        cmd=(create_virtual_user --hash)
+        if [ -n "$uid" ] || [ -n "$gid" ]; then
+          echo "🚧 line $line_no ($name): Ignoring uid and gid. They only apply to system users."
+        fi
+      else
+        cmd=(create_user --hash)
+        [ -n "$uid" ] && cmd+=(--uid "$uid")
+        [ -n "$gid" ] && cmd+=(--gid "$gid")
+      fi
+      [ -n "$home" ] && cmd+=(--home "$home_name")
+      [ "$dry_run" = "true" ] && cmd+=(--dry-run)
+      cmd+=(-- "$name" "$hash")
+
+      if ! output="$("${cmd[@]}" 2>&1)"; then
+        error="$(echo "$output" | sed -n -e "s/$esc\[[0-9;]*m//g" -e 's/^\[[^]]*\] \[ERROR\] //p' | head -n 1)"
+        error="${error:-Failed to create the user}"
+      fi
+    fi
+
+    if [ -n "$error" ]; then
+      echo "❌ line $line_no (${name:-?}): $error"
+      failed=$((failed + 1))
+      continue
+    fi
+
+    [ -n "$output" ] && echo "$output"
+    if [ "$dry_run" != "true" ] && [ -n "$quota" ]; then
+      set_quota "$name" "$quota"
+      quotas=true
+    fi
+    imported=$((imported + 1))
+  done < "$file"
+
+  # Quotas are only enforced by a running quota_guard
+  if [ "$quotas" = "true" ] && ! pgrep -f "quota_guard" >/dev/null; then
+    nohup quota_guard >> /proc/1/fd/1 2>&1 &
+  fi
+
+  if [ "$dry_run" = "true" ]; then
+    echo "🔍 Dry run: $imported users would be imported. Rows with errors: $failed. Nothing was changed."
+  else
+    echo "📥 Imported $imported users. Rows with errors: $failed."
+  fi
+
+  [ "$failed" -eq 0 ]
+}
+
+# --- Dispatch ---
+case "$1 $2" in
+  "user quota") shift 2; user_quota "$@" ;;
+  "user import") shift 2; user_import "$@" ;;
+  "help "|" ") usage ;;
+  *)
+    echo "Unknown command: $*" >&2
+    usage >&2
+    exit 1
+    ;;
+esac
@@ -0,0 +1,42 @@
+#!/usr/bin/env bash
+# parse_size - Converts a human-readable size into bytes
+#
+# Usage: parse_size <size>
+#
+# Accepts plain byte counts ("1048576") or a number followed by a unit:
+#   B, KB, MB, GB, TB       decimal units (powers of 1000)
+#   K, M, G, T              binary units (powers of 1024)
+#   KiB, MiB, GiB, TiB      binary units (powers of 1024)
+# Units are case-insensitive and fractional values such as "1.5GiB" are allowed.
+# Prints the size in whole bytes, or exits 1 if the value cannot be parsed.
+
+SIZE="${1// /}"
+
+# --- Validate Arguments ---
+if [ -z "$SIZE" ]; then
+  echo "Usage: parse_size <size>" >&2
+  exit 1
+fi
+
+if [[ ! "$SIZE" =~ ^([0-9]+(\.[0-9]+)?)([a-zA-Z]*)$ ]]; then
+  exit 1
+fi
+
+NUMBER="${BASH_REMATCH[1]}"
+UNIT="${BASH_REMATCH[3]^^}"
+
+# --- Resolve Unit Multiplier ---
+case "$UNIT" in
+  ""|B)         MULTIPLIER=1 ;;
+  KB)           MULTIPLIER=1000 ;;
+  MB)           MULTIPLIER=1000000 ;;
+  GB)           MULTIPLIER=1000000000 ;;
+  TB)           MULTIPLIER=1000000000000 ;;
+  K|KIB)        MULTIPLIER=1024 ;;
+  M|MIB)        MULTIPLIER=1048576 ;;
+  G|GIB)        MULTIPLIER=1073741824 ;;
+  T|TIB)        MULTIPLIER=1099511627776 ;;
+  *)            exit 1 ;;
+esac
+
+awk -v n="$NUMBER" -v m="$MULTIPLIER" 'BEGIN { printf "%.0f\n", n * m }'
@@ -8,11 +8,50 @@ CONFIG_FILE="${1:-/etc/ftp/config.yaml}"  # Default config path if none provided
 if [ ! -f "$CONFIG_FILE" ]; then
   echo "CONFIG_FILE_DETECTED=0"
   echo "YAML_ADDRESS=''"
+  echo "YAML_ADDRESS_DISCOVERY_URL=''"
+  echo "YAML_ADDRESS_REFRESH=''"
+  echo "YAML_INTERNAL_ADDRESS=''"
+  echo "YAML_INTERNAL_CIDRS=''"
+  echo "YAML_LISTEN=''"
+  echo "YAML_ACTIVE_MODE=''"
+  echo "YAML_ALLOW_FXP=''"
+  echo "YAML_ACTIVE_SOURCE_PORT=''"
+  echo "YAML_LISTEN_ADDRESS=''"
   echo "YAML_MIN_PORT=''"
   echo "YAML_MAX_PORT=''"
   echo "YAML_TLS_CERT=''"
   echo "YAML_TLS_KEY=''"
+  echo "YAML_ALLOW_CIDRS=''"
+  echo "YAML_DENY_CIDRS=''"
+  echo "YAML_MAX_CLIENTS=''"
+  echo "YAML_MAX_PER_IP=''"
+  echo "YAML_IDLE_TIMEOUT=''"
+  echo "YAML_DATA_TIMEOUT=''"
+  echo "YAML_MAX_USER_SESSIONS=''"
+  echo "YAML_MAX_UPLOAD_RATE=''"
+  echo "YAML_MAX_DOWNLOAD_RATE=''"
+  echo "YAML_ANON_ENABLED=''"
+  echo "YAML_ANON_ROOT=''"
+  echo "YAML_ANON_INCOMING_DIR=''"
+  echo "YAML_ANON_MAX_RATE=''"
+  echo "YAML_USER_MODE=''"
+  echo "YAML_VIRTUAL_ACCOUNT=''"
+  echo "YAML_LDAP_URL=''"
+  echo "YAML_LDAP_BIND_DN=''"
+  echo "YAML_LDAP_BIND_PASSWORD_ENV=''"
+  echo "YAML_LDAP_BASE_DN=''"
+  echo "YAML_LDAP_USER_FILTER=''"
+  echo "YAML_LDAP_GROUP_FILTER=''"
+  echo "YAML_LDAP_HOME_ATTRIBUTE=''"
+  echo "YAML_VAULT_ADDRESS=''"
+  echo "YAML_VAULT_TOKEN_ENV=''"
+  echo "YAML_VAULT_ROLE_ID=''"
+  echo "YAML_VAULT_SECRET_ID_ENV=''"
+  echo "YAML_VAULT_APPROLE_PATH=''"
+  echo "YAML_VAULT_REFRESH=''"
+  echo "YAML_AGE_KEY_FILE=''"
   echo "YAML_USER_COUNT=0"
+  echo "YAML_GROUP_COUNT=0"
   exit 0
 fi
 
@@ -20,11 +59,50 @@ fi
 if ! yq '.' "$CONFIG_FILE" >/dev/null 2>&1; then
   echo "CONFIG_FILE_DETECTED=1"
   echo "YAML_ADDRESS=''"
+  echo "YAML_ADDRESS_DISCOVERY_URL=''"
+  echo "YAML_ADDRESS_REFRESH=''"
+  echo "YAML_INTERNAL_ADDRESS=''"
+  echo "YAML_INTERNAL_CIDRS=''"
+  echo "YAML_LISTEN=''"
+  echo "YAML_ACTIVE_MODE=''"
+  echo "YAML_ALLOW_FXP=''"
+  echo "YAML_ACTIVE_SOURCE_PORT=''"
+  echo "YAML_LISTEN_ADDRESS=''"
   echo "YAML_MIN_PORT=''"
   echo "YAML_MAX_PORT=''"
   echo "YAML_TLS_CERT=''"
   echo "YAML_TLS_KEY=''"
+  echo "YAML_ALLOW_CIDRS=''"
+  echo "YAML_DENY_CIDRS=''"
+  echo "YAML_MAX_CLIENTS=''"
+  echo "YAML_MAX_PER_IP=''"
+  echo "YAML_IDLE_TIMEOUT=''"
+  echo "YAML_DATA_TIMEOUT=''"
+  echo "YAML_MAX_USER_SESSIONS=''"
+  echo "YAML_MAX_UPLOAD_RATE=''"
+  echo "YAML_MAX_DOWNLOAD_RATE=''"
+  echo "YAML_ANON_ENABLED=''"
+  echo "YAML_ANON_ROOT=''"
+  echo "YAML_ANON_INCOMING_DIR=''"
+  echo "YAML_ANON_MAX_RATE=''"
+  echo "YAML_USER_MODE=''"
+  echo "YAML_VIRTUAL_ACCOUNT=''"
+  echo "YAML_LDAP_URL=''"
+  echo "YAML_LDAP_BIND_DN=''"
+  echo "YAML_LDAP_BIND_PASSWORD_ENV=''"
+  echo "YAML_LDAP_BASE_DN=''"
+  echo "YAML_LDAP_USER_FILTER=''"
+  echo "YAML_LDAP_GROUP_FILTER=''"
+  echo "YAML_LDAP_HOME_ATTRIBUTE=''"
+  echo "YAML_VAULT_ADDRESS=''"
+  echo "YAML_VAULT_TOKEN_ENV=''"
+  echo "YAML_VAULT_ROLE_ID=''"
+  echo "YAML_VAULT_SECRET_ID_ENV=''"
+  echo "YAML_VAULT_APPROLE_PATH=''"
+  echo "YAML_VAULT_REFRESH=''"
+  echo "YAML_AGE_KEY_FILE=''"
   echo "YAML_USER_COUNT=0"
+  echo "YAML_GROUP_COUNT=0"
   exit 0
 fi
 
@@ -33,29 +111,139 @@ echo "CONFIG_FILE_DETECTED=1"
 
 # --- Parse Server Settings ---
 server_address=$(yq '.server.address // ""' "$CONFIG_FILE")
+server_address_discovery_url=$(yq '.server.address_discovery_url // ""' "$CONFIG_FILE")
+server_address_refresh=$(yq '.server.address_refresh // ""' "$CONFIG_FILE")
+server_internal_address=$(yq '.server.internal_address // ""' "$CONFIG_FILE")
+server_internal_cidrs=$(yq '.server.internal_cidrs // [] | join(" ")' "$CONFIG_FILE")
 server_min_port=$(yq '.server.min_port // ""' "$CONFIG_FILE")
 server_max_port=$(yq '.server.max_port // ""' "$CONFIG_FILE")
 
+# --- Parse Listen Settings ---
+server_listen=$(yq '.server.listen // ""' "$CONFIG_FILE")
+server_listen_address=$(yq '.server.listen_address // ""' "$CONFIG_FILE")
+
+# --- Parse Active Mode Settings ---
+server_active_mode=$(yq '.server.active_mode // ""' "$CONFIG_FILE")
+server_allow_fxp=$(yq '.server.allow_fxp // ""' "$CONFIG_FILE")
+server_active_source_port=$(yq '.server.active_source_port // ""' "$CONFIG_FILE")
+
 # --- Parse TLS Settings ---
 server_tls_cert=$(yq '.server.tls_cert // ""' "$CONFIG_FILE")
 server_tls_key=$(yq '.server.tls_key // ""' "$CONFIG_FILE")
 
+# --- Parse User Mode ---
+server_user_mode=$(yq '.server.user_mode // ""' "$CONFIG_FILE")
+server_virtual_account=$(yq '.server.virtual_account // ""' "$CONFIG_FILE")
+
+# --- Parse Access Lists ---
+server_allow_cidrs=$(yq '.server.allow_cidrs // [] | join(" ")' "$CONFIG_FILE")
+server_deny_cidrs=$(yq '.server.deny_cidrs // [] | join(" ")' "$CONFIG_FILE")
+
+# --- Parse Connection Limits ---
+server_max_clients=$(yq '.server.max_clients // ""' "$CONFIG_FILE")
+server_max_per_ip=$(yq '.server.max_per_ip // ""' "$CONFIG_FILE")
+server_idle_timeout=$(yq '.server.idle_timeout // ""' "$CONFIG_FILE")
+server_data_timeout=$(yq '.server.data_timeout // ""' "$CONFIG_FILE")
+server_max_user_sessions=$(yq '.server.max_user_sessions // ""' "$CONFIG_FILE")
+
+# --- Parse Bandwidth Limits ---
+server_max_upload_rate=$(yq '.server.max_upload_rate // ""' "$CONFIG_FILE")
+server_max_download_rate=$(yq '.server.max_download_rate // ""' "$CONFIG_FILE")
+
+# --- Parse Anonymous Access ---
+anon_enabled=$(yq '.server.anonymous.enabled // ""' "$CONFIG_FILE")
+anon_root=$(yq '.server.anonymous.root // ""' "$CONFIG_FILE")
+anon_incoming_dir=$(yq '.server.anonymous.incoming_dir // ""' "$CONFIG_FILE")
+anon_max_rate=$(yq '.server.anonymous.max_rate // ""' "$CONFIG_FILE")
+
+# --- Parse LDAP Authentication ---
+ldap_url=$(yq '.auth.ldap.url // ""' "$CONFIG_FILE")
+ldap_bind_dn=$(yq '.auth.ldap.bind_dn // ""' "$CONFIG_FILE")
+ldap_bind_password_env=$(yq '.auth.ldap.bind_password_env // ""' "$CONFIG_FILE")
+ldap_base_dn=$(yq '.auth.ldap.base_dn // ""' "$CONFIG_FILE")
+ldap_user_filter=$(yq '.auth.ldap.user_filter // ""' "$CONFIG_FILE")
+ldap_group_filter=$(yq '.auth.ldap.group_filter // ""' "$CONFIG_FILE")
+ldap_home_attribute=$(yq '.auth.ldap.home_attribute // ""' "$CONFIG_FILE")
+
+# --- Parse Vault Settings ---
+vault_address=$(yq '.auth.vault.address // ""' "$CONFIG_FILE")
+vault_token_env=$(yq '.auth.vault.token_env // ""' "$CONFIG_FILE")
+vault_role_id=$(yq '.auth.vault.role_id // ""' "$CONFIG_FILE")
+vault_secret_id_env=$(yq '.auth.vault.secret_id_env // ""' "$CONFIG_FILE")
+vault_approle_path=$(yq '.auth.vault.approle_path // ""' "$CONFIG_FILE")
+vault_refresh=$(yq '.auth.vault.refresh // ""' "$CONFIG_FILE")
+
+# --- Parse Encrypted Values ---
+age_key_file=$(yq '.auth.age.key_file // ""' "$CONFIG_FILE")
+
 # --- Parse User Count ---
 user_count=$(yq '.users | length' "$CONFIG_FILE" 2>/dev/null || echo "0")
+group_count=$(yq '.groups | length' "$CONFIG_FILE" 2>/dev/null || echo "0")
 
 # --- Output Variables ---
 echo "YAML_ADDRESS='$server_address'"
+echo "YAML_ADDRESS_DISCOVERY_URL='$server_address_discovery_url'"
+echo "YAML_ADDRESS_REFRESH='$server_address_refresh'"
+echo "YAML_INTERNAL_ADDRESS='$server_internal_address'"
+echo "YAML_INTERNAL_CIDRS='$server_internal_cidrs'"
+echo "YAML_LISTEN='$server_listen'"
+echo "YAML_ACTIVE_MODE='$server_active_mode'"
+echo "YAML_ALLOW_FXP='$server_allow_fxp'"
+echo "YAML_ACTIVE_SOURCE_PORT='$server_active_source_port'"
+echo "YAML_LISTEN_ADDRESS='$server_listen_address'"
 echo "YAML_MIN_PORT='$server_min_port'"
 echo "YAML_MAX_PORT='$server_max_port'"
 echo "YAML_TLS_CERT='$server_tls_cert'"
 echo "YAML_TLS_KEY='$server_tls_key'"
+echo "YAML_ALLOW_CIDRS='$server_allow_cidrs'"
+echo "YAML_DENY_CIDRS='$server_deny_cidrs'"
+echo "YAML_MAX_CLIENTS='$server_max_clients'"
+echo "YAML_MAX_PER_IP='$server_max_per_ip'"
+echo "YAML_IDLE_TIMEOUT='$server_idle_timeout'"
+echo "YAML_DATA_TIMEOUT='$server_data_timeout'"
+echo "YAML_MAX_USER_SESSIONS='$server_max_user_sessions'"
+echo "YAML_MAX_UPLOAD_RATE='$server_max_upload_rate'"
+echo "YAML_MAX_DOWNLOAD_RATE='$server_max_download_rate'"
+echo "YAML_ANON_ENABLED='$anon_enabled'"
+echo "YAML_ANON_ROOT='$anon_root'"
+echo "YAML_ANON_INCOMING_DIR='$anon_incoming_dir'"
+echo "YAML_ANON_MAX_RATE='$anon_max_rate'"
+echo "YAML_USER_MODE='$server_user_mode'"
+echo "YAML_VIRTUAL_ACCOUNT='$server_virtual_account'"
+echo "YAML_LDAP_URL='$ldap_url'"
+echo "YAML_LDAP_BIND_DN='$ldap_bind_dn'"
+echo "YAML_LDAP_BIND_PASSWORD_ENV='$ldap_bind_password_env'"
+echo "YAML_LDAP_BASE_DN='$ldap_base_dn'"
+echo "YAML_LDAP_USER_FILTER='$ldap_user_filter'"
+echo "YAML_LDAP_GROUP_FILTER='$ldap_group_filter'"
+echo "YAML_LDAP_HOME_ATTRIBUTE='$ldap_home_attribute'"
+echo "YAML_VAULT_ADDRESS='$vault_address'"
+echo "YAML_VAULT_TOKEN_ENV='$vault_token_env'"
+echo "YAML_VAULT_ROLE_ID='$vault_role_id'"
+echo "YAML_VAULT_SECRET_ID_ENV='$vault_secret_id_env'"
+echo "YAML_VAULT_APPROLE_PATH='$vault_approle_path'"
+echo "YAML_VAULT_REFRESH='$vault_refresh'"
+echo "YAML_AGE_KEY_FILE='$age_key_file'"
 echo "YAML_USER_COUNT=$user_count"
+echo "YAML_GROUP_COUNT=$group_count"
 
 # --- Parse User Details ---
 i=0
 while [ "$i" -lt "$user_count" ]; do
   username=$(yq ".users[$i].username // \"\"" "$CONFIG_FILE")
   pass_env=$(yq ".users[$i].password_env // \"\"" "$CONFIG_FILE")
+  allow_cidrs=$(yq ".users[$i].allow_cidrs // [] | join(\" \")" "$CONFIG_FILE")
+  deny_cidrs=$(yq ".users[$i].deny_cidrs // [] | join(\" \")" "$CONFIG_FILE")
+  max_sessions=$(yq ".users[$i].max_sessions // \"\"" "$CONFIG_FILE")
+  max_upload_rate=$(yq ".users[$i].max_upload_rate // \"\"" "$CONFIG_FILE")
+  max_download_rate=$(yq ".users[$i].max_download_rate // \"\"" "$CONFIG_FILE")
+  quota=$(yq ".users[$i].quota // \"\"" "$CONFIG_FILE")
+  admin=$(yq ".users[$i].admin // \"\"" "$CONFIG_FILE")
+  read_only=$(yq ".users[$i].read_only // \"\"" "$CONFIG_FILE")
+  service_account=$(yq ".users[$i].service_account // \"\"" "$CONFIG_FILE")
+  vault_path=$(yq ".users[$i].password_vault.path // \"\"" "$CONFIG_FILE")
+  vault_field=$(yq ".users[$i].password_vault.field // \"password\"" "$CONFIG_FILE")
+  password_age=$(yq ".users[$i].password_age // \"\"" "$CONFIG_FILE")
 
   # Fetch password override from environment variable
   env_val="$(printenv "$pass_env")"
@@ -64,5 +252,35 @@ while [ "$i" -lt "$user_count" ]; do
   echo "YAML_USER_${i}_NAME='$username'"
   echo "YAML_USER_${i}_PASS_ENV='$pass_env'"
   echo "YAML_USER_${i}_PASS_OVERRIDE='${env_val}'"
+  echo "YAML_USER_${i}_ALLOW_CIDRS='$allow_cidrs'"
+  echo "YAML_USER_${i}_DENY_CIDRS='$deny_cidrs'"
+  echo "YAML_USER_${i}_MAX_SESSIONS='$max_sessions'"
+  echo "YAML_USER_${i}_MAX_UPLOAD_RATE='$max_upload_rate'"
+  echo "YAML_USER_${i}_MAX_DOWNLOAD_RATE='$max_download_rate'"
+  echo "YAML_USER_${i}_QUOTA='$quota'"
+  echo "YAML_USER_${i}_ADMIN='$admin'"
+  echo "YAML_USER_${i}_READ_ONLY='$read_only'"
+  echo "YAML_USER_${i}_SERVICE_ACCOUNT='$service_account'"
+  echo "YAML_USER_${i}_VAULT_PATH='$vault_path'"
+  echo "YAML_USER_${i}_VAULT_FIELD='$vault_field'"
+  echo "YAML_USER_${i}_PASSWORD_AGE='$password_age'"
+  i=$((i + 1))
+done
+
+# --- Parse Group Details ---
+i=0
+while [ "$i" -lt "$group_count" ]; do
+  group_name=$(yq ".groups[$i].name // \"\"" "$CONFIG_FILE")
+  group_gid=$(yq ".groups[$i].gid // \"\"" "$CONFIG_FILE")
+  group_directory=$(yq ".groups[$i].directory // \"\"" "$CONFIG_FILE")
+  group_members=$(yq ".groups[$i].members // [] | join(\" \")" "$CONFIG_FILE")
+  group_quota=$(yq ".groups[$i].quota // \"\"" "$CONFIG_FILE")
+
+  # --- Output Resolved Variables ---
+  echo "YAML_GROUP_${i}_NAME='$group_name'"
+  echo "YAML_GROUP_${i}_GID='$group_gid'"
+  echo "YAML_GROUP_${i}_DIRECTORY='$group_directory'"
+  echo "YAML_GROUP_${i}_MEMBERS='$group_members'"
+  echo "YAML_GROUP_${i}_QUOTA='$group_quota'"
   i=$((i + 1))
-done
\ No newline at end of file
+done
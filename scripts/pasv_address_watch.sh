@@ -0,0 +1,110 @@
+#!/usr/bin/env bash
+# pasv_address_watch - Resolves passive addresses and keeps them up to date
+#
+# Usage: pasv_address_watch [--once]
+#
+# Reads ADDRESS, INTERNAL_ADDRESS and INTERNAL_CIDRS from the environment and
+# writes the passive address map read by pasv.so when a session logs in. Each
+# line holds a client CIDR and the address advertised to it; internal ranges
+# come first and 0.0.0.0/0 maps everyone else to ADDRESS. Without --once the
+# map is rebuilt every ADDRESS_REFRESH seconds, so DNS changes and new public
+# addresses apply to new sessions without a restart.
+
+PASV_DIR="${PASV_DIR:-/etc/vsftpd/pasv}"
+ADDRESS_REFRESH="${ADDRESS_REFRESH:-300}"
+DEFAULT_INTERNAL_CIDRS="10.0.0.0/8 172.16.0.0/12 192.168.0.0/16"
+
+MAP_FILE="$PASV_DIR/map"
+INTERNAL_CIDRS="${INTERNAL_CIDRS//,/ }"
+
+# --- Validate Settings ---
+if [ -n "$INTERNAL_ADDRESS" ] && [ -z "${INTERNAL_CIDRS// /}" ]; then
+  INTERNAL_CIDRS="$DEFAULT_INTERNAL_CIDRS"
+fi
+
+for cidr in $INTERNAL_CIDRS; do
+  if ! cidr_match --validate "$cidr"; then
+    log ERROR "❌ Invalid CIDR '$cidr' in internal_cidrs."
+    exit 1
+  fi
+done
+
+if [ -n "${INTERNAL_CIDRS// /}" ] && [ -z "$INTERNAL_ADDRESS" ]; then
+  log WARN "🚧 internal_cidrs is set without internal_address. Internal clients get the external address."
+fi
+
+if [[ ! "$ADDRESS_REFRESH" =~ ^[0-9]+$ ]] || [ "$ADDRESS_REFRESH" -eq 0 ]; then
+  log WARN "🚧 Ignoring invalid address_refresh '$ADDRESS_REFRESH'. Using 300 seconds."
+  ADDRESS_REFRESH=300
+fi
+
+# Static IPv4 addresses never change, so only names and auto need refreshing
+is_dynamic() {
+  [ -n "$1" ] && [[ ! "$1" =~ ^[0-9.]+$ ]]
+}
+
+# Logs an address whenever it differs from the last resolved value
+report() {
+  local scope="$1" previous="$2" current="$3"
+  if [ "$previous" != "$current" ]; then
+    log INFO "🌐 Passive address for $scope clients: $current"
+  fi
+}
+
+# --- Build Map ---
+# Start from the current map so a running watcher only logs real changes
+EXTERNAL="$(awk '$1 == "0.0.0.0/0" { print $2 }' "$MAP_FILE" 2>/dev/null)"
+INTERNAL="$(awk '$1 != "0.0.0.0/0" { print $2; exit }' "$MAP_FILE" 2>/dev/null)"
+
+refresh() {
+  local external="" internal="" tmp="$MAP_FILE.tmp"
+
+  if [ -n "$ADDRESS" ]; then
+    # Keep the last good value if a refresh fails
+    external=$(resolve_address "$ADDRESS") || external="$EXTERNAL"
+  fi
+  if [ -n "$INTERNAL_ADDRESS" ]; then
+    internal=$(resolve_address "$INTERNAL_ADDRESS") || internal="$INTERNAL"
+  fi
+
+  mkdir -p "$PASV_DIR"
+  : > "$tmp"
+  if [ -n "$internal" ]; then
+    for cidr in $INTERNAL_CIDRS; do
+      echo "$cidr $internal" >> "$tmp"
+    done
+  fi
+  if [ -n "$external" ]; then
+    echo "0.0.0.0/0 $external" >> "$tmp"
+  fi
+  mv "$tmp" "$MAP_FILE"
+
+  report internal "$INTERNAL" "$internal"
+  report external "$EXTERNAL" "$external"
+  INTERNAL="$internal"
+  EXTERNAL="$external"
+}
+
+refresh
+
+# "auto" has no fallback, so a failed first lookup stops the server
+if { [ "$ADDRESS" = "auto" ] && [ -z "$EXTERNAL" ]; } ||
+  { [ "$INTERNAL_ADDRESS" = "auto" ] && [ -z "$INTERNAL" ]; }; then
+  exit 1
+fi
+
+if [ "$1" = "--once" ]; then
+  exit 0
+fi
+
+if ! is_dynamic "$ADDRESS" && ! is_dynamic "$INTERNAL_ADDRESS"; then
+  log DEBUG "🌐 Passive addresses are static. Address refresh is not needed."
+  exit 0
+fi
+
+# --- Refresh Loop ---
+log INFO "🌐 Refreshing passive addresses every $ADDRESS_REFRESH seconds"
+while true; do
+  sleep "$ADDRESS_REFRESH"
+  refresh
+done
@@ -0,0 +1,87 @@
+#!/usr/bin/env bash
+# quota_guard - Publishes disk quotas to quota.so and usage metrics
+#
+# Usage: quota_guard [--once]
+#
+# Quotas are enforced inside vsftpd by quota.so, which reads the scopes file
+# written here when a session starts. Each "<quota_bytes> <directory>" line
+# is a user's directory or a shared group folder with a quota of its own.
+# Group folders come first, so writes into them are charged to the group;
+# folders of groups without a quota count towards their members' quotas.
+# Every QUOTA_INTERVAL seconds the file is rewritten and usage is written in
+# Prometheus text format to QUOTA_METRICS_FILE for node_exporter's textfile
+# collector.
+
+QUOTA_DIR="${QUOTA_DIR:-/etc/vsftpd/quotas}"
+QUOTA_INTERVAL="${QUOTA_INTERVAL:-5}"
+QUOTA_METRICS_FILE="${QUOTA_METRICS_FILE:-/var/run/mini-ftp/metrics.prom}"
+QUOTA_SCOPES="${QUOTA_SCOPES:-$QUOTA_DIR/.scopes}"
+
+# --- Single Enforcement Pass ---
+check_quotas() {
+  local quota_metrics="" usage_metrics="" group_quota_metrics="" group_usage_metrics="" scopes=""
+  local name quota usage
+
+  for quota_file in "$QUOTA_DIR"/.groups/*; do
+    [ -f "$quota_file" ] || continue
+
+    name="$(basename "$quota_file")"
+    quota="$(cat "$quota_file")"
+    usage="$(quota_usage --group "$name")"
+
+    scopes+="$quota $(quota_usage --dir --group "$name")"$'\n'
+    group_quota_metrics+="mini_ftp_group_quota_bytes{group=\"$name\"} $quota"$'\n'
+    group_usage_metrics+="mini_ftp_group_usage_bytes{group=\"$name\"} $usage"$'\n'
+  done
+
+  for quota_file in "$QUOTA_DIR"/*; do
+    [ -f "$quota_file" ] || continue
+
+    name="$(basename "$quota_file")"
+    quota="$(cat "$quota_file")"
+    usage="$(quota_usage "$name")"
+
+    scopes+="$quota $(quota_usage --dir "$name")"$'\n'
+    quota_metrics+="mini_ftp_user_quota_bytes{user=\"$name\"} $quota"$'\n'
+    usage_metrics+="mini_ftp_user_usage_bytes{user=\"$name\"} $usage"$'\n'
+  done
+
+  # --- Publish Scopes ---
+  local tmp
+  mkdir -p "$(dirname "$QUOTA_SCOPES")"
+  tmp="$(mktemp "$QUOTA_SCOPES.XXXXXX")" || return
+  printf "%s" "$scopes" > "$tmp"
+  chmod 644 "$tmp"
+  mv "$tmp" "$QUOTA_SCOPES"
+
+  # --- Publish Metrics ---
+  mkdir -p "$(dirname "$QUOTA_METRICS_FILE")"
+  {
+    echo "# HELP mini_ftp_user_quota_bytes Disk quota configured for the user."
+    echo "# TYPE mini_ftp_user_quota_bytes gauge"
+    printf "%s" "$quota_metrics"
+    echo "# HELP mini_ftp_user_usage_bytes Bytes stored in the user's FTP directory."
+    echo "# TYPE mini_ftp_user_usage_bytes gauge"
+    printf "%s" "$usage_metrics"
+    if [ -n "$group_quota_metrics" ]; then
+      echo "# HELP mini_ftp_group_quota_bytes Disk quota configured for the shared group folder."
+      echo "# TYPE mini_ftp_group_quota_bytes gauge"
+      printf "%s" "$group_quota_metrics"
+      echo "# HELP mini_ftp_group_usage_bytes Bytes stored in the shared group folder."
+      echo "# TYPE mini_ftp_group_usage_bytes gauge"
+      printf "%s" "$group_usage_metrics"
+    fi
+  } > "$QUOTA_METRICS_FILE.tmp"
+  mv "$QUOTA_METRICS_FILE.tmp" "$QUOTA_METRICS_FILE"
+}
+
+if [ "$1" = "--once" ]; then
+  check_quotas
+  exit 0
+fi
+
+log INFO "📦 Quota guard started (checking every ${QUOTA_INTERVAL}s)"
+while true; do
+  check_quotas
+  sleep "$QUOTA_INTERVAL"
+done
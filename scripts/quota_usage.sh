@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+# quota_usage - Prints the number of bytes stored in a user's FTP directory
+#
+# Usage: quota_usage [--dir] [--group] <name>
+#
+# Usage is the sum of apparent file sizes, so it does not depend on the
+# filesystem's block size or quota support. With --group the usage of a
+# shared group folder is printed instead, and with --dir the directory is
+# printed instead of its usage.
+#
+# A shared folder mounted into a user's tree counts towards the user's usage
+# unless the group has a quota of its own.
+
+FTP_ROOT="${FTP_ROOT:-/ftp}"
+GROUP_ROOT="${GROUP_ROOT:-/ftp/.groups}"
+QUOTA_DIR="${QUOTA_DIR:-/etc/vsftpd/quotas}"
+USER_CONFIG_DIR="${USER_CONFIG_DIR:-/etc/vsftpd/users}"
+
+PRINT_DIR=0
+GROUP=0
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --dir) PRINT_DIR=1 ;;
+    --group) GROUP=1 ;;
+    *) break ;;
+  esac
+  shift
+done
+
+NAME="$1"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  echo "Usage: quota_usage [--dir] [--group] <name>" >&2
+  exit 1
+fi
+
+if [ "$GROUP" -eq 1 ]; then
+  USER_DIR="$GROUP_ROOT/$NAME"
+else
+  # Imported users may have a home that is not named after them
+  HOME_NAME="$NAME"
+  HOME_DIR="$(getent passwd "$NAME" | cut -d: -f6)"
+  if [ -z "$HOME_DIR" ]; then
+    HOME_DIR="$(sed -n 's/^local_root=//p' "$USER_CONFIG_DIR/$NAME" 2>/dev/null | tail -n 1)"
+  fi
+  if [ "$(dirname "${HOME_DIR:-/}")" = "/ftp" ]; then
+    HOME_NAME="$(basename "$HOME_DIR")"
+  fi
+  USER_DIR="$FTP_ROOT/$HOME_NAME"
+fi
+
+if [ "$PRINT_DIR" -eq 1 ]; then
+  echo "$USER_DIR"
+  exit 0
+fi
+
+if [ ! -d "$USER_DIR" ]; then
+  echo 0
+  exit 0
+fi
+
+# Shared folders of groups with a quota are charged to the group, so they
+# are left out wherever they are mounted
+CHARGED=()
+for quota_file in "$QUOTA_DIR"/.groups/*; do
+  [ -f "$quota_file" ] || continue
+  CHARGED+=("$(stat -c %d:%i "$GROUP_ROOT/$(basename "$quota_file")" 2>/dev/null)")
+done
+
+PRUNE=()
+while read -r mount_point; do
+  identity="$(stat -c %d:%i "$mount_point" 2>/dev/null)"
+  for charged in "${CHARGED[@]}"; do
+    if [ "$identity" = "$charged" ]; then
+      PRUNE+=(-path "$mount_point" -prune -o)
+    fi
+  done
+done < <(awk -v dir="$USER_DIR/" 'index($5, dir) == 1 { print $5 }' /proc/self/mountinfo 2>/dev/null)
+
+find "$USER_DIR" "${PRUNE[@]}" -type f -exec stat -c %s {} + 2>/dev/null | awk '{ total += $1 } END { printf "%.0f\n", total }'
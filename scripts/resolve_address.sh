@@ -0,0 +1,83 @@
+#!/usr/bin/env bash
+# resolve_address - Resolves a passive address setting to an IPv4 address
+#
+# Usage: resolve_address <address|hostname|auto>
+#
+# IPv4 addresses are printed unchanged and DNS names are resolved. "auto"
+# asks ADDRESS_DISCOVERY_URL for the public address when it is set (any
+# endpoint that replies with the caller's IP as plain text), and otherwise
+# uses the address of the interface holding the default route.
+
+SPEC="$1"
+ADDRESS_DISCOVERY_URL="${ADDRESS_DISCOVERY_URL:-}"
+
+is_ipv4() {
+  [[ "$1" =~ ^[0-9]{1,3}(\.[0-9]{1,3}){3}$ ]] && cidr_match --validate "$1/32"
+}
+
+# --- Discovery Endpoint ---
+from_discovery_url() {
+  local address
+  address=$(curl -fsS --max-time 5 --retry 5 --retry-delay 1 --retry-connrefused \
+    "$ADDRESS_DISCOVERY_URL" 2>/dev/null | tr -d '[:space:]')
+
+  if ! is_ipv4 "$address"; then
+    log ERROR "❌ Address discovery at $ADDRESS_DISCOVERY_URL did not return an IPv4 address." >&2
+    return 1
+  fi
+  echo "$address"
+}
+
+# --- Default Route ---
+from_default_route() {
+  local device address
+  device=$(ip -4 route show default 2>/dev/null | awk '{ for (i = 1; i < NF; i++) if ($i == "dev") { print $(i + 1); exit } }')
+  address=$(ip -4 -o addr show dev "$device" 2>/dev/null | awk '{ split($4, a, "/"); print a[1]; exit }')
+
+  if [ -z "$device" ] || ! is_ipv4 "$address"; then
+    log ERROR "❌ Could not find an IPv4 address on the default route." >&2
+    return 1
+  fi
+  echo "$address"
+}
+
+# --- DNS ---
+from_dns() {
+  local address
+  address=$(getent hosts "$SPEC" 2>/dev/null | awk '$1 ~ /^[0-9.]+$/ { print $1; exit }')
+
+  # getent may only return IPv6 results, so ask DNS for an A record directly
+  if [ -z "$address" ]; then
+    address=$(nslookup -type=a "$SPEC" 2>/dev/null | awk '/^Name:/ { found = 1 } found && /^Address/ { print $2; exit }')
+  fi
+
+  if ! is_ipv4 "$address"; then
+    log ERROR "❌ Could not resolve '$SPEC' to an IPv4 address." >&2
+    return 1
+  fi
+  echo "$address"
+}
+
+# --- Error Handling ---
+if [ -z "$SPEC" ]; then
+  log ERROR "Usage: resolve_address <address|hostname|auto>" >&2
+  exit 1
+fi
+
+# --- Resolve ---
+if [ "$SPEC" = "auto" ]; then
+  if [ -n "$ADDRESS_DISCOVERY_URL" ]; then
+    from_discovery_url || exit 1
+  else
+    from_default_route || exit 1
+  fi
+elif is_ipv4 "$SPEC"; then
+  echo "$SPEC"
+elif [[ "$SPEC" =~ ^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$ ]]; then
+  from_dns || exit 1
+else
+  log ERROR "❌ Invalid passive address '$SPEC'. Expected an IPv4 address, a DNS name or auto." >&2
+  exit 1
+fi
+
+exit 0
@@ -0,0 +1,69 @@
+#!/usr/bin/env bash
+# set_access_rules - Writes the IP allow/deny rules enforced by check_access
+#
+# Usage: set_access_rules <--global|username> "<allow_cidrs>" "<deny_cidrs>"
+#
+# CIDR lists may be separated by spaces or commas. Rules are stored in
+# /etc/vsftpd/access, one "allow <cidr>" or "deny <cidr>" entry per line.
+# The rules access.so checks when a client connects are rewritten from them.
+
+ACCESS_DIR="${ACCESS_DIR:-/etc/vsftpd/access}"
+
+SCOPE="$1"
+ALLOW_CIDRS="${2//,/ }"
+DENY_CIDRS="${3//,/ }"
+
+# --- Error Handling ---
+if [ -z "$SCOPE" ]; then
+  log ERROR "Usage: set_access_rules <--global|username> <allow_cidrs> <deny_cidrs>"
+  exit 1
+fi
+
+if [ "$SCOPE" = "--global" ]; then
+  RULES_FILE="$ACCESS_DIR/global"
+  LABEL="server"
+else
+  RULES_FILE="$ACCESS_DIR/users/$SCOPE"
+  LABEL="user '$SCOPE'"
+fi
+
+# --- Validate CIDRs ---
+for cidr in $ALLOW_CIDRS $DENY_CIDRS; do
+  if ! cidr_match --validate "$cidr"; then
+    log ERROR "Invalid CIDR '$cidr' in access list for $LABEL"
+    exit 1
+  fi
+done
+
+# --- Write Rules ---
+mkdir -p "$(dirname "$RULES_FILE")"
+: > "$RULES_FILE"
+
+for cidr in $ALLOW_CIDRS; do
+  echo "allow $cidr" >> "$RULES_FILE"
+done
+
+for cidr in $DENY_CIDRS; do
+  echo "deny $cidr" >> "$RULES_FILE"
+done
+
+chmod 644 "$RULES_FILE"
+
+# --- Connection Rules ---
+# Server deny rules apply to everyone. With a server allow list, an address
+# outside it may still connect if some user's own allow list admits it.
+CONNECT_FILE="$ACCESS_DIR/connect"
+{
+  awk '$1 == "deny"' "$ACCESS_DIR/global" 2>/dev/null
+  if grep -q '^allow ' "$ACCESS_DIR/global" 2>/dev/null; then
+    cat "$ACCESS_DIR/global" "$ACCESS_DIR"/users/* 2>/dev/null | awk '$1 == "allow"' | sort -u
+  fi
+} > "$CONNECT_FILE.tmp"
+chmod 644 "$CONNECT_FILE.tmp"
+mv "$CONNECT_FILE.tmp" "$CONNECT_FILE"
+
+if [ -n "$ALLOW_CIDRS" ] || [ -n "$DENY_CIDRS" ]; then
+  log INFO "🛡️ Access rules for $LABEL: allow=[${ALLOW_CIDRS:-any}] deny=[${DENY_CIDRS:-none}]"
+fi
+
+exit 0
@@ -0,0 +1,51 @@
+#!/usr/bin/env bash
+# set_password - Changes the password of an existing FTP user
+#
+# Usage: set_password <username> < password
+#
+# The new password is read from the first line of stdin. Virtual users have
+# their hash replaced in VIRTUAL_USER_DB and system users are updated with
+# usermod. The change applies to the next login.
+
+VIRTUAL_USER_DB="${VIRTUAL_USER_DB:-/etc/vsftpd/virtual_users}"
+
+NAME="$1"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  log ERROR "Usage: set_password <username> < password"
+  exit 1
+fi
+
+IFS= read -r PASSWORD
+if [ -z "$PASSWORD" ]; then
+  log ERROR "The new password for user '$NAME' is empty"
+  exit 1
+fi
+
+HASH="$(printf "%s\n" "$PASSWORD" | mkpasswd -m sha512)"
+if [ -z "$HASH" ]; then
+  log ERROR "Failed to hash the password for user '$NAME'"
+  exit 1
+fi
+
+# --- Virtual Users ---
+if [ -f "$VIRTUAL_USER_DB" ] && awk -F: -v name="$NAME" '$1 == name { found = 1 } END { exit !found }' "$VIRTUAL_USER_DB"; then
+  awk -F: -v OFS=: -v name="$NAME" -v hash="$HASH" '$1 == name { $2 = hash } { print }' \
+    "$VIRTUAL_USER_DB" > "$VIRTUAL_USER_DB.tmp"
+  chmod 600 "$VIRTUAL_USER_DB.tmp"
+  mv "$VIRTUAL_USER_DB.tmp" "$VIRTUAL_USER_DB"
+  exit 0
+fi
+
+# --- System Users ---
+if id "$NAME" &>/dev/null; then
+  if ! usermod -p "$HASH" "$NAME"; then
+    log ERROR "Failed to change the password for user '$NAME'"
+    exit 1
+  fi
+  exit 0
+fi
+
+log ERROR "User '$NAME' does not exist"
+exit 1
@@ -0,0 +1,51 @@
+#!/usr/bin/env bash
+# set_quota - Sets the disk quota for a user or a shared group folder
+#
+# Usage: set_quota [--group] <name> <quota>
+#
+# The quota uses parse_size units, e.g. "10GiB". It is stored in bytes and
+# published to quota.so by quota_guard. An empty value or 0 removes the
+# quota. Group quotas are kept in a .groups subdirectory, so they never
+# clash with a user of the same name.
+
+QUOTA_DIR="${QUOTA_DIR:-/etc/vsftpd/quotas}"
+
+KIND="user"
+if [ "$1" = "--group" ]; then
+  KIND="group"
+  QUOTA_DIR="$QUOTA_DIR/.groups"
+  shift
+fi
+
+NAME="$1"
+QUOTA="$2"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  log ERROR "Usage: set_quota [--group] <name> <quota>"
+  exit 1
+fi
+
+QUOTA_FILE="$QUOTA_DIR/$NAME"
+
+if [ -z "$QUOTA" ] || [ "$QUOTA" = "0" ]; then
+  rm -f "$QUOTA_FILE"
+  exit 0
+fi
+
+if ! QUOTA_BYTES=$(parse_size "$QUOTA"); then
+  log ERROR "Invalid quota '$QUOTA' for $KIND '$NAME'. Expected a size such as 10GiB."
+  exit 1
+fi
+
+# --- Write Quota ---
+mkdir -p "$QUOTA_DIR"
+echo "$QUOTA_BYTES" > "$QUOTA_FILE"
+chmod 644 "$QUOTA_FILE"
+
+if [ "$KIND" = "group" ]; then
+  log INFO "📦 Group '$NAME' has a quota of $QUOTA ($QUOTA_BYTES bytes)"
+else
+  log INFO "📦 User '$NAME' has a quota of $QUOTA ($QUOTA_BYTES bytes)"
+fi
+exit 0
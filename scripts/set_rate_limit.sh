@@ -0,0 +1,56 @@
+#!/usr/bin/env bash
+# set_rate_limit - Applies bandwidth limits to a single user
+#
+# Usage: set_rate_limit <username> <max_upload_rate> <max_download_rate>
+#
+# Rates use parse_size units with an optional "/s" suffix, e.g. "5MiB/s".
+# They are written to RATE_DIR as "<upload> <download>" bytes per second and
+# applied by rate.so, since vsftpd's local_max_rate covers both directions.
+# A direction without a rate stays unlimited.
+
+RATE_DIR="${RATE_DIR:-/etc/vsftpd/rates}"
+
+NAME="$1"
+UPLOAD_RATE="$2"
+DOWNLOAD_RATE="$3"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  log ERROR "Usage: set_rate_limit <username> <max_upload_rate> <max_download_rate>"
+  exit 1
+fi
+
+# Converts a rate to bytes per second, or fails with the offending field
+to_bytes() {
+  local field="$1" rate="$2" bytes
+  [ -n "$rate" ] || return 0
+
+  if ! bytes=$(parse_size "${rate%/s}"); then
+    log ERROR "Invalid $field '$rate' for user '$NAME'. Expected a rate such as 5MiB/s." >&2
+    return 1
+  fi
+  echo "$bytes"
+}
+
+UPLOAD_BYTES=$(to_bytes max_upload_rate "$UPLOAD_RATE") || exit 1
+DOWNLOAD_BYTES=$(to_bytes max_download_rate "$DOWNLOAD_RATE") || exit 1
+
+# --- Write Limit ---
+RATE_FILE="$RATE_DIR/$NAME"
+UPLOAD_BYTES="${UPLOAD_BYTES:-0}"
+DOWNLOAD_BYTES="${DOWNLOAD_BYTES:-0}"
+
+if [ "$UPLOAD_BYTES" -eq 0 ] && [ "$DOWNLOAD_BYTES" -eq 0 ]; then
+  rm -f "$RATE_FILE"
+  exit 0
+fi
+
+mkdir -p "$RATE_DIR"
+echo "$UPLOAD_BYTES $DOWNLOAD_BYTES" > "$RATE_FILE"
+chmod 644 "$RATE_FILE"
+
+describe() {
+  if [ "$1" -eq 0 ]; then echo "unlimited"; else echo "$1 bytes/s"; fi
+}
+log INFO "🐢 User '$NAME' rates: upload $(describe "$UPLOAD_BYTES"), download $(describe "$DOWNLOAD_BYTES")"
+exit 0
@@ -0,0 +1,38 @@
+#!/usr/bin/env bash
+# set_session_limit - Sets the maximum number of concurrent sessions for a user
+#
+# Usage: set_session_limit <username> <max_sessions>
+#
+# The limit is enforced by check_access at login. An empty value or 0 removes
+# the limit.
+
+ACCESS_DIR="${ACCESS_DIR:-/etc/vsftpd/access}"
+
+NAME="$1"
+MAX_SESSIONS="$2"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  log ERROR "Usage: set_session_limit <username> <max_sessions>"
+  exit 1
+fi
+
+SESSION_FILE="$ACCESS_DIR/sessions/$NAME"
+
+if [ -z "$MAX_SESSIONS" ] || [ "$MAX_SESSIONS" = "0" ]; then
+  rm -f "$SESSION_FILE"
+  exit 0
+fi
+
+if [[ ! "$MAX_SESSIONS" =~ ^[0-9]+$ ]]; then
+  log ERROR "Invalid session limit '$MAX_SESSIONS' for user '$NAME'. Expected a whole number."
+  exit 1
+fi
+
+# --- Write Limit ---
+mkdir -p "$(dirname "$SESSION_FILE")"
+echo "$MAX_SESSIONS" > "$SESSION_FILE"
+chmod 644 "$SESSION_FILE"
+
+log INFO "🔢 User '$NAME' is limited to $MAX_SESSIONS concurrent session(s)"
+exit 0
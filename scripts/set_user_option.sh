@@ -0,0 +1,49 @@
+#!/usr/bin/env bash
+# set_user_option - Sets a vsftpd option for a single user
+#
+# Usage: set_user_option <username> <option> [value]
+#
+# Options are written to the user's file in vsftpd's user_config_dir and are
+# applied when that user logs in. Setting an option again replaces it, and
+# omitting the value removes it.
+
+USER_CONFIG_DIR="${USER_CONFIG_DIR:-/etc/vsftpd/users}"
+
+NAME="$1"
+OPTION="$2"
+VALUE="$3"
+
+# --- Error Handling ---
+if [ -z "$NAME" ] || [ -z "$OPTION" ]; then
+  log ERROR "Usage: set_user_option <username> <option> [value]"
+  exit 1
+fi
+
+if [[ ! "$OPTION" =~ ^[a-z_]+$ ]]; then
+  log ERROR "Invalid vsftpd option: '$OPTION'"
+  exit 1
+fi
+
+CONFIG_FILE="$USER_CONFIG_DIR/$NAME"
+
+# --- Write Option ---
+# Writers are serialized so concurrent updates to one file are not lost, and
+# the file is replaced in one rename so vsftpd never reads half of it
+mkdir -p "$USER_CONFIG_DIR"
+exec 9>"$USER_CONFIG_DIR/.lock"
+flock 9
+
+TMP_FILE="$(mktemp "$USER_CONFIG_DIR/.$NAME.XXXXXX")" || exit 1
+grep -v "^$OPTION=" "$CONFIG_FILE" > "$TMP_FILE" 2>/dev/null
+if [ -n "$VALUE" ]; then
+  echo "$OPTION=$VALUE" >> "$TMP_FILE"
+fi
+chmod 644 "$TMP_FILE"
+mv "$TMP_FILE" "$CONFIG_FILE"
+
+if [ -n "$VALUE" ]; then
+  log DEBUG "🔧 Set $OPTION=$VALUE for user '$NAME'"
+else
+  log DEBUG "🔧 Removed $OPTION for user '$NAME'"
+fi
+exit 0
@@ -0,0 +1,56 @@
+#!/usr/bin/env bash
+# share_home - Gives writable admins access to a user's home
+#
+# Usage: share_home <directory> [account]
+#
+# Each admin account in ADMIN_LIST, or only the given account, is added to the
+# ACLs of the home and everything in it, so the owner's modes and umask are
+# left alone. Default ACLs give the admin access to new files, and give the
+# owner access to files the admin creates. Shared group folders mounted into
+# the home keep their own permissions.
+
+ADMIN_LIST="${ADMIN_LIST:-/etc/vsftpd/admins}"
+
+HOME_DIR="${1%/}"
+ACCOUNT="$2"
+
+# --- Error Handling ---
+if [ -z "$HOME_DIR" ]; then
+  log ERROR "Usage: share_home <directory> [account]"
+  exit 1
+fi
+
+if [ ! -d "$HOME_DIR" ]; then
+  log ERROR "Home '$HOME_DIR' does not exist"
+  exit 1
+fi
+
+if [ -n "$ACCOUNT" ]; then
+  ACCOUNTS="$ACCOUNT"
+elif ! ACCOUNTS="$(cat "$ADMIN_LIST" 2>/dev/null)"; then
+  exit 0
+fi
+
+OWNER="$(stat -c %U "$HOME_DIR")"
+
+# Shared group folders are bind mounts below the home
+PRUNE=()
+while read -r mount_point; do
+  PRUNE+=(-path "$mount_point" -prune -o)
+done < <(awk -v dir="$HOME_DIR/" 'index($5, dir) == 1 { print $5 }' /proc/self/mountinfo 2>/dev/null)
+
+# --- Grant Access ---
+for account in $ACCOUNTS; do
+  if [ "$account" = "$OWNER" ]; then
+    continue
+  fi
+
+  if ! find "$HOME_DIR" "${PRUNE[@]}" -exec setfacl -m "u:$account:rwX" {} + || \
+    ! find "$HOME_DIR" "${PRUNE[@]}" -type d -exec setfacl -d -m "u:$account:rwx,u:$OWNER:rwx" {} +; then
+    log ERROR "Failed to give admin '$account' access to $HOME_DIR. Does the filesystem support ACLs?"
+    exit 1
+  fi
+  log DEBUG "🔧 Admin account '$account' can write to $HOME_DIR"
+done
+
+exit 0
@@ -0,0 +1,32 @@
+#!/usr/bin/env bash
+# user_account - Prints the Linux account an FTP user's sessions run as
+#
+# Usage: user_account <username>
+#
+# Virtual users run as the service account recorded in VIRTUAL_USER_DB.
+# System users run as their own account. Exits 1 for unknown users.
+
+VIRTUAL_USER_DB="${VIRTUAL_USER_DB:-/etc/vsftpd/virtual_users}"
+
+NAME="$1"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  echo "Usage: user_account <username>" >&2
+  exit 1
+fi
+
+# --- Virtual Users ---
+ACCOUNT="$(awk -F: -v name="$NAME" '$1 == name { print $3; exit }' "$VIRTUAL_USER_DB" 2>/dev/null)"
+if [ -n "$ACCOUNT" ]; then
+  echo "$ACCOUNT"
+  exit 0
+fi
+
+# --- System Users ---
+if id "$NAME" &>/dev/null; then
+  echo "$NAME"
+  exit 0
+fi
+
+exit 1
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+# user_sessions - Prints the process IDs of a user's logged-in FTP sessions
+#
+# Usage: user_sessions <username>
+#
+# Every logged-in session runs a vsftpd process as the user's account, titled
+# "vsftpd: <ip>/<username>: <status>" (setproctitle_enable). Several users
+# can share an account, so sessions are matched by the login name in the
+# title. The name is compared as plain text, never as a pattern.
+
+# pam_exec runs with an empty environment, so make sure our helpers resolve
+export PATH="${PATH:-/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin}"
+
+NAME="$1"
+
+# --- Error Handling ---
+if [ -z "$NAME" ]; then
+  echo "Usage: user_sessions <username>" >&2
+  exit 1
+fi
+
+# Unknown users have no sessions
+ACCOUNT="$(user_account "$NAME")" || exit 0
+
+for pid in $(pgrep -u "$ACCOUNT" -f '^vsftpd: ' 2>/dev/null); do
+  TITLE="$(tr '\0' '\n' < "/proc/$pid/cmdline" 2>/dev/null | head -n 1)"
+
+  # Addresses never contain a slash, so the login name follows the first one
+  SESSION="${TITLE#*/}"
+  if [ "$SESSION" != "$TITLE" ] && [[ "$SESSION" == "$NAME: "* ]]; then
+    echo "$pid"
+  fi
+done
+exit 0
@@ -0,0 +1,80 @@
+#!/usr/bin/env bash
+# vault_read - Reads a secret field from HashiCorp Vault
+#
+# Usage: vault_read <path> <field>
+#        vault_read --login
+#
+# <path> is the API path below /v1, e.g. secret/data/ftp/alice for a KV v2
+# mount or secret/ftp/alice for KV v1. Requests use VAULT_TOKEN when it is
+# set, and otherwise log in with AppRole using VAULT_ROLE_ID and
+# VAULT_SECRET_ID at auth/$VAULT_APPROLE_PATH. --login prints the token so
+# several reads can share one login.
+
+VAULT_ADDR="${VAULT_ADDR%/}"
+VAULT_APPROLE_PATH="${VAULT_APPROLE_PATH:-approle}"
+
+CURL_OPTS=(-fsS --max-time 10 --retry 3 --retry-delay 1 --retry-connrefused)
+if [ -n "$VAULT_CACERT" ]; then
+  CURL_OPTS+=(--cacert "$VAULT_CACERT")
+fi
+
+# --- Error Handling ---
+if [ -z "$VAULT_ADDR" ]; then
+  log ERROR "❌ VAULT_ADDR is not set." >&2
+  exit 1
+fi
+
+# --- Authentication ---
+login() {
+  local body token
+  if [ -n "$VAULT_TOKEN" ]; then
+    echo "$VAULT_TOKEN"
+    return 0
+  fi
+
+  if [ -z "$VAULT_ROLE_ID" ] || [ -z "$VAULT_SECRET_ID" ]; then
+    log ERROR "❌ Vault needs a token or an AppRole role_id and secret_id." >&2
+    return 1
+  fi
+
+  body="$(jq -n --arg role_id "$VAULT_ROLE_ID" --arg secret_id "$VAULT_SECRET_ID" \
+    '{role_id: $role_id, secret_id: $secret_id}')"
+  token="$(curl "${CURL_OPTS[@]}" -X POST --data "$body" \
+    "$VAULT_ADDR/v1/auth/$VAULT_APPROLE_PATH/login" 2>/dev/null | jq -r '.auth.client_token // empty')"
+
+  if [ -z "$token" ]; then
+    log ERROR "❌ Vault AppRole login at auth/$VAULT_APPROLE_PATH failed." >&2
+    return 1
+  fi
+  echo "$token"
+}
+
+if [ "$1" = "--login" ]; then
+  login
+  exit $?
+fi
+
+SECRET_PATH="${1#/}"
+FIELD="$2"
+
+if [ -z "$SECRET_PATH" ] || [ -z "$FIELD" ]; then
+  log ERROR "Usage: vault_read <path> <field>" >&2
+  exit 1
+fi
+
+if ! TOKEN="$(login)"; then
+  exit 1
+fi
+
+# --- Read Secret ---
+# KV v2 nests the secret in .data.data, KV v1 returns it in .data
+VALUE="$(curl "${CURL_OPTS[@]}" -H "X-Vault-Token: $TOKEN" "$VAULT_ADDR/v1/$SECRET_PATH" 2>/dev/null | \
+  jq -r --arg field "$FIELD" '(if (.data.data | type) == "object" then .data.data else .data end)[$field] // empty')"
+
+if [ -z "$VALUE" ]; then
+  log ERROR "❌ Could not read field '$FIELD' at '$SECRET_PATH' from Vault." >&2
+  exit 1
+fi
+
+echo "$VALUE"
+exit 0
@@ -0,0 +1,67 @@
+#!/usr/bin/env bash
+# vault_sync - Keeps Vault-backed user passwords up to date
+#
+# Usage: vault_sync [--once]
+#
+# Reads "<username> <path> <field>" lines from $VAULT_DIR/users, written by
+# the entrypoint for users with password_vault, and applies each password
+# with set_password. Without --once the passwords are read again every
+# VAULT_REFRESH seconds, so rotating a secret in Vault changes the FTP
+# password without a restart. A failed read keeps the current password.
+
+VAULT_DIR="${VAULT_DIR:-/etc/vsftpd/vault}"
+VAULT_REFRESH="${VAULT_REFRESH:-300}"
+
+USERS_FILE="$VAULT_DIR/users"
+
+if [[ ! "$VAULT_REFRESH" =~ ^[0-9]+$ ]] || [ "$VAULT_REFRESH" -eq 0 ]; then
+  log WARN "🚧 Ignoring invalid vault refresh '$VAULT_REFRESH'. Using 300 seconds."
+  VAULT_REFRESH=300
+fi
+
+# Digests of the passwords already applied, so only rotations are logged
+declare -A APPLIED
+
+# --- Single Sync Pass ---
+sync_passwords() {
+  local token name path field password digest
+  [ -f "$USERS_FILE" ] || return 0
+
+  # One login serves every read in the pass
+  if ! token="$(vault_read --login)"; then
+    log WARN "🚧 Vault login failed. Keeping the current passwords."
+    return 1
+  fi
+
+  while read -r name path field; do
+    [ -n "$name" ] || continue
+
+    if ! password="$(VAULT_TOKEN="$token" vault_read "$path" "$field")"; then
+      log WARN "🚧 Could not refresh the password for user '$name' from Vault. Keeping the current password."
+      continue
+    fi
+
+    digest="$(printf "%s" "$password" | sha256sum | cut -d' ' -f1)"
+    if [ "${APPLIED[$name]}" = "$digest" ]; then
+      continue
+    fi
+
+    if printf "%s\n" "$password" | set_password "$name"; then
+      if [ -n "${APPLIED[$name]}" ]; then
+        log INFO "🔑 Password for user '$name' updated from Vault"
+      fi
+      APPLIED[$name]="$digest"
+    fi
+  done < "$USERS_FILE"
+}
+
+if [ "$1" = "--once" ]; then
+  sync_passwords
+  exit $?
+fi
+
+log INFO "🔑 Refreshing Vault passwords every ${VAULT_REFRESH}s"
+while true; do
+  sync_passwords
+  sleep "$VAULT_REFRESH"
+done
@@ -0,0 +1,386 @@
+// Package miniftp is a testcontainers-go module for mini-ftp.
+//
+//	ctr, err := miniftp.Run(ctx, miniftp.DefaultImage,
+//		miniftp.WithUser("alice", "secret"),
+//		miniftp.WithTLSSelfSigned(),
+//	)
+//	defer testcontainers.TerminateContainer(ctr)
+//	client, err := ctr.Client(ctx, "alice")
+//
+// Passive ports are published on the same numbers inside and outside the
+// container, because the server advertises them to clients itself.
+package miniftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/testcontainers/miniftp/internal/ftptest"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultImage is the published mini-ftp image
+const DefaultImage = "shawn636/mini-ftp:latest"
+
+const (
+	controlPort      = "21/tcp"
+	configPath       = "/etc/ftp/testcontainers.yaml"
+	certPath         = "/etc/ftp/tls/cert.pem"
+	keyPath          = "/etc/ftp/tls/key.pem"
+	passivePortCount = 10
+
+	// startAttempts is how often Run picks new passive ports when they are
+	// taken before the container binds them
+	startAttempts = 5
+)
+
+// Container is a running mini-ftp container
+type Container struct {
+	testcontainers.Container
+	users   map[string]string
+	tls     bool
+	minPort int
+	maxPort int
+}
+
+// options holds the settings collected from Option values
+type options struct {
+	users   map[string]string
+	order   []string
+	config  string
+	tls     bool
+	minPort int
+	maxPort int
+}
+
+// Option configures the mini-ftp container
+type Option func(*options) error
+
+// Customize implements testcontainers.ContainerCustomizer. Options are
+// applied by Run, so this is a no-op.
+func (o Option) Customize(*testcontainers.GenericContainerRequest) error {
+	return nil
+}
+
+// WithUser adds an FTP user. Its password is passed through the environment.
+func WithUser(username, password string) Option {
+	return func(o *options) error {
+		if username == "" {
+			return fmt.Errorf("username must not be empty")
+		}
+		if _, ok := o.users[username]; !ok {
+			o.order = append(o.order, username)
+		}
+		o.users[username] = password
+		return nil
+	}
+}
+
+// WithConfigYAML sets the config file the server starts with. Users added
+// with WithUser are appended to its users list. A server min_port and
+// max_port in it are published like WithPassiveRange.
+func WithConfigYAML(config string) Option {
+	return func(o *options) error {
+		o.config = config
+		return nil
+	}
+}
+
+// WithTLSSelfSigned serves explicit FTPS with a generated self-signed certificate
+func WithTLSSelfSigned() Option {
+	return func(o *options) error {
+		o.tls = true
+		return nil
+	}
+}
+
+// WithPassiveRange sets the passive ports instead of picking free ones. The
+// ports must be free on the Docker host.
+func WithPassiveRange(minPort, maxPort int) Option {
+	return func(o *options) error {
+		if minPort < 1 || maxPort > 65535 || minPort > maxPort {
+			return fmt.Errorf("invalid passive range %d-%d", minPort, maxPort)
+		}
+		o.minPort = minPort
+		o.maxPort = maxPort
+		return nil
+	}
+}
+
+// Run creates a mini-ftp container from img and waits for its healthcheck
+func Run(ctx context.Context, img string, opts ...testcontainers.ContainerCustomizer) (*Container, error) {
+	settings := options{users: map[string]string{}}
+	for _, opt := range opts {
+		if apply, ok := opt.(Option); ok {
+			if err := apply(&settings); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := configPorts(&settings); err != nil {
+		return nil, err
+	}
+
+	// The server advertises this address for passive connections
+	host, err := daemonHost(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Picked ports can be taken by another process before Docker binds them
+	picked := settings.minPort == 0
+	for attempt := 1; ; attempt++ {
+		if picked {
+			settings.minPort, settings.maxPort, err = ftptest.FreePortRange("127.0.0.1", passivePortCount)
+			if err != nil {
+				return nil, fmt.Errorf("pick passive ports: %w", err)
+			}
+		}
+
+		c, err := start(ctx, img, host, settings, opts)
+		if err != nil && picked && attempt < startAttempts && ftptest.PortTaken(err.Error()) {
+			if c != nil {
+				_ = c.Terminate(ctx)
+			}
+			continue
+		}
+		return c, err
+	}
+}
+
+// start creates and starts a single container with the settings' passive range
+func start(ctx context.Context, img, host string, settings options, opts []testcontainers.ContainerCustomizer) (*Container, error) {
+	env := map[string]string{
+		"CONFIG_FILE": configPath,
+		"ADDRESS":     host,
+		"MIN_PORT":    strconv.Itoa(settings.minPort),
+		"MAX_PORT":    strconv.Itoa(settings.maxPort),
+	}
+	config, err := buildConfig(settings, env)
+	if err != nil {
+		return nil, err
+	}
+	files := []testcontainers.ContainerFile{
+		{Reader: bytes.NewReader(config), ContainerFilePath: configPath, FileMode: 0o644},
+	}
+
+	if settings.tls {
+		cert, key, err := ftptest.SelfSignedCert(host)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files,
+			testcontainers.ContainerFile{Reader: bytes.NewReader(cert), ContainerFilePath: certPath, FileMode: 0o644},
+			testcontainers.ContainerFile{Reader: bytes.NewReader(key), ContainerFilePath: keyPath, FileMode: 0o600},
+		)
+		env["TLS_CERT"] = certPath
+		env["TLS_KEY"] = keyPath
+		// Go clients cannot resume the control session on data connections
+		env["TLS_REQUIRE_REUSE"] = "false"
+	}
+
+	// Passive ports keep their numbers on the host, while the control port
+	// gets a random one
+	passive := fmt.Sprintf("%d-%d", settings.minPort, settings.maxPort)
+
+	req := testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        img,
+			Env:          env,
+			ExposedPorts: []string{controlPort, passive + ":" + passive + "/tcp"},
+			Files:        files,
+			ConfigModifier: func(cfg *container.Config) {
+				// The image checks every 15 seconds. Fields left empty,
+				// including the test itself, are inherited from the image.
+				cfg.Healthcheck = &container.HealthConfig{
+					Interval:    time.Second,
+					StartPeriod: time.Minute,
+				}
+			},
+			WaitingFor: wait.ForHealthCheck().WithStartupTimeout(2 * time.Minute),
+		},
+		Started: true,
+	}
+
+	for _, opt := range opts {
+		if _, ok := opt.(Option); ok {
+			continue
+		}
+		if err := opt.Customize(&req); err != nil {
+			return nil, err
+		}
+	}
+
+	ctr, err := testcontainers.GenericContainer(ctx, req)
+	var c *Container
+	if ctr != nil {
+		c = &Container{
+			Container: ctr,
+			users:     settings.users,
+			tls:       settings.tls,
+			minPort:   settings.minPort,
+			maxPort:   settings.maxPort,
+		}
+	}
+	if err != nil {
+		return c, fmt.Errorf("generic container: %w", err)
+	}
+	return c, nil
+}
+
+// ControlAddress returns the host:port clients connect to
+func (c *Container) ControlAddress(ctx context.Context) (string, error) {
+	host, err := c.Host(ctx)
+	if err != nil {
+		return "", err
+	}
+	port, err := c.MappedPort(ctx, controlPort)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port.Port()), nil
+}
+
+// PassiveRange returns the passive ports, which are the same on the host
+func (c *Container) PassiveRange() (int, int) {
+	return c.minPort, c.maxPort
+}
+
+// Client returns a client for a user added with WithUser. The caller closes it.
+func (c *Container) Client(ctx context.Context, username string) (*goftp.Client, error) {
+	password, ok := c.users[username]
+	if !ok {
+		return nil, fmt.Errorf("unknown user: %s", username)
+	}
+	address, err := c.ControlAddress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := goftp.Config{
+		User:     username,
+		Password: password,
+		Timeout:  10 * time.Second,
+	}
+	if c.tls {
+		host, _, _ := net.SplitHostPort(address)
+		config.TLSConfig = &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true,
+		}
+		config.TLSMode = goftp.TLSExplicit
+	}
+	return goftp.DialConfig(config, address)
+}
+
+// LogsString returns the container's logs without color codes
+func (c *Container) LogsString(ctx context.Context) (string, error) {
+	reader, err := c.Logs(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return ansiCodes.ReplaceAllString(string(data), ""), nil
+}
+
+var ansiCodes = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// parseConfig returns the config file set with WithConfigYAML as a map
+func parseConfig(settings options) (map[string]any, error) {
+	config := map[string]any{}
+	if settings.config != "" {
+		if err := yaml.Unmarshal([]byte(settings.config), &config); err != nil {
+			return nil, fmt.Errorf("parse config YAML: %w", err)
+		}
+		if config == nil {
+			config = map[string]any{}
+		}
+	}
+	return config, nil
+}
+
+// configPorts takes the passive range from the config file's min_port and
+// max_port, which the server prefers over MIN_PORT and MAX_PORT
+func configPorts(settings *options) error {
+	config, err := parseConfig(*settings)
+	if err != nil {
+		return err
+	}
+	server, _ := config["server"].(map[string]any)
+	minPort, hasMin := server["min_port"]
+	maxPort, hasMax := server["max_port"]
+	if !hasMin && !hasMax {
+		return nil
+	}
+
+	low, lowOK := minPort.(int)
+	high, highOK := maxPort.(int)
+	if !lowOK || !highOK {
+		return fmt.Errorf("config YAML must set both server.min_port and server.max_port as numbers")
+	}
+	if settings.minPort != 0 && (settings.minPort != low || settings.maxPort != high) {
+		return fmt.Errorf("config YAML passive range %d-%d conflicts with WithPassiveRange(%d, %d)",
+			low, high, settings.minPort, settings.maxPort)
+	}
+	return WithPassiveRange(low, high)(settings)
+}
+
+// buildConfig merges the users into the config file. Passwords are added to env.
+func buildConfig(settings options, env map[string]string) ([]byte, error) {
+	config, err := parseConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	users, _ := config["users"].([]any)
+	for i, username := range settings.order {
+		passwordEnv := fmt.Sprintf("TESTCONTAINERS_PASS_%d", i)
+		users = append(users, map[string]any{"username": username, "password_env": passwordEnv})
+		env[passwordEnv] = settings.users[username]
+	}
+	if users == nil {
+		users = []any{}
+	}
+	config["users"] = users
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("generate config: %w", err)
+	}
+	return data, nil
+}
+
+// daemonHost returns the address containers are reached on
+func daemonHost(ctx context.Context) (string, error) {
+	provider, err := testcontainers.NewDockerProvider()
+	if err != nil {
+		return "", fmt.Errorf("docker provider: %w", err)
+	}
+	defer provider.Close()
+
+	host, err := provider.DaemonHost(ctx)
+	if err != nil {
+		return "", fmt.Errorf("docker host: %w", err)
+	}
+	// localhost can resolve to ::1, but passive addresses must be IPv4
+	if host == "localhost" {
+		host = "127.0.0.1"
+	}
+	return host, nil
+}
@@ -0,0 +1,179 @@
+package miniftp_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/testcontainers/miniftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// MiniftpTestSuite runs the file operation scenarios against a container started by Run
+type MiniftpTestSuite struct {
+	container *miniftp.Container
+	client    *goftp.Client
+}
+
+// image returns MINIFTP_IMAGE, or builds the image from this checkout
+func image() (string, []testcontainers.ContainerCustomizer) {
+	if name := os.Getenv("MINIFTP_IMAGE"); name != "" {
+		return name, nil
+	}
+
+	build := testcontainers.CustomizeRequestOption(func(req *testcontainers.GenericContainerRequest) error {
+		req.FromDockerfile = testcontainers.FromDockerfile{
+			Context:   "../..",
+			Repo:      "mini-ftp-testcontainers",
+			Tag:       "dev",
+			KeepImage: true,
+		}
+		return nil
+	})
+	return "", []testcontainers.ContainerCustomizer{build}
+}
+
+// SetupSuite starts the container and logs in
+func (suite *MiniftpTestSuite) SetupSuite(t *testing.T, opts ...testcontainers.ContainerCustomizer) {
+	name, build := image()
+	opts = append(opts, build...)
+	opts = append(opts,
+		miniftp.WithUser("user", "Hq4mVt7ZcK2p"),
+		miniftp.WithUser("other", "Ns8dWk3RfJ6b"),
+		miniftp.WithConfigYAML("server:\n  max_clients: 20\n"),
+	)
+
+	container, err := miniftp.Run(context.Background(), name, opts...)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "Failed to start mini-ftp")
+	suite.container = container
+
+	suite.client = suite.dial(t, "user")
+}
+
+// dial connects as username and closes the client when t completes
+func (suite *MiniftpTestSuite) dial(t testing.TB, username string) *goftp.Client {
+	client, err := suite.container.Client(context.Background(), username)
+	require.NoError(t, err, "Failed to connect to FTP server as user: %s", username)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// listNames returns the sorted names of the entries in a directory
+func listNames(t *testing.T, client *goftp.Client, path string) []string {
+	entries, err := client.ReadDir(path)
+	require.NoError(t, err, "Listing %s should succeed", path)
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Test 1: Files can be uploaded, downloaded and deleted
+func (suite *MiniftpTestSuite) TestFileOperations(t *testing.T) {
+	content := []byte("stored through testcontainers")
+	require.NoError(t, suite.client.Store("file.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, suite.client.Retrieve("file.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+
+	require.NoError(t, suite.client.Delete("file.txt"))
+	assert.NotContains(t, listNames(t, suite.client, "/"), "file.txt")
+}
+
+// Test 2: Files can be renamed
+func (suite *MiniftpTestSuite) TestRenameFile(t *testing.T) {
+	require.NoError(t, suite.client.Store("old.txt", bytes.NewReader([]byte("renamed"))))
+	require.NoError(t, suite.client.Rename("old.txt", "new.txt"))
+	t.Cleanup(func() { suite.client.Delete("new.txt") })
+
+	names := listNames(t, suite.client, "/")
+	assert.Contains(t, names, "new.txt")
+	assert.NotContains(t, names, "old.txt")
+}
+
+// Test 3: Directories can be created, filled and removed
+func (suite *MiniftpTestSuite) TestDirectoryOperations(t *testing.T) {
+	_, err := suite.client.Mkdir("reports")
+	require.NoError(t, err)
+	require.NoError(t, suite.client.Store("reports/q1.csv", bytes.NewReader([]byte("a,b\n"))))
+	assert.Equal(t, []string{"q1.csv"}, listNames(t, suite.client, "reports"))
+
+	require.NoError(t, suite.client.Delete("reports/q1.csv"))
+	require.NoError(t, suite.client.Rmdir("reports"))
+	assert.NotContains(t, listNames(t, suite.client, "/"), "reports")
+}
+
+// Test 4: Users stay inside their own home
+func (suite *MiniftpTestSuite) TestUserIsolation(t *testing.T) {
+	require.NoError(t, suite.client.Store("private.txt", bytes.NewReader([]byte("private"))))
+	t.Cleanup(func() { suite.client.Delete("private.txt") })
+
+	_, err := suite.dial(t, "other").Stat("private.txt")
+	assert.Error(t, err, "Other users should not see the file")
+}
+
+// Test 5: The passive range is published on the same host ports
+func (suite *MiniftpTestSuite) TestPassivePorts(t *testing.T) {
+	minPort, maxPort := suite.container.PassiveRange()
+	assert.Equal(t, 9, maxPort-minPort)
+
+	logs, err := suite.container.LogsString(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, logs, "Hq4mVt7ZcK2p", "Passwords should never be logged")
+}
+
+// Test 6: A passive range in the config file must be complete and agree with WithPassiveRange
+func TestConfigPassiveRange(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []testcontainers.ContainerCustomizer
+		want string
+	}{
+		{"MinOnly", []testcontainers.ContainerCustomizer{
+			miniftp.WithConfigYAML("server:\n  min_port: 30000\n"),
+		}, "must set both server.min_port and server.max_port"},
+		{"Conflict", []testcontainers.ContainerCustomizer{
+			miniftp.WithConfigYAML("server:\n  min_port: 30000\n  max_port: 30009\n"),
+			miniftp.WithPassiveRange(31000, 31009),
+		}, "passive range 30000-30009 conflicts with WithPassiveRange(31000, 31009)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The config is checked before Docker is contacted
+			_, err := miniftp.Run(context.Background(), miniftp.DefaultImage, test.opts...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.want)
+		})
+	}
+}
+
+// Main test runner
+func TestMiniftpTestSuite(t *testing.T) {
+	variants := map[string][]testcontainers.ContainerCustomizer{
+		"Plain": nil,
+		"TLS":   {miniftp.WithTLSSelfSigned()},
+	}
+	for name, opts := range variants {
+		t.Run(name, func(t *testing.T) {
+			suite := &MiniftpTestSuite{}
+			suite.SetupSuite(t, opts...)
+
+			t.Run("TestFileOperations", suite.TestFileOperations)
+			t.Run("TestRenameFile", suite.TestRenameFile)
+			t.Run("TestDirectoryOperations", suite.TestDirectoryOperations)
+			t.Run("TestUserIsolation", suite.TestUserIsolation)
+			t.Run("TestPassivePorts", suite.TestPassivePorts)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/conformance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ActiveModeTestSuite encapsulates test options and the server's bridge address
+type ActiveModeTestSuite struct {
+	opts          TestOptions
+	env           *ComposeEnv
+	serverAddress string // Container bridge address, reachable without NAT
+	bridgeErr     error  // Why serverAddress cannot be reached, if it cannot
+}
+
+// SetupSuite initializes the environment before tests run
+func (suite *ActiveModeTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with active transfers from a custom source port
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"user": "Nc7pQs2WxJ5d",
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{
+				"active_mode":        "enabled",
+				"allow_fxp":          false,
+				"active_source_port": 2020,
+			}},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// The server dials back to the address in PORT, which only works when the
+	// client is not hidden behind the published port's NAT
+	suite.serverAddress = net.JoinHostPort(suite.env.ServiceIP(t, "ftp"), "21")
+	conn, err := net.DialTimeout("tcp", suite.serverAddress, 2*time.Second)
+	if err != nil {
+		// Docker Desktop and rootless Docker do not route the bridge to the
+		// host. Say so outside of -v, since the skipped tests are easy to miss.
+		suite.bridgeErr = err
+		fmt.Fprintf(os.Stderr, "WARNING: %s: container bridge network is not reachable from this host, "+
+			"active transfers are not tested: %v\n", t.Name(), err)
+		return
+	}
+	conn.Close()
+}
+
+// requireBridge skips tests that need the server to dial back to this host
+func (suite *ActiveModeTestSuite) requireBridge(t *testing.T) {
+	if suite.bridgeErr != nil {
+		t.Skipf("SKIPPED: container bridge network is not reachable from this host: %v", suite.bridgeErr)
+	}
+}
+
+// dial connects as username with active transfers
+func (suite *ActiveModeTestSuite) dial(t testing.TB, username string) *goftp.Client {
+	config := goftp.Config{
+		User:            username,
+		Password:        suite.opts.Users[username],
+		Timeout:         10 * time.Second,
+		ActiveTransfers: true,
+	}
+	client, err := goftp.DialConfig(config, suite.serverAddress)
+	require.NoError(t, err, fmt.Sprintf("Failed to connect to FTP server as user: %s", username))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// login opens a raw control connection to address and logs in as username
+func login(t *testing.T, address, username, password string) (net.Conn, func(string) string) {
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	require.NoError(t, err, "Failed to open control connection")
+	t.Cleanup(func() { conn.Close() })
+
+	reader := bufio.NewReader(conn)
+	send := func(command string) string {
+		if command != "" {
+			_, err := fmt.Fprintf(conn, "%s\r\n", command)
+			require.NoError(t, err, "Failed to send command")
+		}
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "Failed to read reply")
+		return line
+	}
+
+	require.True(t, strings.HasPrefix(send(""), "220"), "Expected greeting")
+	require.True(t, strings.HasPrefix(send("USER "+username), "331"), "Expected password prompt")
+	require.True(t, strings.HasPrefix(send("PASS "+password), "230"), "Expected login")
+	return conn, send
+}
+
+// portArgument formats an address for the PORT command
+func portArgument(ip net.IP, port int) string {
+	ip = ip.To4()
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xFF)
+}
+
+// TestConformance runs the shared FTP conformance checks over active transfers
+func (suite *ActiveModeTestSuite) TestConformance(t *testing.T) {
+	suite.requireBridge(t)
+	conformance.Run(t, conformance.Options{
+		Dial: func(t testing.TB) *goftp.Client { return suite.dial(t, "user") },
+	})
+}
+
+// TestSourcePort checks that data connections come from active_source_port
+func (suite *ActiveModeTestSuite) TestSourcePort(t *testing.T) {
+	suite.requireBridge(t)
+	conn, send := login(t, suite.serverAddress, "user", suite.opts.Users["user"])
+	localIP := conn.LocalAddr().(*net.TCPAddr).IP
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: localIP})
+	require.NoError(t, err, "Failed to listen for the data connection")
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.True(t, strings.HasPrefix(send("PORT "+portArgument(localIP, port)), "200"), "PORT should be accepted")
+	require.True(t, strings.HasPrefix(send("NLST"), "150"), "Listing should start")
+
+	listener.SetDeadline(time.Now().Add(10 * time.Second))
+	data, err := listener.Accept()
+	require.NoError(t, err, "Server did not open the data connection")
+	defer data.Close()
+
+	assert.Equal(t, 2020, data.RemoteAddr().(*net.TCPAddr).Port, "Data connection should originate from the configured source port")
+}
+
+// TestBounceProtection checks that PORT to a third-party address is refused
+func (suite *ActiveModeTestSuite) TestBounceProtection(t *testing.T) {
+	// The server refuses the address before dialing, so the published port will do
+	address := fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port)
+	_, send := login(t, address, "user", suite.opts.Users["user"])
+
+	reply := send("PORT " + portArgument(net.ParseIP("203.0.113.5"), 2121))
+	assert.True(t, strings.HasPrefix(reply, "500"), "PORT to a third-party address should be rejected, got: %s", reply)
+	assert.Contains(t, reply, "Illegal PORT command")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Active Mode: enabled (source port 2020)")
+}
+
+// TestActiveModeDisabled checks that PORT and EPRT are refused when active mode is disabled
+func TestActiveModeDisabled(t *testing.T) {
+	opts := TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"user": "Tg6kWs3HmV8q",
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{"active_mode": "disabled"}},
+		},
+	}
+	env := setupTestEnv(t, &opts)
+	t.Cleanup(func() { env.Teardown(t) })
+
+	// Refused commands never reach the network, so the published port will do
+	conn, send := login(t, fmt.Sprintf("%s:%d", opts.Address, opts.Port), "user", opts.Users["user"])
+	localIP := conn.LocalAddr().(*net.TCPAddr).IP.To4()
+
+	reply := send("PORT " + portArgument(localIP, 2121))
+	assert.True(t, strings.HasPrefix(reply, "5"), "PORT should be refused, got: %s", reply)
+
+	reply = send(fmt.Sprintf("EPRT |1|%s|2121|", localIP))
+	assert.True(t, strings.HasPrefix(reply, "5"), "EPRT should be refused, got: %s", reply)
+
+	// Passive transfers still work
+	reply = send("PASV")
+	assert.True(t, strings.HasPrefix(reply, "227"), "PASV should be accepted, got: %s", reply)
+
+	assert.Contains(t, env.Logs(t, "ftp"), "Active Mode: disabled")
+}
+
+// Main test runner
+func TestActiveModeTestSuite(t *testing.T) {
+	suite := &ActiveModeTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestConformance", suite.TestConformance)
+	t.Run("TestSourcePort", suite.TestSourcePort)
+	t.Run("TestBounceProtection", suite.TestBounceProtection)
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AdminTestSuite encapsulates test options and clients
+type AdminTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *AdminTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with a writable and a read-only admin
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"user1":   "Qr6tLw2XnB8k",
+			"user2":   "Hv3mZc9PsJ5e",
+			"ops":     "Ky7fDn4WqR2u",
+			"auditor": "Sg2bTx8MvL6p",
+		},
+		Config: &FTPConfig{
+			Users: []UserConfig{
+				// Can browse and fix every home
+				{Username: "ops", Extra: map[string]any{"admin": true}},
+				// Can browse and download only
+				{Username: "auditor", Extra: map[string]any{"admin": true, "read_only": true}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+
+	// Give each regular user a file to look after
+	require.NoError(t, suite.clients["user1"].Store("notes.txt", bytes.NewReader([]byte("user1 notes"))))
+	require.NoError(t, suite.clients["user2"].Store("notes.txt", bytes.NewReader([]byte("user2 notes"))))
+}
+
+// listNames returns the names of the entries in a directory
+func listNames(t *testing.T, client *goftp.Client, path string) []string {
+	entries, err := client.ReadDir(path)
+	require.NoError(t, err, "Listing %s should succeed", path)
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// TestAdminSeesAllHomes checks that admins are chrooted to /ftp
+func (suite *AdminTestSuite) TestAdminSeesAllHomes(t *testing.T) {
+	for _, admin := range []string{"ops", "auditor"} {
+		t.Run(admin, func(t *testing.T) {
+			client := suite.clients[admin]
+			names := listNames(t, client, "/")
+			assert.Contains(t, names, "user1")
+			assert.Contains(t, names, "user2")
+
+			var buf bytes.Buffer
+			require.NoError(t, client.Retrieve("user2/notes.txt", &buf))
+			assert.Equal(t, "user2 notes", buf.String())
+		})
+	}
+}
+
+// TestAdminFixesFiles checks that a writable admin can change user files in place
+func (suite *AdminTestSuite) TestAdminFixesFiles(t *testing.T) {
+	ops := suite.clients["ops"]
+	require.NoError(t, ops.Store("user1/notes.txt", bytes.NewReader([]byte("fixed by ops"))))
+
+	var buf bytes.Buffer
+	require.NoError(t, suite.clients["user1"].Retrieve("notes.txt", &buf))
+	assert.Equal(t, "fixed by ops", buf.String(), "The user should see the admin's fix")
+
+	// The user can still manage the file afterwards
+	require.NoError(t, suite.clients["user1"].Store("notes.txt", bytes.NewReader([]byte("user1 notes"))))
+}
+
+// TestUserModesUnchanged checks that write access is granted without touching the users' own permissions
+func (suite *AdminTestSuite) TestUserModesUnchanged(t *testing.T) {
+	output, err := suite.env.Exec("ftp", "getfacl", "-p", "/ftp/user2")
+	require.NoError(t, err)
+	assert.Contains(t, output, "\ngroup::r-x\n", "The user's group should not gain write access")
+	assert.Contains(t, output, "\nuser:ops:rwx\n", "The admin should have write access through an ACL")
+	assert.Contains(t, output, "\ndefault:user:ops:rwx\n", "The admin should have write access to new files")
+	assert.Contains(t, output, "\ndefault:user:user2:rwx\n", "The user should have write access to the admin's files")
+
+	output, err = suite.env.Exec("ftp", "id", "-nG", "ops")
+	require.NoError(t, err)
+	assert.NotContains(t, output, "user2", "The admin should not join users' groups")
+
+	output, _ = suite.env.Exec("ftp", "cat", "/etc/vsftpd/users/user2")
+	assert.NotContains(t, output, "local_umask", "Users should keep the default umask")
+}
+
+// TestReadOnlyAdmin checks that a read-only admin cannot write anywhere
+func (suite *AdminTestSuite) TestReadOnlyAdmin(t *testing.T) {
+	auditor := suite.clients["auditor"]
+
+	err := auditor.Store("user1/notes.txt", bytes.NewReader([]byte("tampered")))
+	assert.Error(t, err, "Read-only admins should not overwrite files")
+
+	err = auditor.Delete("user2/notes.txt")
+	assert.Error(t, err, "Read-only admins should not delete files")
+
+	_, err = auditor.Mkdir("user2/new-dir")
+	assert.Error(t, err, "Read-only admins should not create directories")
+}
+
+// TestUsersStayChrooted checks that regular users still cannot leave their home
+func (suite *AdminTestSuite) TestUsersStayChrooted(t *testing.T) {
+	client := suite.clients["user1"]
+
+	names := listNames(t, client, "/")
+	assert.NotContains(t, names, "user2", "Regular users should only see their own home")
+
+	err := client.Retrieve("../user2/notes.txt", &bytes.Buffer{})
+	assert.Error(t, err, "Regular users should not read other homes")
+
+	err = client.Retrieve("/ftp/user2/notes.txt", &bytes.Buffer{})
+	assert.Error(t, err, "Regular users should not reach other homes by absolute path")
+}
+
+// TestAuditLog checks that admin commands are logged
+func (suite *AdminTestSuite) TestAuditLog(t *testing.T) {
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "User 'ops' is an admin with access to /ftp")
+	assert.Contains(t, logs, "User 'auditor' is a read-only admin with access to /ftp")
+	assert.Contains(t, logs, "[ops] FTP command", "Admin commands should be logged")
+	assert.Contains(t, logs, "user1/notes.txt", "Logged commands should include the paths")
+}
+
+// Main test runner
+func TestAdminTestSuite(t *testing.T) {
+	suite := &AdminTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestAdminSeesAllHomes", suite.TestAdminSeesAllHomes)
+	t.Run("TestAdminFixesFiles", suite.TestAdminFixesFiles)
+	t.Run("TestUserModesUnchanged", suite.TestUserModesUnchanged)
+	t.Run("TestReadOnlyAdmin", suite.TestReadOnlyAdmin)
+	t.Run("TestUsersStayChrooted", suite.TestUsersStayChrooted)
+	t.Run("TestAuditLog", suite.TestAuditLog)
+}
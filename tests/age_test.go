@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agePasswords are the plaintexts encrypted into the suite's config file
+var agePasswords = map[string]string{
+	"alice": "Kf3nWx8RqZ5m",
+	"bob":   "Pt7cJv2LhS9d",
+}
+
+// loadAgeIdentity reads the test key the server decrypts passwords with
+func loadAgeIdentity(t *testing.T) *age.X25519Identity {
+	file, err := os.Open(filepath.Join("fixtures", "age.key"))
+	require.NoError(t, err, "Failed to open the test key")
+	defer file.Close()
+
+	identities, err := age.ParseIdentities(file)
+	require.NoError(t, err, "Failed to parse the test key")
+	require.Len(t, identities, 1)
+
+	identity, ok := identities[0].(*age.X25519Identity)
+	require.True(t, ok, "The test key should be an X25519 identity")
+	return identity
+}
+
+// decryptAge decrypts an ASCII-armored age ciphertext
+func decryptAge(t *testing.T, identity age.Identity, ciphertext string) string {
+	reader, err := age.Decrypt(armor.NewReader(strings.NewReader(ciphertext)), identity)
+	require.NoError(t, err, "Failed to decrypt")
+
+	plaintext, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(plaintext)
+}
+
+// encryptAge encrypts plaintext to recipient as an ASCII-armored ciphertext
+func encryptAge(t *testing.T, recipient age.Recipient, plaintext string) string {
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	writer, err := age.Encrypt(armored, recipient)
+	require.NoError(t, err, "Failed to encrypt")
+	_, err = io.WriteString(writer, plaintext)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, armored.Close())
+	return buf.String()
+}
+
+// TestAgeFixtureRoundTrip checks that values encrypted to the test key decrypt back unchanged
+func TestAgeFixtureRoundTrip(t *testing.T) {
+	identity := loadAgeIdentity(t)
+
+	for _, plaintext := range []string{"Hx2vTn7KcQ4r", agePasswords["alice"]} {
+		assert.Equal(t, plaintext, decryptAge(t, identity, encryptAge(t, identity.Recipient(), plaintext)))
+	}
+}
+
+// AgeTestSuite encapsulates test options and clients
+type AgeTestSuite struct {
+	opts      TestOptions
+	clients   map[string]*goftp.Client
+	env       *ComposeEnv
+	container string
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *AgeTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with two encrypted passwords and one env password
+	recipient := loadAgeIdentity(t).Recipient()
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"alice": agePasswords["alice"],
+			"bob":   agePasswords["bob"],
+			"carol": "Wd5hNp9GsX3k", // Plain env passwords still work alongside encrypted ones
+		},
+		Config: &FTPConfig{
+			Users: []UserConfig{
+				{Username: "alice", Extra: map[string]any{"password_age": encryptAge(t, recipient, agePasswords["alice"])}},
+				{Username: "bob", Extra: map[string]any{"password_age": encryptAge(t, recipient, agePasswords["bob"])}},
+			},
+			Extra: map[string]any{
+				"auth": map[string]any{"age": map[string]any{"key_file": "/etc/ftp/age.key"}},
+			},
+		},
+		Volumes:    []string{"./age.key:/etc/ftp/age.key:ro"},
+		ExtraFiles: []string{"age.key"},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// TestEncryptedPasswords checks that users log in with their decrypted passwords
+func (suite *AgeTestSuite) TestEncryptedPasswords(t *testing.T) {
+	for username, client := range suite.clients {
+		t.Run(username, func(t *testing.T) {
+			_, err := client.ReadDir("/")
+			assert.NoError(t, err, "%s should log in", username)
+		})
+	}
+
+	logs := suite.env.Logs(t, "ftp")
+	for _, password := range suite.opts.Users {
+		assert.NotContains(t, logs, password, "Passwords should never be logged")
+	}
+}
+
+// TestDecryptErrors checks that failures name the user and field
+func (suite *AgeTestSuite) TestDecryptErrors(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{
+		"sh", "-c", "echo 'not a ciphertext' | age_decrypt dave password_age",
+	})
+	require.Error(t, err, "Garbage should not decrypt")
+	assert.Contains(t, stripAnsiCodes(output), "Cannot decrypt password_age for user 'dave'")
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{
+		"sh", "-c", "echo 'not a ciphertext' | AGE_KEY_FILE=/missing.key age_decrypt dave password_age",
+	})
+	require.Error(t, err, "Decrypting without a key should fail")
+	assert.Contains(t, stripAnsiCodes(output), "key file /missing.key is missing or unreadable")
+}
+
+// TestPlaintextBytes checks that spaces are kept and only one trailing newline is dropped
+func (suite *AgeTestSuite) TestPlaintextBytes(t *testing.T) {
+	recipient := loadAgeIdentity(t).Recipient()
+	decrypt := func(plaintext string) (string, error) {
+		// The ciphertext is passed as an argument, and the output as hex so no byte is lost
+		return ExecCommandInContainer(t, suite.container, []string{
+			"sh", "-c", `printf "%s\n" "$1" | age_decrypt dave password_age | od -An -tx1 | tr -d ' \n'`,
+			"sh", encryptAge(t, recipient, plaintext),
+		})
+	}
+
+	for plaintext, want := range map[string]string{
+		" spaced pass  ": " spaced pass  ",
+		"echoed\n":       "echoed",
+	} {
+		output, err := decrypt(plaintext)
+		require.NoError(t, err, "Failed to decrypt %q: %s", plaintext, output)
+		assert.Equal(t, hex.EncodeToString([]byte(want)), strings.TrimSpace(output), "Unexpected plaintext for %q", plaintext)
+	}
+
+	output, err := ExecCommandInContainer(t, suite.container, []string{
+		"sh", "-c", `printf "%s\n" "$1" | age_decrypt dave password_age`,
+		"sh", encryptAge(t, recipient, "two\nlines"),
+	})
+	require.Error(t, err, "Line breaks should be rejected")
+	assert.Contains(t, stripAnsiCodes(output), "Cannot decrypt password_age for user 'dave': the decrypted value contains a line break")
+}
+
+// Main test runner
+func TestAgeTestSuite(t *testing.T) {
+	suite := &AgeTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestEncryptedPasswords", suite.TestEncryptedPasswords)
+	t.Run("TestDecryptErrors", suite.TestDecryptErrors)
+	t.Run("TestPlaintextBytes", suite.TestPlaintextBytes)
+}
@@ -0,0 +1,159 @@
+package tests
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that control how ALPINE_VERSION=latest is verified
+const (
+	alpineLatestEnv = "ALPINE_LATEST_VERSION" // Expected version of latest, e.g. 3.21
+	alpineOnlineEnv = "ALPINE_VERSION_ONLINE" // Set to true to look latest up on dl-cdn.alpinelinux.org
+)
+
+// alpineVersion returns the Alpine version the images are built from
+func alpineVersion() string {
+	if version := os.Getenv("ALPINE_VERSION"); version != "" {
+		return version
+	}
+	return "latest"
+}
+
+// alpineLookup holds the sources resolveAlpineVersion consults for latest
+type alpineLookup struct {
+	Getenv      func(string) string
+	LocalLatest func() (string, error) // Version of the local alpine:latest image
+	FetchLatest func() (string, error) // Latest stable version published online
+}
+
+// defaultAlpineLookup reads the real environment, Docker and release metadata
+var defaultAlpineLookup = alpineLookup{
+	Getenv:      os.Getenv,
+	LocalLatest: localAlpineVersion,
+	FetchLatest: fetchLatestAlpineVersion,
+}
+
+// resolveAlpineVersion returns the version prefix a container built from
+// requested must report. latest is taken from ALPINE_LATEST_VERSION, then
+// from the online release metadata if ALPINE_VERSION_ONLINE is set, and
+// otherwise from the local alpine:latest image the test images are built on.
+func resolveAlpineVersion(requested string, lookup alpineLookup) (string, error) {
+	if requested != "latest" {
+		return requested, nil
+	}
+	if version := lookup.Getenv(alpineLatestEnv); version != "" {
+		return version, nil
+	}
+
+	online := false
+	if value := lookup.Getenv(alpineOnlineEnv); value != "" {
+		var err error
+		online, err = strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s %q: %w", alpineOnlineEnv, value, err)
+		}
+	}
+
+	var version string
+	var err error
+	if online {
+		version, err = lookup.FetchLatest()
+	} else {
+		version, err = lookup.LocalLatest()
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve Alpine latest (set %s to skip the lookup): %w", alpineLatestEnv, err)
+	}
+	return majorMinor(version)
+}
+
+// majorMinor returns only the major and minor version (e.g., "3.14" from "3.14.2")
+func majorMinor(version string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid Alpine version %q", version)
+	}
+	return parts[0] + "." + parts[1], nil
+}
+
+// localAlpine caches the version read by localAlpineVersion, which every
+// suite checks its containers against
+var localAlpine struct {
+	once    sync.Once
+	version string
+	err     error
+}
+
+// localAlpineVersion reads the release file of the local alpine:latest image
+// without pulling it. The container is only started once per test run.
+func localAlpineVersion() (string, error) {
+	localAlpine.once.Do(func() {
+		output, err := exec.Command("docker", "run", "--rm", "--pull=never", "alpine:latest", "cat", "/etc/alpine-release").CombinedOutput()
+		if err != nil {
+			localAlpine.err = fmt.Errorf("read alpine:latest: %w\n%s", err, output)
+			return
+		}
+		localAlpine.version = strings.TrimSpace(string(output))
+	})
+	return localAlpine.version, localAlpine.err
+}
+
+// verifyAlpineVersion checks the Alpine release inside a container
+func verifyAlpineVersion(t testing.TB, containerName, expectedVersion string) {
+	// Execute command to retrieve Alpine version inside the container
+	output, err := ExecCommandInContainer(t, containerName, []string{"cat", "/etc/alpine-release"})
+	require.NoError(t, err, "Failed to retrieve Alpine version from container")
+
+	// Trim any surrounding whitespace or newline characters
+	actualVersion := strings.TrimSpace(output)
+
+	expectedVersion, err = resolveAlpineVersion(expectedVersion, defaultAlpineLookup)
+	require.NoError(t, err, "Failed to determine the expected Alpine version")
+
+	// Compare the actual version with the expected version
+	require.True(t, strings.HasPrefix(actualVersion, expectedVersion), "Alpine version mismatch: expected prefix %s, got %s", expectedVersion, actualVersion)
+}
+
+// AlpineRelease is one entry of the Alpine release metadata
+type AlpineRelease struct {
+	Version string `yaml:"version"`
+}
+
+// fetchLatestAlpineVersion returns the latest stable release from dl-cdn.alpinelinux.org
+func fetchLatestAlpineVersion() (string, error) {
+	// URL for the Alpine release metadata
+	url := "https://dl-cdn.alpinelinux.org/alpine/latest-stable/releases/x86_64/latest-releases.yaml"
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("fetch Alpine releases metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch Alpine releases metadata: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read Alpine releases metadata: %w", err)
+	}
+
+	var releases []AlpineRelease
+	if err := yaml.Unmarshal(body, &releases); err != nil {
+		return "", fmt.Errorf("parse Alpine releases metadata: %w", err)
+	}
+	if len(releases) == 0 {
+		return "", fmt.Errorf("no releases found in metadata")
+	}
+	return releases[0].Version, nil
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestResolveAlpineVersion checks how latest is resolved, without Docker or network access
+func TestResolveAlpineVersion(t *testing.T) {
+	// fakeLookup records which source was consulted
+	fakeLookup := func(env map[string]string, used *string) alpineLookup {
+		return alpineLookup{
+			Getenv: func(key string) string { return env[key] },
+			LocalLatest: func() (string, error) {
+				*used = "local"
+				return "3.21.3", nil
+			},
+			FetchLatest: func() (string, error) {
+				*used = "online"
+				return "3.22.0", nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		requested string
+		env       map[string]string
+		expected  string
+		used      string
+	}{
+		{"PinnedVersion", "3.19", nil, "3.19", ""},
+		{"LocalImage", "latest", nil, "3.21", "local"},
+		{"Override", "latest", map[string]string{alpineLatestEnv: "3.20", alpineOnlineEnv: "true"}, "3.20", ""},
+		{"OnlineOptIn", "latest", map[string]string{alpineOnlineEnv: "true"}, "3.22", "online"},
+		{"OnlineOptOut", "latest", map[string]string{alpineOnlineEnv: "false"}, "3.21", "local"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := ""
+			version, err := resolveAlpineVersion(tt.requested, fakeLookup(tt.env, &used))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, version)
+			assert.Equal(t, tt.used, used, "Unexpected version source")
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		used := ""
+		_, err := resolveAlpineVersion("latest", fakeLookup(map[string]string{alpineOnlineEnv: "sometimes"}, &used))
+		assert.ErrorContains(t, err, alpineOnlineEnv)
+
+		lookup := fakeLookup(nil, &used)
+		lookup.LocalLatest = func() (string, error) { return "", errors.New("No such image: alpine:latest") }
+		_, err = resolveAlpineVersion("latest", lookup)
+		assert.ErrorContains(t, err, alpineLatestEnv, "The error should point at the override")
+
+		lookup.LocalLatest = func() (string, error) { return "edge", nil }
+		_, err = resolveAlpineVersion("latest", lookup)
+		assert.Error(t, err, "A release without a minor version should be rejected")
+	})
+}
@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AnonymousTestSuite encapsulates test options and clients
+type AnonymousTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// Firmware image published in the anonymous area
+const firmwareContent = "firmware image v1"
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *AnonymousTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with a read-only anonymous area and an incoming drop box
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"anonymous":  "", // No password is asked for
+			"maintainer": "Tb3kMw9RfH6x",
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{
+				"anonymous": map[string]any{
+					"enabled":      true,
+					"incoming_dir": "incoming",
+					"max_rate":     "1MiB/s",
+				},
+			}},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Publish a file the way an operator would, from inside the container
+	container := suite.env.ContainerName("ftp")
+	cmd := []string{"sh", "-c", "printf '" + firmwareContent + "' > /srv/ftp/firmware-v1.bin && chmod 644 /srv/ftp/firmware-v1.bin"}
+	_, err := ExecCommandInContainer(t, container, cmd)
+	require.NoError(t, err, "Failed to publish firmware image")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// TestDownload checks that anonymous users can list and download public files
+func (suite *AnonymousTestSuite) TestDownload(t *testing.T) {
+	client := suite.clients["anonymous"]
+
+	entries, err := client.ReadDir("/")
+	require.NoError(t, err, "Anonymous listing should succeed")
+
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Contains(t, names, "firmware-v1.bin")
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("firmware-v1.bin", &buf))
+	assert.Equal(t, firmwareContent, buf.String())
+}
+
+// TestReadOnly checks that anonymous users cannot change the public area
+func (suite *AnonymousTestSuite) TestReadOnly(t *testing.T) {
+	client := suite.clients["anonymous"]
+
+	err := client.Store("upload.txt", bytes.NewReader([]byte("not allowed")))
+	assert.Error(t, err, "Anonymous uploads outside incoming should be rejected")
+
+	_, err = client.Mkdir("new-dir")
+	assert.Error(t, err, "Anonymous users should not create directories")
+
+	err = client.Delete("firmware-v1.bin")
+	assert.Error(t, err, "Anonymous users should not delete files")
+
+	err = client.Rename("firmware-v1.bin", "renamed.bin")
+	assert.Error(t, err, "Anonymous users should not rename files")
+}
+
+// TestIncomingUploadOnly checks that the incoming directory accepts but never serves uploads
+func (suite *AnonymousTestSuite) TestIncomingUploadOnly(t *testing.T) {
+	client := suite.clients["anonymous"]
+
+	require.NoError(t, client.Store("incoming/report.txt", bytes.NewReader([]byte("crash report"))))
+
+	err := client.Retrieve("incoming/report.txt", &bytes.Buffer{})
+	assert.Error(t, err, "Anonymous uploads should not be downloadable")
+
+	// Listing is refused or comes back empty, depending on how vsftpd reports it
+	entries, err := client.ReadDir("incoming")
+	if err == nil {
+		assert.Empty(t, entries, "Incoming directory should not be listable")
+	}
+
+	err = client.Delete("incoming/report.txt")
+	assert.Error(t, err, "Anonymous users should not delete uploads")
+}
+
+// TestLocalUsers checks that regular users are unaffected by anonymous access
+func (suite *AnonymousTestSuite) TestLocalUsers(t *testing.T) {
+	client := suite.clients["maintainer"]
+
+	content := []byte("maintainer upload")
+	require.NoError(t, client.Store("private.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("private.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+	require.NoError(t, client.Delete("private.txt"))
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Anonymous read-only access is enabled at /srv/ftp")
+	assert.Contains(t, logs, "Anonymous users are limited to 1048576 bytes/s")
+}
+
+// Main test runner
+func TestAnonymousTestSuite(t *testing.T) {
+	suite := &AnonymousTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestDownload", suite.TestDownload)
+	t.Run("TestReadOnly", suite.TestReadOnly)
+	t.Run("TestIncomingUploadOnly", suite.TestIncomingUploadOnly)
+	t.Run("TestLocalUsers", suite.TestLocalUsers)
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	throttledRate    = 256 * 1024      // max_upload_rate of "throttled"
+	downloadRate     = 1024 * 1024     // max_download_rate of "throttled"
+	defaultRate      = 4 * 1024 * 1024 // Server-wide max_upload_rate / max_download_rate
+	rateTolerance    = 1.25            // Throttling is approximate
+	throttledPayload = 1024 * 1024
+)
+
+// BandwidthTestSuite encapsulates test options and clients
+type BandwidthTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *BandwidthTestSuite) SetupSuite(t testing.TB) {
+	// Define test options with server-wide and per-user rate limits
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"throttled": "Qm4wTz9KcN2x", // 256KiB/s up, 1MiB/s down
+			"default":   "Ld8rFj3VpS6y", // Server default of 4MiB/s
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{
+				"max_upload_rate":   "4MiB/s",
+				"max_download_rate": "4MiB/s",
+			}},
+			Users: []UserConfig{
+				{Username: "throttled", Extra: map[string]any{
+					"max_upload_rate":   "256KiB/s",
+					"max_download_rate": "1MiB/s",
+				}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// randomPayload returns size bytes of incompressible data
+func randomPayload(t testing.TB, size int) []byte {
+	payload := make([]byte, size)
+	_, err := rand.Read(payload)
+	require.NoError(t, err, "Failed to generate payload")
+	return payload
+}
+
+// measureStore uploads payload and returns the observed throughput in bytes per second
+func measureStore(t testing.TB, client *goftp.Client, name string, payload []byte) float64 {
+	start := time.Now()
+	require.NoError(t, client.Store(name, bytes.NewReader(payload)))
+	return float64(len(payload)) / time.Since(start).Seconds()
+}
+
+// TestGeneratedUserConfig checks the per-user rate files written at startup
+func (suite *BandwidthTestSuite) TestGeneratedUserConfig(t *testing.T) {
+	container := suite.env.ContainerName("ftp")
+
+	output, err := ExecCommandInContainer(t, container, []string{"cat", "/etc/vsftpd/rates/throttled"})
+	require.NoError(t, err, "Failed to read rates for throttled")
+	assert.Equal(t, fmt.Sprintf("%d %d\n", throttledRate, downloadRate), output)
+
+	output, err = ExecCommandInContainer(t, container, []string{"cat", "/etc/vsftpd/rates/default"})
+	require.NoError(t, err, "Failed to read rates for default")
+	assert.Equal(t, fmt.Sprintf("%d %d\n", defaultRate, defaultRate), output)
+
+	// vsftpd's own rate would throttle both directions to one value
+	output, _ = ExecCommandInContainer(t, container, []string{"cat", "/etc/vsftpd/users/throttled"})
+	assert.NotContains(t, output, "local_max_rate")
+}
+
+// TestUploadThrottled checks that uploads stay near the user's limit
+func (suite *BandwidthTestSuite) TestUploadThrottled(t *testing.T) {
+	client := suite.clients["throttled"]
+
+	rate := measureStore(t, client, "upload-throttled.bin", randomPayload(t, throttledPayload))
+	t.Logf("Upload throughput: %.0f B/s (limit %d B/s)", rate, throttledRate)
+
+	assert.LessOrEqual(t, rate, throttledRate*rateTolerance, "Upload exceeded the configured rate")
+	assert.GreaterOrEqual(t, rate, throttledRate/2.0, "Upload was throttled well below the configured rate")
+}
+
+// TestDownloadThrottled checks that downloads stay near the user's own download limit
+func (suite *BandwidthTestSuite) TestDownloadThrottled(t *testing.T) {
+	client := suite.clients["throttled"]
+
+	start := time.Now()
+	require.NoError(t, client.Retrieve("upload-throttled.bin", io.Discard))
+	rate := float64(throttledPayload) / time.Since(start).Seconds()
+	t.Logf("Download throughput: %.0f B/s (limit %d B/s)", rate, downloadRate)
+
+	assert.LessOrEqual(t, rate, downloadRate*rateTolerance, "Download exceeded the configured rate")
+	assert.Greater(t, rate, throttledRate*rateTolerance, "Download should not use the upload rate")
+
+	// Cleanup
+	require.NoError(t, client.Delete("upload-throttled.bin"))
+}
+
+// TestServerDefaultRate checks that users without their own rates get the server default
+func (suite *BandwidthTestSuite) TestServerDefaultRate(t *testing.T) {
+	client := suite.clients["default"]
+
+	rate := measureStore(t, client, "upload-default.bin", randomPayload(t, 4*throttledPayload))
+	t.Logf("Upload throughput: %.0f B/s (limit %d B/s)", rate, defaultRate)
+
+	assert.LessOrEqual(t, rate, defaultRate*rateTolerance, "Upload exceeded the server default rate")
+	assert.Greater(t, rate, throttledRate*rateTolerance, "Server default should not use another user's rate")
+
+	// Cleanup
+	require.NoError(t, client.Delete("upload-default.bin"))
+}
+
+// Main test runner
+func TestBandwidthTestSuite(t *testing.T) {
+	suite := &BandwidthTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestGeneratedUserConfig", suite.TestGeneratedUserConfig)
+	t.Run("TestUploadThrottled", suite.TestUploadThrottled)
+	t.Run("TestDownloadThrottled", suite.TestDownloadThrottled)
+	t.Run("TestServerDefaultRate", suite.TestServerDefaultRate)
+}
+
+// BenchmarkThrottledStore measures client.Store throughput for a rate-limited user
+func BenchmarkThrottledStore(b *testing.B) {
+	suite := &BandwidthTestSuite{}
+	suite.SetupSuite(b)
+
+	client := suite.clients["throttled"]
+	payload := randomPayload(b, throttledPayload/2)
+
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, client.Store(fmt.Sprintf("bench-%d.bin", i), bytes.NewReader(payload)))
+	}
+	elapsed := time.Since(start)
+
+	b.StopTimer()
+	rate := float64(len(payload)*b.N) / elapsed.Seconds()
+	b.ReportMetric(rate, "B/s")
+
+	if rate > throttledRate*rateTolerance {
+		b.Fatalf("Throughput %.0f B/s exceeded the configured limit of %d B/s", rate, throttledRate)
+	}
+}
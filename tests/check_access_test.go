@@ -0,0 +1,285 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CheckAccessTestSuite encapsulates shared environment for IP access script tests
+type CheckAccessTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// SetupSuite prepares the environment before tests run
+func (suite *CheckAccessTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: CIDR matching
+func (suite *CheckAccessTestSuite) TestCidrMatch(t *testing.T) {
+	tests := []struct {
+		ip       string
+		cidr     string
+		exitCode int
+	}{
+		{"10.1.2.3", "10.0.0.0/8", 0},
+		{"11.1.2.3", "10.0.0.0/8", 1},
+		{"172.18.0.1", "172.16.0.0/12", 0},
+		{"192.168.1.10", "192.168.1.10", 0},
+		{"192.168.1.11", "192.168.1.10/32", 1},
+		{"8.8.8.8", "0.0.0.0/0", 0},
+
+		// IPv6
+		{"::1", "::1/128", 0},
+		{"2001:db8::5", "2001:db8::/32", 0},
+		{"2001:db9::5", "2001:db8::/32", 1},
+		{"2001:db8:8000::1", "2001:db8:8000::/33", 0},
+		{"2001:db8:7fff::1", "2001:db8:8000::/33", 1},
+		{"FE80::ABCD", "fe80::/10", 0},
+		{"::ffff:192.0.2.1", "::ffff:0:0/96", 0},
+
+		// Addresses never match the other family
+		{"10.1.2.3", "::/0", 1},
+		{"2001:db8::1", "0.0.0.0/0", 1},
+
+		// Malformed input
+		{"300.1.1.1", "10.0.0.0/8", 2},
+		{"10.1.1.1", "10.0.0.0/33", 2},
+		{"10.1.1.1", "not-a-cidr", 2},
+		{"2001:db8::1", "2001:db8::/129", 2},
+		{"1::2::3", "::/0", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s_in_%s", test.ip, test.cidr), func(t *testing.T) {
+			cmd := []string{"sh", "-c", fmt.Sprintf("cidr_match '%s' '%s'; echo \"exit=$?\"", test.ip, test.cidr)}
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+			require.NoError(t, err, "Failed to execute cidr_match")
+			assert.Contains(t, output, fmt.Sprintf("exit=%d", test.exitCode))
+		})
+	}
+}
+
+// Test 2: Rules are validated and written
+func (suite *CheckAccessTestSuite) TestSetAccessRules(t *testing.T) {
+	cmd := []string{"sh", "-c", "ACCESS_DIR=/tmp/access-rules set_access_rules backup '10.0.0.0/8,192.168.0.0/16' '10.9.0.0/16' && cat /tmp/access-rules/users/backup"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to write access rules")
+
+	assert.Contains(t, output, "allow 10.0.0.0/8")
+	assert.Contains(t, output, "allow 192.168.0.0/16")
+	assert.Contains(t, output, "deny 10.9.0.0/16")
+
+	// Invalid CIDRs are rejected with the owning scope in the message
+	cmd = []string{"sh", "-c", "ACCESS_DIR=/tmp/access-rules set_access_rules --global '10.0.0.0/40' ''"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.Error(t, err, "Expected error due to invalid CIDR")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid CIDR '10.0.0.0/40' in access list for server")
+}
+
+// Test 3: Login decisions combine server and user rules
+func (suite *CheckAccessTestSuite) TestCheckAccess(t *testing.T) {
+	setup := "export ACCESS_DIR=/tmp/check-access && " +
+		"set_access_rules --global '172.16.0.0/12' '172.20.0.0/16' >/dev/null && " +
+		"set_access_rules backup '10.0.0.0/8' '' >/dev/null && " +
+		"set_access_rules guest '' '172.17.0.0/16' >/dev/null && " +
+		"set_access_rules v6user '2001:db8::/32' '2001:db8:bad::/48' >/dev/null"
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to write access rules")
+
+	tests := []struct {
+		user    string
+		ip      string
+		allowed bool
+		reason  string
+	}{
+		{"backup", "10.2.3.4", true, ""},                                  // User allow list replaces server list
+		{"backup", "172.18.0.1", false, "not in the allow list"},          // Server allow list no longer applies
+		{"guest", "172.18.0.1", true, ""},                                 // Falls back to server allow list
+		{"guest", "172.17.0.1", false, "matches deny rule 172.17.0.0/16"}, // User deny list
+		{"guest", "172.20.0.5", false, "matches deny rule 172.20.0.0/16"}, // Server deny list
+		{"guest", "::ffff:172.18.0.1", true, ""},                          // IPv4-mapped addresses
+		{"nobody", "8.8.8.8", false, "not in the allow list"},
+		{"v6user", "2001:db8:1::10", true, ""},
+		{"v6user", "fe80::1%eth0", false, "not in the allow list"}, // Zone index is dropped
+		{"v6user", "2001:db8:bad::1", false, "matches deny rule 2001:db8:bad::/48"},
+		{"v6user", "10.2.3.4", false, "not in the allow list"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s_from_%s", test.user, test.ip), func(t *testing.T) {
+			cmd := []string{"sh", "-c", fmt.Sprintf("ACCESS_DIR=/tmp/check-access check_access '%s' '%s'", test.user, test.ip)}
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+
+			if test.allowed {
+				require.NoError(t, err, "Expected login to be allowed")
+				assert.Empty(t, output)
+			} else {
+				require.Error(t, err, "Expected login to be rejected")
+				assert.Contains(t, stripAnsiCodes(output), fmt.Sprintf("Rejected login for '%s' from", test.user))
+				assert.Contains(t, stripAnsiCodes(output), test.reason)
+			}
+		})
+	}
+}
+
+// Test 4: Session limits are validated and written
+func (suite *CheckAccessTestSuite) TestSetSessionLimit(t *testing.T) {
+	cmd := []string{"sh", "-c", "ACCESS_DIR=/tmp/session-limits set_session_limit backup 2 && cat /tmp/session-limits/sessions/backup"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to write session limit")
+	assert.Contains(t, stripAnsiCodes(output), "User 'backup' is limited to 2 concurrent session(s)")
+	assert.Contains(t, output, "2\n")
+
+	// A limit of 0 removes the limit
+	cmd = []string{"sh", "-c", "ACCESS_DIR=/tmp/session-limits set_session_limit backup 0 && test ! -f /tmp/session-limits/sessions/backup"}
+	_, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Expected session limit to be removed")
+
+	// Non-numeric limits are rejected
+	cmd = []string{"sh", "-c", "ACCESS_DIR=/tmp/session-limits set_session_limit backup many"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.Error(t, err, "Expected error due to invalid session limit")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid session limit 'many' for user 'backup'")
+}
+
+// Test 5: Session limits also apply to users matched by an allow list
+func (suite *CheckAccessTestSuite) TestSessionLimitWithAllowList(t *testing.T) {
+	// A process titled like a vsftpd session and running as the user stands in for a logged-in session
+	setup := "export ACCESS_DIR=/tmp/session-access && " +
+		"set_access_rules limited '10.0.0.0/8' '' >/dev/null && " +
+		"set_session_limit limited 1 >/dev/null && " +
+		"adduser -D -H limited"
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to prepare session limit")
+	t.Cleanup(func() {
+		_, _ = ExecCommandInContainer(t, suite.env.ContainerName, []string{"pkill", "-u", "limited"})
+	})
+
+	check := []string{"sh", "-c", "ACCESS_DIR=/tmp/session-access check_access limited 10.1.2.3"}
+	_, err = ExecCommandInContainer(t, suite.env.ContainerName, check)
+	require.NoError(t, err, "Expected first session to be allowed")
+
+	start := []string{"su", "-s", "/bin/bash", "limited", "-c", "(exec -a 'vsftpd: 10.1.2.3/limited: IDLE' bash -c 'sleep 60; :') >/dev/null 2>&1 &"}
+	_, err = ExecCommandInContainer(t, suite.env.ContainerName, start)
+	require.NoError(t, err, "Failed to start session stand-in")
+
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, check)
+	require.Error(t, err, "Expected second session to be rejected")
+	assert.Contains(t, stripAnsiCodes(output), "user already has 1 of 1 sessions")
+}
+
+// Test 6: Anonymous access is refused where the login-time IP check cannot apply
+func (suite *CheckAccessTestSuite) TestAnonymousStartup(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"AllowList", "ALLOW_CIDRS=10.0.0.0/8", "Anonymous access cannot be enabled together with server allow_cidrs or deny_cidrs"},
+		{"DenyList", "DENY_CIDRS=203.0.113.0/24", "Anonymous access cannot be enabled together with server allow_cidrs or deny_cidrs"},
+		{"RootInHomes", "ANON_ROOT=/ftp/public", "Invalid anonymous root '/ftp/public'. Expected a directory outside /ftp"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The entrypoint stops before starting vsftpd
+			cmd := []string{"sh", "-c", "ACCESS_DIR=/tmp/anon-access ANON_ENABLED=true " + test.env + " docker-entrypoint"}
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+			require.Error(t, err, "Expected startup to fail")
+			assert.Contains(t, stripAnsiCodes(output), test.want)
+		})
+	}
+}
+
+// Test 7: Connection rules hold the server deny list and every allow list
+func (suite *CheckAccessTestSuite) TestConnectRules(t *testing.T) {
+	setup := "export ACCESS_DIR=/tmp/connect-rules && " +
+		"set_access_rules --global '' '172.20.0.0/16' >/dev/null && " +
+		"set_access_rules guest '10.0.0.0/8' '' >/dev/null && " +
+		"cat $ACCESS_DIR/connect"
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to write access rules")
+	assert.Equal(t, "deny 172.20.0.0/16\n", output, "Without a server allow list any address may connect")
+
+	setup = "export ACCESS_DIR=/tmp/connect-rules && " +
+		"set_access_rules --global '172.16.0.0/12' '172.20.0.0/16' >/dev/null && " +
+		"set_access_rules backup '2001:db8::/32' '2001:db8:bad::/48' >/dev/null && " +
+		"cat $ACCESS_DIR/connect"
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to write access rules")
+
+	assert.Contains(t, output, "deny 172.20.0.0/16")
+	assert.Contains(t, output, "allow 172.16.0.0/12")
+	assert.Contains(t, output, "allow 10.0.0.0/8", "User allow lists extend the server allow list")
+	assert.Contains(t, output, "allow 2001:db8::/32")
+	assert.NotContains(t, output, "2001:db8:bad::/48", "User deny lists only apply at login")
+}
+
+// Test 8: Sessions are counted per login name, even when users share an account
+func (suite *CheckAccessTestSuite) TestUserSessions(t *testing.T) {
+	// Both users run as root, and "a.b" would match "axb" as a pattern
+	setup := "printf 'a.b:x:root\\naxb:x:root\\n' > /tmp/session-users && " +
+		"(exec -a 'vsftpd: 10.1.2.3/axb: IDLE' bash -c 'sleep 60; :') >/dev/null 2>&1 & " +
+		"(exec -a 'vsftpd: 2001:db8::1/a.b: IDLE' bash -c 'sleep 60; :') >/dev/null 2>&1 & " +
+		"(exec -a 'vsftpd: 2001:db8::1/a.b: RETR reports/axb: ' bash -c 'sleep 60; :') >/dev/null 2>&1 &"
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"bash", "-c", setup})
+	require.NoError(t, err, "Failed to start session stand-ins")
+	t.Cleanup(func() {
+		_, _ = ExecCommandInContainer(t, suite.env.ContainerName, []string{"pkill", "-f", "^vsftpd: "})
+	})
+
+	count := func(name string) string {
+		cmd := []string{"sh", "-c", fmt.Sprintf("VIRTUAL_USER_DB=/tmp/session-users user_sessions '%s' | wc -l", name)}
+		output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+		require.NoError(t, err, "Failed to count sessions")
+		return strings.TrimSpace(output)
+	}
+
+	assert.Equal(t, "2", count("a.b"))
+	assert.Equal(t, "1", count("axb"), "Another user's transfer status should not count")
+	assert.Equal(t, "0", count("root"), "Users sharing the account should not count")
+}
+
+// Test 9: Connection limits that are not whole numbers stop startup
+func (suite *CheckAccessTestSuite) TestInvalidLimits(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"MAX_CLIENTS=ten", "Invalid value 'ten' for max_clients. Expected a whole number."},
+		{"MAX_PER_IP=-1", "Invalid value '-1' for max_per_ip. Expected a whole number."},
+		{"IDLE_TIMEOUT=5m", "Invalid value '5m' for idle_session_timeout. Expected a whole number."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.env, func(t *testing.T) {
+			// The entrypoint stops before starting vsftpd
+			cmd := []string{"sh", "-c", "ACCESS_DIR=/tmp/limit-access " + test.env + " docker-entrypoint"}
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+			require.Error(t, err, "Expected startup to fail")
+			assert.Contains(t, stripAnsiCodes(output), test.want)
+		})
+	}
+}
+
+// Main test runner
+func TestCheckAccessTestSuite(t *testing.T) {
+	suite := &CheckAccessTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestCidrMatch", suite.TestCidrMatch)
+	t.Run("TestSetAccessRules", suite.TestSetAccessRules)
+	t.Run("TestCheckAccess", suite.TestCheckAccess)
+	t.Run("TestSetSessionLimit", suite.TestSetSessionLimit)
+	t.Run("TestSessionLimitWithAllowList", suite.TestSessionLimitWithAllowList)
+	t.Run("TestAnonymousStartup", suite.TestAnonymousStartup)
+	t.Run("TestConnectRules", suite.TestConnectRules)
+	t.Run("TestUserSessions", suite.TestUserSessions)
+	t.Run("TestInvalidLimits", suite.TestInvalidLimits)
+}
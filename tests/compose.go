@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// UserSource selects how a generated project passes its users to the server
+type UserSource string
+
+const (
+	UsersFromEnv    UserSource = "env"    // The only user is passed as FTP_USER and FTP_PASS
+	UsersFromConfig UserSource = "config" // Users are listed in a generated config file
+)
+
+// TLSMode selects whether a generated project serves FTPS
+type TLSMode string
+
+const (
+	TLSNone       TLSMode = ""            // Plain FTP
+	TLSSelfSigned TLSMode = "self-signed" // A sidecar generates a self-signed certificate
+)
+
+// Paths of the certificate written by the ssl sidecar (see fixtures/generate-cert.sh)
+const (
+	selfSignedCert = "/ssl/live/mini-ftp.duckdns.org/fullchain.pem"
+	selfSignedKey  = "/ssl/live/mini-ftp.duckdns.org/privkey.pem"
+)
+
+// FTPConfig is marshalled to the config file of a generated project
+type FTPConfig struct {
+	Server ServerConfig   `yaml:"server,omitempty"`
+	Users  []UserConfig   `yaml:"users"`
+	Extra  map[string]any `yaml:",inline"` // Other sections, e.g. groups or auth
+}
+
+// ServerConfig holds the server section of the config file
+type ServerConfig struct {
+	Address string         `yaml:"address,omitempty"`
+	TLSCert string         `yaml:"tls_cert,omitempty"`
+	TLSKey  string         `yaml:"tls_key,omitempty"`
+	Extra   map[string]any `yaml:",inline"` // Any other server keys
+}
+
+// UserConfig is one entry of the users list. Users from TestOptions.Users
+// are added automatically, so entries are only needed for extra keys.
+type UserConfig struct {
+	Username    string         `yaml:"username"`
+	PasswordEnv string         `yaml:"password_env,omitempty"`
+	Extra       map[string]any `yaml:",inline"` // Any other user keys
+}
+
+// composeProject mirrors the parts of a compose file the generator writes
+type composeProject struct {
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes,omitempty"`
+}
+
+// composeService is one service of a generated compose file
+type composeService struct {
+	Image       string            `yaml:"image"`
+	Init        bool              `yaml:"init,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	CapAdd      []string          `yaml:"cap_add,omitempty"`
+	SecurityOpt []string          `yaml:"security_opt,omitempty"`
+}
+
+// writeGeneratedProject writes docker-compose.yaml, and config.yaml for
+// config users, into dir from the declarative parts of opts
+func writeGeneratedProject(t testing.TB, dir string, opts *TestOptions) {
+	ftp := composeService{
+		Image: "${FTP_IMAGE}",
+		Ports: []string{
+			"${FTP_PORT}:21",
+			"${PASV_MIN_PORT}-${PASV_MAX_PORT}:${PASV_MIN_PORT}-${PASV_MAX_PORT}",
+		},
+		Environment: map[string]string{},
+		Volumes:     append([]string{}, opts.Volumes...),
+		CapAdd:      opts.CapAdd,
+		SecurityOpt: opts.SecurityOpt,
+	}
+	project := composeProject{
+		Services: map[string]composeService{},
+		Volumes:  map[string]struct{}{},
+	}
+
+	if opts.TLS == TLSSelfSigned {
+		project.Services["ssl"] = composeService{
+			Image:   "${SSL_IMAGE}",
+			Init:    true,
+			Restart: "no",
+			Volumes: []string{"ssl:/ssl"},
+		}
+		ftp.Volumes = append(ftp.Volumes, "ssl:/ssl")
+	}
+
+	switch opts.Source {
+	case UsersFromEnv:
+		require.Len(t, opts.Users, 1, "Environment variables can only define one user")
+		for username, password := range opts.Users {
+			ftp.Environment["FTP_USER"] = username
+			ftp.Environment["FTP_PASS"] = password
+		}
+		ftp.Environment["ADDRESS"] = opts.Address
+		if opts.TLS == TLSSelfSigned {
+			ftp.Environment["TLS_CERT"] = selfSignedCert
+			ftp.Environment["TLS_KEY"] = selfSignedKey
+		}
+
+	case UsersFromConfig:
+		config := FTPConfig{}
+		if opts.Config != nil {
+			config = *opts.Config
+			config.Users = append([]UserConfig{}, opts.Config.Users...)
+		}
+		if config.Server.Address == "" {
+			config.Server.Address = opts.Address
+		}
+		if opts.TLS == TLSSelfSigned {
+			config.Server.TLSCert = selfSignedCert
+			config.Server.TLSKey = selfSignedKey
+		}
+		config.Users = configUsers(config.Users, opts, ftp.Environment)
+
+		data, err := yaml.Marshal(config)
+		require.NoError(t, err, "Failed to generate config file")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), data, 0644), "Failed to write config file")
+
+		ftp.Environment["CONFIG_FILE"] = "/etc/ftp/config.yaml"
+		ftp.Volumes = append(ftp.Volumes, "./config.yaml:/etc/ftp/config.yaml")
+
+	default:
+		t.Fatalf("Unknown user source: %q", opts.Source)
+	}
+
+	// Extra variables are applied last so they can override the generated ones
+	for key, value := range opts.Env {
+		ftp.Environment[key] = value
+	}
+	for key, value := range ftp.Environment {
+		// Compose would interpolate a literal $ in passwords
+		ftp.Environment[key] = strings.ReplaceAll(value, "$", "$$")
+	}
+	project.Services["ftp"] = ftp
+
+	// Declare every named volume, leaving out bind mounts
+	for _, service := range project.Services {
+		for _, volume := range service.Volumes {
+			source, _, _ := strings.Cut(volume, ":")
+			if !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "/") {
+				project.Volumes[source] = struct{}{}
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(project)
+	require.NoError(t, err, "Failed to generate compose file")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), data, 0644), "Failed to write compose file")
+}
+
+// envName matches the characters that cannot appear in a variable name, such
+// as the @ of e-mail style usernames
+var envName = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// configUsers adds every user in opts.Users to the config's users list with a
+// password_env, and sets the password variables in env. The EnvUser is passed
+// as FTP_USER instead, and its config entry gets a different password so that
+// the override can be observed. Entries with another password source, such as
+// password_age, keep it, and the anonymous user is never listed.
+func configUsers(users []UserConfig, opts *TestOptions, env map[string]string) []UserConfig {
+	listed := map[string]int{}
+	for i, user := range users {
+		listed[user.Username] = i
+	}
+
+	usernames := make([]string, 0, len(opts.Users))
+	for username := range opts.Users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		if i, ok := listed[username]; username == "anonymous" || ok && hasPasswordSource(users[i]) {
+			continue
+		}
+		password := opts.Users[username]
+		if username == opts.EnvUser {
+			env["FTP_USER"] = username
+			env["FTP_PASS"] = password
+			password = "overridden-" + password
+		}
+
+		passwordEnv := fmt.Sprintf("%s_PASS", envName.ReplaceAllString(strings.ToUpper(username), "_"))
+		env[passwordEnv] = password
+
+		if i, ok := listed[username]; ok {
+			if users[i].PasswordEnv == "" {
+				users[i].PasswordEnv = passwordEnv
+			}
+			continue
+		}
+		users = append(users, UserConfig{Username: username, PasswordEnv: passwordEnv})
+	}
+	return users
+}
+
+// hasPasswordSource reports whether a user entry sets its password with a key
+// other than password_env
+func hasPasswordSource(user UserConfig) bool {
+	for key := range user.Extra {
+		if strings.HasPrefix(key, "password") {
+			return true
+		}
+	}
+	return false
+}
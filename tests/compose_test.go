@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// readGenerated parses a generated YAML file into out
+func readGenerated(t *testing.T, path string, out any) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "Failed to read generated file")
+	require.NoError(t, yaml.Unmarshal(data, out), "Generated file should be valid YAML")
+}
+
+// TestGeneratedEnvProject checks a project whose only user comes from the environment
+func TestGeneratedEnvProject(t *testing.T) {
+	dir := t.TempDir()
+	writeGeneratedProject(t, dir, &TestOptions{
+		Source:  UsersFromEnv,
+		TLS:     TLSSelfSigned,
+		Address: "127.0.0.1",
+		Users:   map[string]string{"user": "Pa$$word"},
+		Env:     map[string]string{"MAX_CLIENTS": "5"},
+		Volumes: []string{"ftp:/ftp"},
+	})
+
+	var project composeProject
+	readGenerated(t, filepath.Join(dir, "docker-compose.yaml"), &project)
+
+	ftp := project.Services["ftp"]
+	assert.Equal(t, "${FTP_IMAGE}", ftp.Image, "The shared test image should be used")
+	assert.Equal(t, "user", ftp.Environment["FTP_USER"])
+	assert.Equal(t, "Pa$$$$word", ftp.Environment["FTP_PASS"], "Dollar signs should be escaped for compose")
+	assert.Equal(t, "5", ftp.Environment["MAX_CLIENTS"])
+	assert.Equal(t, selfSignedCert, ftp.Environment["TLS_CERT"])
+	assert.ElementsMatch(t, []string{"ftp:/ftp", "ssl:/ssl"}, ftp.Volumes)
+	assert.Contains(t, ftp.Ports, "${FTP_PORT}:21")
+
+	assert.Contains(t, project.Services, "ssl", "TLS should add the certificate sidecar")
+	assert.Equal(t, "${SSL_IMAGE}", project.Services["ssl"].Image)
+	assert.Contains(t, project.Volumes, "ftp")
+	assert.Contains(t, project.Volumes, "ssl")
+	assert.NoFileExists(t, filepath.Join(dir, "config.yaml"))
+}
+
+// TestGeneratedConfigProject checks a project whose users come from a generated config file
+func TestGeneratedConfigProject(t *testing.T) {
+	dir := t.TempDir()
+	writeGeneratedProject(t, dir, &TestOptions{
+		Source:  UsersFromConfig,
+		EnvUser: "bob",
+		Address: "127.0.0.1",
+		Users:   map[string]string{"alice": "Wq4mTz8KcR2n", "bob": "Hn6vPs3LdX9f"},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{"max_clients": 20}},
+			Users: []UserConfig{
+				{Username: "alice", Extra: map[string]any{"quota": "1MiB"}},
+			},
+		},
+	})
+
+	var project composeProject
+	readGenerated(t, filepath.Join(dir, "docker-compose.yaml"), &project)
+	ftp := project.Services["ftp"]
+	assert.Equal(t, "/etc/ftp/config.yaml", ftp.Environment["CONFIG_FILE"])
+	assert.Contains(t, ftp.Volumes, "./config.yaml:/etc/ftp/config.yaml")
+	assert.NotContains(t, project.Services, "ssl")
+	assert.Empty(t, project.Volumes, "Bind mounts should not be declared as volumes")
+
+	// bob is overridden from the environment, so his config password differs
+	assert.Equal(t, "bob", ftp.Environment["FTP_USER"])
+	assert.Equal(t, "Hn6vPs3LdX9f", ftp.Environment["FTP_PASS"])
+	assert.Equal(t, "overridden-Hn6vPs3LdX9f", ftp.Environment["BOB_PASS"])
+	assert.Equal(t, "Wq4mTz8KcR2n", ftp.Environment["ALICE_PASS"])
+
+	var config map[string]any
+	readGenerated(t, filepath.Join(dir, "config.yaml"), &config)
+	assert.Equal(t, map[string]any{"address": "127.0.0.1", "max_clients": 20}, config["server"])
+	assert.Equal(t, []any{
+		map[string]any{"username": "alice", "password_env": "ALICE_PASS", "quota": "1MiB"},
+		map[string]any{"username": "bob", "password_env": "BOB_PASS"},
+	}, config["users"])
+}
+
+// TestGeneratedUserNames checks users whose names or passwords need special handling
+func TestGeneratedUserNames(t *testing.T) {
+	dir := t.TempDir()
+	writeGeneratedProject(t, dir, &TestOptions{
+		Source:  UsersFromConfig,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"alice@example.com": "Wm4rKp8ZtN3c",
+			"bob":               "Jd7xQv2HsL9f",
+			"anonymous":         "",
+		},
+		Config: &FTPConfig{
+			Users: []UserConfig{
+				{Username: "bob", Extra: map[string]any{"password_age": "age-encrypted"}},
+			},
+			Extra: map[string]any{"groups": []map[string]any{{"name": "team", "members": []string{"bob"}}}},
+		},
+	})
+
+	var project composeProject
+	readGenerated(t, filepath.Join(dir, "docker-compose.yaml"), &project)
+	ftp := project.Services["ftp"]
+	assert.Equal(t, "Wm4rKp8ZtN3c", ftp.Environment["ALICE_EXAMPLE_COM_PASS"], "Variable names should only use valid characters")
+	assert.NotContains(t, ftp.Environment, "BOB_PASS", "Users with another password source should keep it")
+	assert.NotContains(t, ftp.Environment, "ANONYMOUS_PASS")
+
+	var config map[string]any
+	readGenerated(t, filepath.Join(dir, "config.yaml"), &config)
+	assert.Equal(t, []any{
+		map[string]any{"username": "bob", "password_age": "age-encrypted"},
+		map[string]any{"username": "alice@example.com", "password_env": "ALICE_EXAMPLE_COM_PASS"},
+	}, config["users"])
+	assert.Equal(t, []any{map[string]any{"name": "team", "members": []any{"bob"}}}, config["groups"])
+}
+
+// TestUniqueIPv6Subnet checks that each project gets its own unique local /64
+func TestUniqueIPv6Subnet(t *testing.T) {
+	_, ula, err := net.ParseCIDR("fc00::/7")
+	require.NoError(t, err)
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		subnet := uniqueIPv6Subnet(t)
+		ip, network, err := net.ParseCIDR(subnet)
+		require.NoError(t, err, "Invalid subnet %s", subnet)
+
+		ones, _ := network.Mask.Size()
+		assert.Equal(t, 64, ones)
+		assert.True(t, ula.Contains(ip), "%s should be a unique local address", subnet)
+		assert.False(t, seen[subnet], "%s was generated twice", subnet)
+		seen[subnet] = true
+	}
+}
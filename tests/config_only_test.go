@@ -2,6 +2,8 @@ package tests
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/secsy/goftp"
@@ -13,148 +15,76 @@ import (
 type ConfigOnlyTestSuite struct {
 	opts    TestOptions
 	clients map[string]*goftp.Client
+	env     *ComposeEnv
 }
 
 // SetupSuite initializes environment and clients before tests run
 func (suite *ConfigOnlyTestSuite) SetupSuite(t *testing.T) {
 	// Define test options (Single User)
-	config := "config.yaml"
 	suite.opts = TestOptions{
-		ComposeFile:  "docker-compose.config-only.yaml",
-		ConfigFile:   &config,
-		UseSSL:       false,
-		Address:      "127.0.0.1",
-		Port:         2123,
-		PassivePorts: "22020-22029",
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
 		Users: map[string]string{
 			"user": "afqpVazRzAdN",
 		},
 	}
 
 	// Setup environment
-	tmpAndProject := setupTestEnv(t, suite.opts)
-	t.Cleanup(func() { teardownTestEnv(t, tmpAndProject) })
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
 
 	// Setup FTP clients for all users
 	suite.clients = setupFTPClients(t, suite.opts)
 }
 
-// TestFileOperations checks upload, download, and deletion
-func (suite *ConfigOnlyTestSuite) TestFileOperations(t *testing.T) {
-	client := suite.clients["user"]
-
-	// Upload a file
-	content := []byte("test file content")
-	require.NoError(t, client.Store("test-file.txt", bytes.NewReader(content)))
-
-	// Download and verify content
-	var buf bytes.Buffer
-	require.NoError(t, client.Retrieve("test-file.txt", &buf))
-	assert.Equal(t, "test file content", buf.String())
-
-	// Delete the file and verify removal
-	require.NoError(t, client.Delete("test-file.txt"))
-
-	entries, err := client.ReadDir("/")
-	require.NoError(t, err)
-	for _, entry := range entries {
-		assert.NotEqual(t, "test-file.txt", entry.Name(), "File should be deleted")
-	}
+// TestConformance runs the shared FTP conformance checks for every user
+func (suite *ConfigOnlyTestSuite) TestConformance(t *testing.T) {
+	runConformance(t, suite.opts)
 }
 
-// TestRenameFile checks renaming functionality
-func (suite *ConfigOnlyTestSuite) TestRenameFile(t *testing.T) {
+// TestFilesOnDisk checks uploads against the files in /ftp and files copied in from the host
+func (suite *ConfigOnlyTestSuite) TestFilesOnDisk(t *testing.T) {
 	client := suite.clients["user"]
+	tmpDir := t.TempDir()
 
-	// Upload file
-	require.NoError(t, client.Store("rename-test.txt", bytes.NewReader([]byte("rename test"))))
-
-	// Rename file
-	require.NoError(t, client.Rename("rename-test.txt", "renamed-file.txt"))
-
-	// Verify renamed file exists
-	entries, err := client.ReadDir("/")
+	// Uploads land in the user's home
+	require.NoError(t, client.Store("on-disk.txt", bytes.NewReader([]byte("stored on disk"))))
+	suite.env.CopyOut(t, "ftp", "/ftp/user/on-disk.txt", filepath.Join(tmpDir, "on-disk.txt"))
+	data, err := os.ReadFile(filepath.Join(tmpDir, "on-disk.txt"))
 	require.NoError(t, err)
+	assert.Equal(t, "stored on disk", string(data))
 
-	found := false
-	for _, entry := range entries {
-		if entry.Name() == "renamed-file.txt" {
-			found = true
-			break
-		}
-	}
-	assert.True(t, found, "Renamed file should exist")
-
-	// Cleanup
-	require.NoError(t, client.Delete("renamed-file.txt"))
-}
-
-// TestDirectoryOperations checks directory operations
-func (suite *ConfigOnlyTestSuite) TestDirectoryOperations(t *testing.T) {
-	client := suite.clients["user"]
-
-	// Create directory
-	dirPath, err := client.Mkdir("test-dir")
-	require.NoError(t, err)
-	assert.Equal(t, "/test-dir", dirPath)
-
-	// Rename directory
-	require.NoError(t, client.Rename("test-dir", "renamed-dir"))
-
-	// Verify renamed directory exists
-	entries, err := client.ReadDir("/")
+	// Files placed in the home are served to the user
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "copied-in.txt"), []byte("copied in"), 0644))
+	suite.env.CopyIn(t, "ftp", filepath.Join(tmpDir, "copied-in.txt"), "/ftp/user/copied-in.txt")
+	_, err = suite.env.Exec("ftp", "chown", "user", "/ftp/user/copied-in.txt")
 	require.NoError(t, err)
 
-	found := false
-	for _, entry := range entries {
-		if entry.Name() == "renamed-dir" && entry.IsDir() {
-			found = true
-			break
-		}
-	}
-	assert.True(t, found, "Renamed directory should exist")
-
-	// Remove directory
-	require.NoError(t, client.Rmdir("renamed-dir"))
-
-	// Verify deletion
-	entries, err = client.ReadDir("/")
-	require.NoError(t, err)
-
-	found = false
-	for _, entry := range entries {
-		if entry.Name() == "renamed-dir" {
-			found = true
-			break
-		}
-	}
-	assert.False(t, found, "Directory should be deleted")
-}
-
-// TestAccessControl ensures restricted access to unauthorized directories
-func (suite *ConfigOnlyTestSuite) TestAccessControl(t *testing.T) {
-	client := suite.clients["user"]
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("copied-in.txt", &buf))
+	assert.Equal(t, "copied in", buf.String())
 
-	// Attempt unauthorized access
-	err := client.Retrieve("../unauthorized.txt", &bytes.Buffer{})
-	assert.Error(t, err, "Should not be able to access files outside FTP home directory")
+	// Cleanup
+	require.NoError(t, client.Delete("on-disk.txt"))
+	require.NoError(t, client.Delete("copied-in.txt"))
 }
 
-// TestFilePermissions checks file permissions
-func (suite *ConfigOnlyTestSuite) TestFilePermissions(t *testing.T) {
+// TestRestart checks that users and files survive a server restart
+func (suite *ConfigOnlyTestSuite) TestRestart(t *testing.T) {
 	client := suite.clients["user"]
+	require.NoError(t, client.Store("before-restart.txt", bytes.NewReader([]byte("kept"))))
 
-	// Upload a file
-	content := []byte("permission test")
-	require.NoError(t, client.Store("perm-test.txt", bytes.NewReader(content)))
+	suite.env.Restart(t, "ftp")
 
-	// Download and verify content
+	// The existing client opens new connections for each command
 	var buf bytes.Buffer
-	require.NoError(t, client.Retrieve("perm-test.txt", &buf))
-	assert.Equal(t, "permission test", buf.String())
+	require.NoError(t, client.Retrieve("before-restart.txt", &buf), "The user should log in after a restart")
+	assert.Equal(t, "kept", buf.String())
+	assert.Contains(t, suite.env.Logs(t, "ftp"), "user")
 
 	// Cleanup
-	require.NoError(t, client.Delete("perm-test.txt"))
+	require.NoError(t, client.Delete("before-restart.txt"))
 }
 
 // Main test runner
@@ -162,9 +92,7 @@ func TestConfigOnlyTestSuite(t *testing.T) {
 	suite := &ConfigOnlyTestSuite{}
 	suite.SetupSuite(t)
 
-	t.Run("TestFileOperations", suite.TestFileOperations)
-	t.Run("TestRenameFile", suite.TestRenameFile)
-	t.Run("TestDirectoryOperations", suite.TestDirectoryOperations)
-	t.Run("TestAccessControl", suite.TestAccessControl)
-	t.Run("TestFilePermissions", suite.TestFilePermissions)
+	t.Run("TestConformance", suite.TestConformance)
+	t.Run("TestFilesOnDisk", suite.TestFilesOnDisk)
+	t.Run("TestRestart", suite.TestRestart)
 }
\ No newline at end of file
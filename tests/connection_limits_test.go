@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ConnectionLimitsTestSuite encapsulates test options and clients
+type ConnectionLimitsTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *ConnectionLimitsTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with connection and session limits
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"single": "Vn5sLq8TzB3k", // max_sessions: 1
+			"multi":  "Hw2pCx7GdR4m", // Server default of 3 sessions
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{
+				"max_clients":       20,
+				"max_per_ip":        4,
+				"idle_timeout":      10,
+				"data_timeout":      30,
+				"max_user_sessions": 3,
+			}},
+			Users: []UserConfig{
+				{Username: "single", Extra: map[string]any{"max_sessions": 1}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// dialControl opens a raw control connection and returns it with the server greeting
+func (suite *ConnectionLimitsTestSuite) dialControl(t *testing.T) (net.Conn, *bufio.Reader, string) {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port), 10*time.Second)
+	require.NoError(t, err, "Failed to open control connection")
+
+	reader := bufio.NewReader(conn)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	greeting, err := reader.ReadString('\n')
+	require.NoError(t, err, "Failed to read server greeting")
+
+	return conn, reader, greeting
+}
+
+// TestMaxPerIP checks that connections beyond max_per_ip are refused
+func (suite *ConnectionLimitsTestSuite) TestMaxPerIP(t *testing.T) {
+	var conns []net.Conn
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// The first four connections from the same address are accepted
+	for i := 0; i < 4; i++ {
+		conn, _, greeting := suite.dialControl(t)
+		conns = append(conns, conn)
+		assert.True(t, strings.HasPrefix(greeting, "220"), "Expected greeting, got: %s", greeting)
+	}
+
+	// The fifth is refused
+	conn, _, greeting := suite.dialControl(t)
+	conns = append(conns, conn)
+	assert.True(t, strings.HasPrefix(greeting, "421"), "Expected connection to be refused, got: %s", greeting)
+}
+
+// TestIdleTimeout checks that idle sessions are closed after idle_timeout
+func (suite *ConnectionLimitsTestSuite) TestIdleTimeout(t *testing.T) {
+	conn, reader, greeting := suite.dialControl(t)
+	defer conn.Close()
+	require.True(t, strings.HasPrefix(greeting, "220"), "Expected greeting, got: %s", greeting)
+
+	_, err := fmt.Fprintf(conn, "USER multi\r\nPASS %s\r\n", suite.opts.Users["multi"])
+	require.NoError(t, err)
+
+	// Wait past the 10 second idle timeout for the server to hang up
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(30*time.Second)))
+	var lastReply string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		lastReply = line
+	}
+	assert.True(t, strings.HasPrefix(lastReply, "421"), "Expected idle timeout reply, got: %s", lastReply)
+}
+
+// TestPerUserSessionLimit checks that max_sessions caps concurrent logins per user
+func (suite *ConnectionLimitsTestSuite) TestPerUserSessionLimit(t *testing.T) {
+	first := suite.clients["single"]
+	_, err := first.ReadDir("/")
+	require.NoError(t, err, "First session should be accepted")
+
+	// A second session for the same user is rejected while the first is open
+	second := setupFTPClients(t, TestOptions{
+		Address: suite.opts.Address,
+		Port:    suite.opts.Port,
+		Users:   map[string]string{"single": suite.opts.Users["single"]},
+	})["single"]
+	defer second.Close()
+
+	_, err = second.ReadDir("/")
+	assert.Error(t, err, "Second concurrent session should be rejected")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "user already has 1 of 1 sessions")
+
+	// Once the first session ends the user may log in again
+	require.NoError(t, first.Close())
+	assert.Eventually(t, func() bool {
+		_, err := second.ReadDir("/")
+		return err == nil
+	}, 15*time.Second, time.Second, "Session should be accepted after the first one closes")
+
+	// Other users keep the server default
+	_, err = suite.clients["multi"].ReadDir("/")
+	assert.NoError(t, err)
+}
+
+// TestPassiveRangeWarning checks that a passive range smaller than max_clients is reported
+func (suite *ConnectionLimitsTestSuite) TestPassiveRangeWarning(t *testing.T) {
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, fmt.Sprintf("Passive port range %s has 10 ports but max_clients is 20", suite.opts.PassivePorts))
+}
+
+// Main test runner
+func TestConnectionLimitsTestSuite(t *testing.T) {
+	suite := &ConnectionLimitsTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestMaxPerIP", suite.TestMaxPerIP)
+	t.Run("TestIdleTimeout", suite.TestIdleTimeout)
+	t.Run("TestPerUserSessionLimit", suite.TestPerUserSessionLimit)
+	t.Run("TestPassiveRangeWarning", suite.TestPassiveRangeWarning)
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// CreateGroupTestSuite encapsulates shared environment for create_group tests
+type CreateGroupTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// SetupSuite prepares the environment before tests run
+func (suite *CreateGroupTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment with two members
+	suite.env = SetupScriptTestEnv(t)
+
+	cmd := []string{"sh", "-c", "create_user alice alicepass && create_user bob bobpass"}
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to create group members")
+}
+
+// Test 1: Invalid arguments are rejected before anything is created
+func (suite *CreateGroupTestSuite) TestInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		message string
+	}{
+		{"MissingMembers", "create_group team '' shared", "Usage: create_group <name> <gid> <directory> <member>..."},
+		{"InvalidName", "create_group 'team one' '' shared alice", "Invalid group name: 'team one'"},
+		{"InvalidGID", "create_group team abc shared alice", "Invalid gid 'abc' for group 'team'"},
+		{"NestedDirectory", "create_group team '' ../escape alice", "Invalid directory '../escape' for group 'team'"},
+		{"UnknownMember", "create_group team '' shared alice carol", "Group 'team' member 'carol' does not exist"},
+		{"ClashesWithUser", "create_group alice '' shared bob", "Group 'alice' already exists"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", test.command})
+			require.Error(t, err, "Expected create_group to fail")
+			assert.Contains(t, stripAnsiCodes(output), test.message)
+		})
+	}
+
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "getent group team"})
+	assert.Error(t, err, "No group should have been created")
+}
+
+// Test 2: The shared folder is created with the group's GID and setgid bit
+func (suite *CreateGroupTestSuite) TestSharedFolder(t *testing.T) {
+	// The script test container has no SYS_ADMIN, so mounting is expected to fail
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "create_group team 2600 shared alice bob"})
+	if err != nil {
+		assert.Contains(t, stripAnsiCodes(output), "need the SYS_ADMIN capability")
+	}
+
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "getent group team && stat -c '%a %G' /ftp/.groups/team"})
+	require.NoError(t, err, "Group and shared folder should exist")
+	assert.Contains(t, output, "team:x:2600:")
+	assert.Contains(t, output, "2775 team")
+}
+
+// Main test runner
+func TestCreateGroupTestSuite(t *testing.T) {
+	suite := &CreateGroupTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestInvalidArguments", suite.TestInvalidArguments)
+	t.Run("TestSharedFolder", suite.TestSharedFolder)
+}
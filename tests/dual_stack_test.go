@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DualStackTestSuite encapsulates test options and clients
+type DualStackTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *DualStackTestSuite) SetupSuite(t *testing.T) {
+	// Define test options for a server listening on both address families
+	config := "config-dual-stack.yaml"
+	suite.opts = TestOptions{
+		ComposeFile: "docker-compose.dual-stack.yaml",
+		ConfigFile:  &config,
+		UseSSL:      false,
+		Address:     "127.0.0.1",
+		Users: map[string]string{
+			"dualuser": "Hq4wTn8VcL3j",
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// roundTrip uploads, downloads and deletes a file with the given client
+func (suite *DualStackTestSuite) roundTrip(t *testing.T, client *goftp.Client, name string) {
+	content := []byte("dual-stack upload over " + name)
+	require.NoError(t, client.Store(name, bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve(name, &buf))
+	assert.Equal(t, string(content), buf.String())
+
+	entries, err := client.ReadDir("/")
+	require.NoError(t, err, "Directory listing should succeed")
+	assert.NotEmpty(t, entries)
+
+	require.NoError(t, client.Delete(name))
+}
+
+// TestListenMode checks that the entrypoint selected the dual-stack listener
+func (suite *DualStackTestSuite) TestListenMode(t *testing.T) {
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Listen Mode: dual", "Listen mode should be logged")
+}
+
+// TestIPv4Client checks that IPv4 clients still work through the IPv6 socket
+func (suite *DualStackTestSuite) TestIPv4Client(t *testing.T) {
+	suite.roundTrip(t, suite.clients["dualuser"], "ipv4.txt")
+}
+
+// TestIPv6Client checks that transfers work over [::1] using EPSV
+func (suite *DualStackTestSuite) TestIPv6Client(t *testing.T) {
+	address := net.JoinHostPort("::1", strconv.Itoa(suite.opts.Port))
+
+	// Docker only publishes ports on IPv6 when the host supports it
+	conn, err := net.DialTimeout("tcp6", address, 2*time.Second)
+	if err != nil {
+		t.Skipf("IPv6 loopback is not reachable on this host: %v", err)
+	}
+	conn.Close()
+
+	config := goftp.Config{
+		User:     "dualuser",
+		Password: suite.opts.Users["dualuser"],
+		Timeout:  10 * time.Second,
+	}
+	client, err := goftp.DialConfig(config, address)
+	require.NoError(t, err, "Failed to connect to FTP server over IPv6")
+	defer client.Close()
+
+	suite.roundTrip(t, client, "ipv6.txt")
+}
+
+// TestIPv6ActiveTransfer checks that EPRT data connections work over IPv6
+func (suite *DualStackTestSuite) TestIPv6ActiveTransfer(t *testing.T) {
+	// The server dials back to the address in EPRT, so connect to the
+	// container's bridge address rather than through the published port
+	ip := suite.env.ServiceIPv6(t, "ftp")
+	if ip == "" {
+		t.Skip("Container has no IPv6 bridge address")
+	}
+	address := net.JoinHostPort(ip, "21")
+
+	conn, err := net.DialTimeout("tcp6", address, 2*time.Second)
+	if err != nil {
+		t.Skipf("Container IPv6 address is not reachable from this host: %v", err)
+	}
+	conn.Close()
+
+	// goftp sends EPRT instead of PORT when the control connection is IPv6
+	config := goftp.Config{
+		User:            "dualuser",
+		Password:        suite.opts.Users["dualuser"],
+		Timeout:         10 * time.Second,
+		ActiveTransfers: true,
+	}
+	client, err := goftp.DialConfig(config, address)
+	require.NoError(t, err, "Failed to connect to FTP server over IPv6")
+	defer client.Close()
+
+	suite.roundTrip(t, client, "eprt.txt")
+}
+
+// Main test runner
+func TestDualStackTestSuite(t *testing.T) {
+	suite := &DualStackTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestListenMode", suite.TestListenMode)
+	t.Run("TestIPv4Client", suite.TestIPv4Client)
+	t.Run("TestIPv6Client", suite.TestIPv6Client)
+	t.Run("TestIPv6ActiveTransfer", suite.TestIPv6ActiveTransfer)
+}
@@ -0,0 +1,152 @@
+package tests
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GroupsTestSuite encapsulates test options and clients
+type GroupsTestSuite struct {
+	opts      TestOptions
+	clients   map[string]*goftp.Client
+	env       *ComposeEnv
+	container string
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *GroupsTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with user1 and user2 sharing a team folder
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"user1":    "Lx4vRm9TcQ2b",
+			"user2":    "Pf8nHw3ZkY6s",
+			"outsider": "Dj5qGt7NsV4m",
+		},
+		Config: &FTPConfig{
+			Users: []UserConfig{
+				{Username: "user1", Extra: map[string]any{"quota": "10MiB"}},
+			},
+			Extra: map[string]any{
+				"groups": []map[string]any{
+					{"name": "team", "gid": 2500, "directory": "shared", "quota": "1MiB", "members": []string{"user1", "user2"}},
+					// No quota of its own, so it counts towards user1's quota
+					{"name": "crew", "directory": "crew", "members": []string{"user1"}},
+				},
+			},
+		},
+		// Shared folders are bind-mounted into each member's chroot
+		CapAdd:      []string{"SYS_ADMIN"},
+		SecurityOpt: []string{"apparmor:unconfined"},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// TestSharedUpload checks that a file uploaded by one member is readable by another
+func (suite *GroupsTestSuite) TestSharedUpload(t *testing.T) {
+	content := []byte("sprint plan from user1")
+	require.NoError(t, suite.clients["user1"].Store("shared/plan.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, suite.clients["user2"].Retrieve("shared/plan.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+}
+
+// TestGroupWrite checks that members can change each other's files
+func (suite *GroupsTestSuite) TestGroupWrite(t *testing.T) {
+	content := []byte("sprint plan revised by user2")
+	require.NoError(t, suite.clients["user2"].Store("shared/plan.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, suite.clients["user1"].Retrieve("shared/plan.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+}
+
+// TestOwnership checks the group ID, setgid bit and inherited group ownership
+func (suite *GroupsTestSuite) TestOwnership(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{"sh", "-c", "getent group team"})
+	require.NoError(t, err, "Group should exist")
+	assert.True(t, strings.HasPrefix(output, "team:x:2500:"), "Group should use the configured GID, got: %s", output)
+
+	// Virtual users join through their service account
+	for _, member := range []string{"user1", "user2"} {
+		account, err := ExecCommandInContainer(t, suite.container, []string{"user_account", member})
+		require.NoError(t, err, "Failed to look up the account for "+member)
+		assert.Contains(t, output, strings.TrimSpace(account))
+	}
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"sh", "-c", "stat -c '%a %G' /ftp/.groups/team"})
+	require.NoError(t, err, "Shared folder should exist")
+	assert.Equal(t, "2775 team\n", output, "Shared folder should be group-owned with the setgid bit")
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"sh", "-c", "stat -c '%a %G' /ftp/.groups/team/plan.txt"})
+	require.NoError(t, err, "Uploaded file should exist")
+	assert.Equal(t, "664 team\n", output, "Uploaded files should inherit the group and be group-writable")
+}
+
+// TestNonMember checks that users outside the group do not see the folder
+func (suite *GroupsTestSuite) TestNonMember(t *testing.T) {
+	_, err := suite.clients["outsider"].ReadDir("shared")
+	assert.Error(t, err, "Non-members should not have a shared folder")
+}
+
+// TestGroupQuota checks that uploads into a folder with a group quota stop with 552
+func (suite *GroupsTestSuite) TestGroupQuota(t *testing.T) {
+	conn, err := suite.clients["user2"].OpenRawConn()
+	require.NoError(t, err, "Failed to open raw connection")
+	defer conn.Close()
+
+	code, msg := rawStore(t, conn, "shared/large.bin", make([]byte, 2*1024*1024))
+	assert.Equal(t, 552, code, "Unexpected reply: %s", msg)
+
+	output, err := ExecCommandInContainer(t, suite.container, []string{"quota_usage", "--group", "team"})
+	require.NoError(t, err, "Failed to read group usage")
+	usage, err := strconv.Atoi(strings.TrimSpace(output))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, usage, 1024*1024, "Group folder should stay within its quota")
+
+	require.NoError(t, suite.clients["user2"].Delete("shared/large.bin"))
+}
+
+// TestQuotaCharges checks that shared files count towards the group's quota
+// when it has one, and towards the member's quota otherwise
+func (suite *GroupsTestSuite) TestQuotaCharges(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{"quota_usage", "user1"})
+	require.NoError(t, err, "Failed to read usage")
+	assert.Equal(t, "0\n", output, "Files in team's folder should be charged to team")
+
+	require.NoError(t, suite.clients["user1"].Store("crew/notes.txt", bytes.NewReader(make([]byte, 100))))
+	t.Cleanup(func() { suite.clients["user1"].Delete("crew/notes.txt") })
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"quota_usage", "user1"})
+	require.NoError(t, err, "Failed to read usage")
+	assert.Equal(t, "100\n", output, "Files in crew's folder should be charged to user1")
+}
+
+// Main test runner
+func TestGroupsTestSuite(t *testing.T) {
+	suite := &GroupsTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestSharedUpload", suite.TestSharedUpload)
+	t.Run("TestGroupWrite", suite.TestGroupWrite)
+	t.Run("TestOwnership", suite.TestOwnership)
+	t.Run("TestNonMember", suite.TestNonMember)
+	t.Run("TestGroupQuota", suite.TestGroupQuota)
+	t.Run("TestQuotaCharges", suite.TestQuotaCharges)
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// imageLabel marks every image built by the tests so they can be found and removed
+const imageLabel = "mini-ftp-test"
+
+// testImage is an image shared by every suite in a test run
+type testImage struct {
+	Name  string            // Repository the image is tagged in
+	Files map[string]string // Build context, context path to path in the project root
+}
+
+// Images used by the suites. The build context and the ID of the Alpine base
+// image are hashed into the tag, so an image is only rebuilt when one of its
+// files or its base image changes.
+var (
+	// serverImage is the mini-ftp image with the test-only vsftpd.conf
+	serverImage = testImage{
+		Name: "mini-ftp-test",
+		Files: map[string]string{
+			"Dockerfile":         "Dockerfile",
+			"scripts":            "scripts",
+			"config":             "config",
+			"preload":            "preload",
+			"config/vsftpd.conf": "tests/fixtures/vsftpd.conf",
+		},
+	}
+
+	// productionImage is the mini-ftp image as published, with the production
+	// vsftpd.conf
+	productionImage = testImage{
+		Name: "mini-ftp-production-test",
+		Files: map[string]string{
+			"Dockerfile": "Dockerfile",
+			"scripts":    "scripts",
+			"config":     "config",
+			"preload":    "preload",
+		},
+	}
+
+	// scriptImage is the mini-ftp image without an entrypoint, for script tests
+	scriptImage = testImage{
+		Name: "mini-ftp-script-test",
+		Files: map[string]string{
+			"Dockerfile": "tests/fixtures/Dockerfile",
+			"scripts":    "scripts",
+		},
+	}
+
+	// sslImage generates the self-signed certificate used by TLSSelfSigned projects
+	sslImage = testImage{
+		Name: "mini-ftp-ssl-test",
+		Files: map[string]string{
+			"Dockerfile":       "tests/fixtures/Dockerfile.ssl",
+			"generate-cert.sh": "tests/fixtures/generate-cert.sh",
+		},
+	}
+
+	// testImages lists every image above, in the order buildImages builds them
+	testImages = []testImage{serverImage, productionImage, scriptImage, sslImage}
+)
+
+// builtImage records the outcome of building one testImage
+type builtImage struct {
+	once sync.Once
+	tag  string
+	err  error
+}
+
+var (
+	builtImagesMu sync.Mutex
+	builtImages   = map[string]*builtImage{}
+)
+
+// Tag builds the image for the current ALPINE_VERSION on first use and
+// returns its tag. Later calls in the same run, and runs that kept their
+// images with -keep-images, reuse it.
+func (image testImage) Tag(t testing.TB) string {
+	builtImagesMu.Lock()
+	built, ok := builtImages[image.Name]
+	if !ok {
+		built = &builtImage{}
+		builtImages[image.Name] = built
+	}
+	builtImagesMu.Unlock()
+
+	built.once.Do(func() { built.tag, _, built.err = image.build() })
+	require.NoError(t, built.err, "Failed to build image %s", image.Name)
+	return built.tag
+}
+
+// build assembles the build context and builds the image unless an image
+// with the same content hash already exists. It reports whether the image
+// was built rather than reused.
+func (image testImage) build() (string, bool, error) {
+	projectRoot, err := filepath.Abs(filepath.Join(".."))
+	if err != nil {
+		return "", false, fmt.Errorf("determine project root: %w", err)
+	}
+
+	contextDir, err := os.MkdirTemp("", "test-image-*")
+	if err != nil {
+		return "", false, fmt.Errorf("create build context: %w", err)
+	}
+	defer os.RemoveAll(contextDir)
+
+	// Directories are copied before the files placed inside them
+	dests := make([]string, 0, len(image.Files))
+	for dest := range image.Files {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+	for _, dest := range dests {
+		if err := copyDir(filepath.Join(projectRoot, image.Files[dest]), filepath.Join(contextDir, dest)); err != nil {
+			return "", false, fmt.Errorf("copy %s: %w", image.Files[dest], err)
+		}
+	}
+
+	contextHash, err := hashDir(contextDir)
+	if err != nil {
+		return "", false, fmt.Errorf("hash build context: %w", err)
+	}
+	version := alpineVersion()
+	baseID, err := baseImageID("alpine:" + version)
+	if err != nil {
+		return "", false, err
+	}
+	hash := imageHash(contextHash, baseID)
+	tag := fmt.Sprintf("%s:%s-%s", image.Name, version, hash[:12])
+
+	if exec.Command("docker", "image", "inspect", tag).Run() == nil {
+		return tag, false, nil
+	}
+
+	cmd := exec.Command(
+		"docker", "build",
+		"--build-arg", "ALPINE_VERSION="+version,
+		"--label", imageLabel+"="+version,
+		"-t", tag,
+		".",
+	)
+	cmd.Dir = contextDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", false, fmt.Errorf("docker build: %w\n%s", err, output)
+	}
+	return tag, true, nil
+}
+
+// buildImages builds every test image for the current ALPINE_VERSION and
+// writes a line per image to w: "built <tag>", or "reused <tag>" when the
+// image already existed. cmd/testmatrix builds the images this way once per
+// version before running the scenarios, and only removes the built ones.
+func buildImages(w io.Writer) error {
+	for _, image := range testImages {
+		tag, built, err := image.build()
+		if err != nil {
+			return fmt.Errorf("build %s: %w", image.Name, err)
+		}
+		status := "reused"
+		if built {
+			status = "built"
+		}
+		fmt.Fprintf(w, "%s %s\n", status, tag)
+	}
+	return nil
+}
+
+// baseImageID returns the ID of a base image, pulling it if it is missing.
+// The build uses the same local image, so a pulled update changes the tag.
+func baseImageID(base string) (string, error) {
+	inspect := func() (string, error) {
+		output, err := exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", base).Output()
+		return strings.TrimSpace(string(output)), err
+	}
+
+	if id, err := inspect(); err == nil {
+		return id, nil
+	}
+	if output, err := exec.Command("docker", "pull", base).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pull %s: %w\n%s", base, err, output)
+	}
+	id, err := inspect()
+	if err != nil {
+		return "", fmt.Errorf("inspect %s: %w", base, err)
+	}
+	return id, nil
+}
+
+// imageHash combines the build context hash with the base image ID
+func imageHash(contextHash, baseID string) string {
+	sum := sha256.Sum256([]byte(contextHash + " " + baseID))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashDir hashes the paths, modes, sizes and contents of every file below dir
+func hashDir(dir string) (string, error) {
+	hash := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s %o %d\n", filepath.ToSlash(rel), info.Mode().Perm(), info.Size())
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(hash, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// removeBuiltImages removes the images used in this run
+func removeBuiltImages() error {
+	builtImagesMu.Lock()
+	defer builtImagesMu.Unlock()
+
+	var tags []string
+	for _, built := range builtImages {
+		if built.err == nil && built.tag != "" {
+			tags = append(tags, built.tag)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	output, err := exec.Command("docker", append([]string{"rmi", "-f"}, tags...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("remove images %s: %w\n%s", strings.Join(tags, ", "), err, output)
+	}
+	return nil
+}
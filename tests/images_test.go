@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestImageContextHash checks that image tags change only with the build context and base image
+func TestImageContextHash(t *testing.T) {
+	write := func(dir, name, content string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	for _, dir := range []string{first, second} {
+		write(dir, "Dockerfile", "FROM alpine\n")
+		write(dir, "scripts/docker-entrypoint.sh", "#!/bin/sh\n")
+	}
+
+	firstHash, err := hashDir(first)
+	require.NoError(t, err)
+	secondHash, err := hashDir(second)
+	require.NoError(t, err)
+	assert.Equal(t, firstHash, secondHash, "Identical contexts should share a tag")
+
+	write(second, "scripts/docker-entrypoint.sh", "#!/bin/sh\necho changed\n")
+	changedHash, err := hashDir(second)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstHash, changedHash, "A changed script should rebuild the image")
+
+	// Moving content between files must change the hash too
+	write(second, "scripts/docker-entrypoint.sh", "")
+	write(second, "scripts/other.sh", "#!/bin/sh\n")
+	movedHash, err := hashDir(second)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstHash, movedHash)
+
+	// A new base image must rebuild an unchanged context
+	oldBase := imageHash(firstHash, "sha256:1111")
+	assert.Equal(t, oldBase, imageHash(firstHash, "sha256:1111"), "The same base should share a tag")
+	assert.NotEqual(t, oldBase, imageHash(firstHash, "sha256:2222"), "A pulled base image should rebuild the image")
+}
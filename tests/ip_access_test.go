@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// IPAccessTestSuite encapsulates test options and clients
+type IPAccessTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *IPAccessTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with per-user allow and deny lists
+	// Docker publishes ports through the bridge gateway, so the harness
+	// always connects from one of the private ranges
+	bridge := []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"partner":  "Jk3vQm8XpL2w", // Bridge subnet allowed
+			"blocked":  "Rt6nWz4HcY9s", // Bridge subnet denied
+			"outsider": "Eb7uKd2MfQ5v", // Only allowed from a foreign subnet
+		},
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{
+				// Connections from inside the container are dropped before the banner
+				"deny_cidrs": []string{"198.51.100.0/24", "127.0.0.0/8"},
+			}},
+			Users: []UserConfig{
+				{Username: "partner", Extra: map[string]any{"allow_cidrs": bridge}},
+				{Username: "blocked", Extra: map[string]any{"deny_cidrs": bridge}},
+				{Username: "outsider", Extra: map[string]any{"allow_cidrs": []string{"203.0.113.0/24"}}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// TestAllowedSubnet checks that a user inside its allow list can transfer files
+func (suite *IPAccessTestSuite) TestAllowedSubnet(t *testing.T) {
+	client := suite.clients["partner"]
+
+	content := []byte("partner upload")
+	require.NoError(t, client.Store("partner.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("partner.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+
+	require.NoError(t, client.Delete("partner.txt"))
+}
+
+// TestDeniedSubnet checks that a user whose deny list matches is rejected
+func (suite *IPAccessTestSuite) TestDeniedSubnet(t *testing.T) {
+	client := suite.clients["blocked"]
+
+	_, err := client.ReadDir("/")
+	assert.Error(t, err, "Login from a denied subnet should be rejected")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Rejected login for 'blocked'", "Rejection should be logged")
+	assert.Contains(t, logs, "matches deny rule", "Rejection reason should be logged")
+}
+
+// TestOutsideAllowList checks that a user is rejected from addresses outside its allow list
+func (suite *IPAccessTestSuite) TestOutsideAllowList(t *testing.T) {
+	client := suite.clients["outsider"]
+
+	_, err := client.ReadDir("/")
+	assert.Error(t, err, "Login from outside the allow list should be rejected")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Rejected login for 'outsider'", "Rejection should be logged")
+	assert.Contains(t, logs, "not in the allow list", "Rejection reason should be logged")
+}
+
+// TestRejectedBeforeGreeting checks that a denied address is disconnected without a 220 reply
+func (suite *IPAccessTestSuite) TestRejectedBeforeGreeting(t *testing.T) {
+	output, err := suite.env.Exec("ftp", "bash", "-c", "exec 3<>/dev/tcp/127.0.0.1/21 && timeout 5 head -c 3 <&3")
+	require.NoError(t, err, "Connecting from inside the container should succeed: %s", output)
+	assert.NotContains(t, output, "220", "A denied address should never see the greeting")
+
+	logs := stripAnsiCodes(suite.env.Logs(t, "ftp"))
+	assert.Contains(t, logs, "Rejected connection from 127.0.0.1: address matches deny rule 127.0.0.0/8")
+}
+
+// Main test runner
+func TestIPAccessTestSuite(t *testing.T) {
+	suite := &IPAccessTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestAllowedSubnet", suite.TestAllowedSubnet)
+	t.Run("TestDeniedSubnet", suite.TestDeniedSubnet)
+	t.Run("TestOutsideAllowList", suite.TestOutsideAllowList)
+	t.Run("TestRejectedBeforeGreeting", suite.TestRejectedBeforeGreeting)
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// LdapTestSuite encapsulates test options and clients
+type LdapTestSuite struct {
+	opts      TestOptions
+	clients   map[string]*goftp.Client
+	env       *ComposeEnv
+	container string
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *LdapTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with one LDAP user and one config user
+	config := "config-ldap.yaml"
+	suite.opts = TestOptions{
+		ComposeFile: "docker-compose.ldap.yaml",
+		ConfigFile:  &config,
+		UseSSL:      false,
+		Address:     "127.0.0.1",
+		Users: map[string]string{
+			"carol": "Tz6pWq3NvK8d",
+			"local": "Rc4nVx7TpJ2g",
+		},
+		UserMode: "virtual",
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// login opens a new session and returns the error of the first command
+func (suite *LdapTestSuite) login(t *testing.T, username, password string) error {
+	config := goftp.Config{
+		User:     username,
+		Password: password,
+		Timeout:  10 * time.Second,
+	}
+	client, err := goftp.DialConfig(config, fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.ReadDir("/")
+	return err
+}
+
+// TestHomeCreatedOnFirstLogin checks that LDAP homes appear only once the user logs in
+func (suite *LdapTestSuite) TestHomeCreatedOnFirstLogin(t *testing.T) {
+	_, err := ExecCommandInContainer(t, suite.container, []string{"test", "-d", "/ftp/carol@example.org"})
+	require.Error(t, err, "The home should not exist before the first login")
+
+	_, err = suite.clients["carol"].ReadDir("/")
+	require.NoError(t, err, "carol should log in with her LDAP password")
+
+	output, err := ExecCommandInContainer(t, suite.container, []string{"stat", "-c", "%U", "/ftp/carol@example.org"})
+	require.NoError(t, err, "The home should be named after the mail attribute")
+	assert.Equal(t, "vftp\n", output, "The home should belong to the service account")
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"getfacl", "-p", "/ftp/carol@example.org"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "\nuser:staff:rwx\n", "The admin should be able to write to the new home")
+	assert.Contains(t, output, "\ndefault:user:staff:rwx\n", "The admin should be able to write to new files")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Created home /ftp/carol@example.org for LDAP user 'carol'")
+}
+
+// TestFileOperations checks that LDAP users can upload and download
+func (suite *LdapTestSuite) TestFileOperations(t *testing.T) {
+	client := suite.clients["carol"]
+
+	content := []byte("stored by an LDAP user")
+	require.NoError(t, client.Store("report.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("report.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+}
+
+// TestRejectedLogins checks wrong passwords, unknown users and the group filter
+func (suite *LdapTestSuite) TestRejectedLogins(t *testing.T) {
+	assert.Error(t, suite.login(t, "carol", "not-the-password"), "A wrong password should be rejected")
+	assert.Error(t, suite.login(t, "mallory", "Tz6pWq3NvK8d"), "Users missing from LDAP should be rejected")
+	assert.Error(t, suite.login(t, "carol*", "Tz6pWq3NvK8d"), "Filter wildcards in usernames should not match")
+	assert.Error(t, suite.login(t, "dave", "Gm9bLs4XwC7k"), "Users outside the group filter should be rejected")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "LDAP login failed for 'dave': not in the allowed group")
+
+	_, err := ExecCommandInContainer(t, suite.container, []string{"test", "-d", "/ftp/dave@example.org"})
+	assert.Error(t, err, "Rejected users should not get a home")
+}
+
+// TestHomeCollision checks that LDAP users whose homes share a name do not share a home
+func (suite *LdapTestSuite) TestHomeCollision(t *testing.T) {
+	require.NoError(t, suite.login(t, "erin", "Wd5kHr8NcQ3x"), "The first user should get the home")
+	assert.Error(t, suite.login(t, "frank", "Jp2vBx6TmS9f"), "A second user with the same home should be rejected")
+	assert.NoError(t, suite.login(t, "erin", "Wd5kHr8NcQ3x"), "The first user should keep the home")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "LDAP login failed for 'frank': home 'ops@example.org' belongs to LDAP user 'erin'")
+}
+
+// TestConfigUserFallback checks that config users still log in without the directory
+func (suite *LdapTestSuite) TestConfigUserFallback(t *testing.T) {
+	require.NoError(t, suite.login(t, "local", "Rc4nVx7TpJ2g"), "Config users should log in alongside LDAP")
+
+	suite.env.Stop(t, "ldap")
+
+	assert.NoError(t, suite.login(t, "local", "Rc4nVx7TpJ2g"), "Config users should log in while LDAP is down")
+	assert.Error(t, suite.login(t, "carol", "Tz6pWq3NvK8d"), "LDAP users cannot log in while LDAP is down")
+}
+
+// Main test runner
+func TestLdapTestSuite(t *testing.T) {
+	suite := &LdapTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestHomeCreatedOnFirstLogin", suite.TestHomeCreatedOnFirstLogin)
+	t.Run("TestFileOperations", suite.TestFileOperations)
+	t.Run("TestRejectedLogins", suite.TestRejectedLogins)
+	t.Run("TestHomeCollision", suite.TestHomeCollision)
+	t.Run("TestConfigUserFallback", suite.TestConfigUserFallback)
+}
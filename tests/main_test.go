@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// keepImages leaves the test images in place so the next run can skip the build
+var keepImages = flag.Bool("keep-images", false, "keep the test images after the run so later runs reuse them")
+
+// onlyBuildImages builds the test images and exits without running any test
+var onlyBuildImages = flag.Bool("build-images", false, "only build the test images for ALPINE_VERSION and print their tags")
+
+// TestMain removes the images built for this run once every suite has finished.
+// Images are built lazily by testImage.Tag, so tests that need no containers
+// never touch Docker.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *onlyBuildImages {
+		if err := buildImages(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	code := m.Run()
+
+	if !*keepImages {
+		if err := removeBuiltImages(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	os.Exit(code)
+}
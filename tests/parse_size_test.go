@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ParseSizeTestSuite encapsulates shared environment for size and rate script tests
+type ParseSizeTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// SetupSuite prepares the environment before tests run
+func (suite *ParseSizeTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: Human-readable sizes
+func (suite *ParseSizeTestSuite) TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"1024", "1024", true},
+		{"500KB", "500000", true},
+		{"5MiB", "5242880", true},
+		{"5M", "5242880", true},
+		{"1.5GiB", "1610612736", true},
+		{"10gib", "10737418240", true},
+		{"2TB", "2000000000000", true},
+
+		// Invalid sizes
+		{"fast", "", false},
+		{"10XB", "", false},
+		{"-5MiB", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			cmd := []string{"parse_size", test.input}
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+
+			if test.valid {
+				require.NoError(t, err, "Expected size to parse")
+				assert.Equal(t, test.expected+"\n", output)
+			} else {
+				assert.Error(t, err, "Expected size to be rejected")
+			}
+		})
+	}
+}
+
+// Test 2: Rate limits are written per user and direction
+func (suite *ParseSizeTestSuite) TestSetRateLimit(t *testing.T) {
+	cmd := []string{"sh", "-c", "RATE_DIR=/tmp/rates set_rate_limit uploader '5MiB/s' '' && cat /tmp/rates/uploader"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set rate limit")
+	assert.Contains(t, output, "5242880 0", "A direction without a rate stays unlimited")
+
+	// Upload and download rates may differ
+	cmd = []string{"sh", "-c", "RATE_DIR=/tmp/rates set_rate_limit split '2MiB/s' '1048576' && cat /tmp/rates/split"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set rate limit")
+	assert.Contains(t, output, "2097152 1048576")
+
+	// Setting the rate again replaces the previous value
+	cmd = []string{"sh", "-c", "RATE_DIR=/tmp/rates set_rate_limit split '' '512KiB/s' && cat /tmp/rates/split"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set rate limit")
+	assert.Contains(t, output, "0 524288")
+	assert.NotContains(t, output, "2097152")
+
+	// Removing both rates removes the file
+	cmd = []string{"sh", "-c", "RATE_DIR=/tmp/rates set_rate_limit split '' '' && test ! -e /tmp/rates/split"}
+	_, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Rates should be removed when none are set")
+
+	// Invalid rates name the user and field
+	cmd = []string{"sh", "-c", "RATE_DIR=/tmp/rates set_rate_limit slow '' 'quick'"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.Error(t, err, "Expected error due to invalid rate")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid max_download_rate 'quick' for user 'slow'")
+}
+
+// Main test runner
+func TestParseSizeTestSuite(t *testing.T) {
+	suite := &ParseSizeTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestParseSize", suite.TestParseSize)
+	t.Run("TestSetRateLimit", suite.TestSetRateLimit)
+}
@@ -25,17 +25,9 @@ func (suite *ParseYamlTestSuite) createConfigFile(t *testing.T, content string)
 	safeName := strings.ReplaceAll(t.Name(), "/", "_")
 	filePath := fmt.Sprintf("/tmp/config-%s.yaml", safeName)
 
-	cmd := []string{
-		"sh", "-c", fmt.Sprintf("echo '%s' | tee %s", content, filePath),
-	}
-	_, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	err := suite.env.WriteFile(filePath, []byte(content), 0644)
 	require.NoError(t, err, "Failed to create config file")
 
-	// Ensure file permissions are correct
-	cmd = []string{"sh", "-c", fmt.Sprintf("chmod 644 %s", filePath)}
-	_, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
-	require.NoError(t, err, "Failed to set permissions")
-
 	return filePath
 }
 // Test 1: Valid YAML config
@@ -43,15 +35,85 @@ func (suite *ParseYamlTestSuite) TestValidConfig(t *testing.T) {
 	config := `
 server:
   address: "127.0.0.1"
+  address_discovery_url: "http://ip.example.com"
+  address_refresh: 120
+  internal_address: "192.168.1.10"
+  internal_cidrs:
+    - "192.168.0.0/16"
+    - "10.0.0.0/8"
   min_port: 21000
   max_port: 21010
+  listen: "dual"
+  active_mode: "disabled"
+  allow_fxp: true
+  active_source_port: 2020
+  listen_address: "::"
   tls_cert: "/etc/ftp/cert.pem"
   tls_key: "/etc/ftp/key.pem"
+  allow_cidrs:
+    - "10.0.0.0/8"
+    - "192.168.0.0/16"
+  deny_cidrs:
+    - "10.9.0.0/16"
+  max_clients: 50
+  max_per_ip: 5
+  idle_timeout: 600
+  data_timeout: 120
+  max_user_sessions: 2
+  max_upload_rate: "10MiB/s"
+  max_download_rate: "20MiB/s"
+  user_mode: "virtual"
+  virtual_account: "ftpusers"
+  anonymous:
+    enabled: true
+    root: "/srv/ftp"
+    incoming_dir: "incoming"
+    max_rate: "1MiB/s"
+auth:
+  ldap:
+    url: "ldap://ldap.example.com"
+    bind_dn: "cn=reader,dc=example,dc=com"
+    bind_password_env: "LDAP_BIND_PASS"
+    base_dn: "dc=example,dc=com"
+    user_filter: "(uid=%u)"
+    group_filter: "(&(cn=ftp)(member=%d))"
+    home_attribute: "mail"
+  vault:
+    address: "https://vault.example.com:8200"
+    token_env: "VAULT_TOKEN_VAR"
+    role_id: "ftp-role"
+    secret_id_env: "VAULT_SECRET_VAR"
+    approle_path: "approle-ftp"
+    refresh: 60
+  age:
+    key_file: "/run/secrets/age.key"
 users:
   - username: "user1"
     password_env: "USER1_PASS"
+    password_vault:
+      path: "secret/data/ftp/user1"
+      field: "ftp_password"
+    allow_cidrs:
+      - "10.1.0.0/16"
   - username: "user2"
     password_env: "USER2_PASS"
+    deny_cidrs:
+      - "192.168.7.0/24"
+    max_sessions: 1
+    max_upload_rate: "5MiB/s"
+    quota: "10GiB"
+    admin: true
+    read_only: true
+    service_account: "partners"
+    password_age: "age-ciphertext"
+groups:
+  - name: "team"
+    gid: 2500
+    directory: "shared"
+    quota: "5GiB"
+    members:
+      - "user1"
+      - "user2"
 `
 
 	configPath := suite.createConfigFile(t, config)
@@ -64,15 +126,80 @@ users:
 	// Validate the output
 	assert.Contains(t, output, "CONFIG_FILE_DETECTED=1")
 	assert.Contains(t, output, "YAML_ADDRESS='127.0.0.1'")
+	assert.Contains(t, output, "YAML_ADDRESS_DISCOVERY_URL='http://ip.example.com'")
+	assert.Contains(t, output, "YAML_ADDRESS_REFRESH='120'")
+	assert.Contains(t, output, "YAML_INTERNAL_ADDRESS='192.168.1.10'")
+	assert.Contains(t, output, "YAML_INTERNAL_CIDRS='192.168.0.0/16 10.0.0.0/8'")
+	assert.Contains(t, output, "YAML_LISTEN='dual'")
+	assert.Contains(t, output, "YAML_ACTIVE_MODE='disabled'")
+	assert.Contains(t, output, "YAML_ALLOW_FXP='true'")
+	assert.Contains(t, output, "YAML_ACTIVE_SOURCE_PORT='2020'")
+	assert.Contains(t, output, "YAML_LISTEN_ADDRESS='::'")
 	assert.Contains(t, output, "YAML_MIN_PORT='21000'")
 	assert.Contains(t, output, "YAML_MAX_PORT='21010'")
 	assert.Contains(t, output, "YAML_TLS_CERT='/etc/ftp/cert.pem'")
 	assert.Contains(t, output, "YAML_TLS_KEY='/etc/ftp/key.pem'")
+	assert.Contains(t, output, "YAML_ALLOW_CIDRS='10.0.0.0/8 192.168.0.0/16'")
+	assert.Contains(t, output, "YAML_DENY_CIDRS='10.9.0.0/16'")
+	assert.Contains(t, output, "YAML_MAX_CLIENTS='50'")
+	assert.Contains(t, output, "YAML_MAX_PER_IP='5'")
+	assert.Contains(t, output, "YAML_IDLE_TIMEOUT='600'")
+	assert.Contains(t, output, "YAML_DATA_TIMEOUT='120'")
+	assert.Contains(t, output, "YAML_MAX_USER_SESSIONS='2'")
+	assert.Contains(t, output, "YAML_MAX_UPLOAD_RATE='10MiB/s'")
+	assert.Contains(t, output, "YAML_MAX_DOWNLOAD_RATE='20MiB/s'")
+	assert.Contains(t, output, "YAML_ANON_ENABLED='true'")
+	assert.Contains(t, output, "YAML_ANON_ROOT='/srv/ftp'")
+	assert.Contains(t, output, "YAML_ANON_INCOMING_DIR='incoming'")
+	assert.Contains(t, output, "YAML_ANON_MAX_RATE='1MiB/s'")
+	assert.Contains(t, output, "YAML_USER_MODE='virtual'")
+	assert.Contains(t, output, "YAML_VIRTUAL_ACCOUNT='ftpusers'")
+	assert.Contains(t, output, "YAML_LDAP_URL='ldap://ldap.example.com'")
+	assert.Contains(t, output, "YAML_LDAP_BIND_DN='cn=reader,dc=example,dc=com'")
+	assert.Contains(t, output, "YAML_LDAP_BIND_PASSWORD_ENV='LDAP_BIND_PASS'")
+	assert.Contains(t, output, "YAML_LDAP_BASE_DN='dc=example,dc=com'")
+	assert.Contains(t, output, "YAML_LDAP_USER_FILTER='(uid=%u)'")
+	assert.Contains(t, output, "YAML_LDAP_GROUP_FILTER='(&(cn=ftp)(member=%d))'")
+	assert.Contains(t, output, "YAML_LDAP_HOME_ATTRIBUTE='mail'")
+	assert.Contains(t, output, "YAML_VAULT_ADDRESS='https://vault.example.com:8200'")
+	assert.Contains(t, output, "YAML_VAULT_TOKEN_ENV='VAULT_TOKEN_VAR'")
+	assert.Contains(t, output, "YAML_VAULT_ROLE_ID='ftp-role'")
+	assert.Contains(t, output, "YAML_VAULT_SECRET_ID_ENV='VAULT_SECRET_VAR'")
+	assert.Contains(t, output, "YAML_VAULT_APPROLE_PATH='approle-ftp'")
+	assert.Contains(t, output, "YAML_VAULT_REFRESH='60'")
+	assert.Contains(t, output, "YAML_AGE_KEY_FILE='/run/secrets/age.key'")
 	assert.Contains(t, output, "YAML_USER_COUNT=2")
 	assert.Contains(t, output, "YAML_USER_0_NAME='user1'")
 	assert.Contains(t, output, "YAML_USER_0_PASS_ENV='USER1_PASS'")
 	assert.Contains(t, output, "YAML_USER_1_NAME='user2'")
 	assert.Contains(t, output, "YAML_USER_1_PASS_ENV='USER2_PASS'")
+	assert.Contains(t, output, "YAML_USER_0_ALLOW_CIDRS='10.1.0.0/16'")
+	assert.Contains(t, output, "YAML_USER_0_DENY_CIDRS=''")
+	assert.Contains(t, output, "YAML_USER_1_ALLOW_CIDRS=''")
+	assert.Contains(t, output, "YAML_USER_1_DENY_CIDRS='192.168.7.0/24'")
+	assert.Contains(t, output, "YAML_USER_0_MAX_SESSIONS=''")
+	assert.Contains(t, output, "YAML_USER_1_MAX_SESSIONS='1'")
+	assert.Contains(t, output, "YAML_USER_1_MAX_UPLOAD_RATE='5MiB/s'")
+	assert.Contains(t, output, "YAML_USER_1_MAX_DOWNLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_USER_0_QUOTA=''")
+	assert.Contains(t, output, "YAML_USER_1_QUOTA='10GiB'")
+	assert.Contains(t, output, "YAML_USER_0_ADMIN=''")
+	assert.Contains(t, output, "YAML_USER_1_ADMIN='true'")
+	assert.Contains(t, output, "YAML_USER_1_READ_ONLY='true'")
+	assert.Contains(t, output, "YAML_USER_0_SERVICE_ACCOUNT=''")
+	assert.Contains(t, output, "YAML_USER_1_SERVICE_ACCOUNT='partners'")
+	assert.Contains(t, output, "YAML_USER_0_VAULT_PATH='secret/data/ftp/user1'")
+	assert.Contains(t, output, "YAML_USER_0_VAULT_FIELD='ftp_password'")
+	assert.Contains(t, output, "YAML_USER_1_VAULT_PATH=''")
+	assert.Contains(t, output, "YAML_USER_1_VAULT_FIELD='password'")
+	assert.Contains(t, output, "YAML_USER_0_PASSWORD_AGE=''")
+	assert.Contains(t, output, "YAML_USER_1_PASSWORD_AGE='age-ciphertext'")
+	assert.Contains(t, output, "YAML_GROUP_COUNT=1")
+	assert.Contains(t, output, "YAML_GROUP_0_NAME='team'")
+	assert.Contains(t, output, "YAML_GROUP_0_GID='2500'")
+	assert.Contains(t, output, "YAML_GROUP_0_DIRECTORY='shared'")
+	assert.Contains(t, output, "YAML_GROUP_0_MEMBERS='user1 user2'")
+	assert.Contains(t, output, "YAML_GROUP_0_QUOTA='5GiB'")
 }
 
 // Test 2: Empty YAML config
@@ -86,11 +213,31 @@ func (suite *ParseYamlTestSuite) TestEmptyConfig(t *testing.T) {
 
 	assert.Contains(t, output, "CONFIG_FILE_DETECTED=1")
 	assert.Contains(t, output, "YAML_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_CIDRS=''")
+	assert.Contains(t, output, "YAML_LISTEN=''")
+	assert.Contains(t, output, "YAML_ACTIVE_MODE=''")
+	assert.Contains(t, output, "YAML_LISTEN_ADDRESS=''")
 	assert.Contains(t, output, "YAML_MIN_PORT=''")
 	assert.Contains(t, output, "YAML_MAX_PORT=''")
 	assert.Contains(t, output, "YAML_TLS_CERT=''")
 	assert.Contains(t, output, "YAML_TLS_KEY=''")
+	assert.Contains(t, output, "YAML_ALLOW_CIDRS=''")
+	assert.Contains(t, output, "YAML_DENY_CIDRS=''")
+	assert.Contains(t, output, "YAML_MAX_CLIENTS=''")
+	assert.Contains(t, output, "YAML_MAX_PER_IP=''")
+	assert.Contains(t, output, "YAML_IDLE_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_DATA_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_MAX_USER_SESSIONS=''")
+	assert.Contains(t, output, "YAML_MAX_UPLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_MAX_DOWNLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_ANON_ENABLED=''")
+	assert.Contains(t, output, "YAML_USER_MODE=''")
+	assert.Contains(t, output, "YAML_LDAP_URL=''")
+	assert.Contains(t, output, "YAML_VAULT_ADDRESS=''")
+	assert.Contains(t, output, "YAML_AGE_KEY_FILE=''")
 	assert.Contains(t, output, "YAML_USER_COUNT=0")
+	assert.Contains(t, output, "YAML_GROUP_COUNT=0")
 }
 
 // Test 3: Missing Config File
@@ -101,11 +248,31 @@ func (suite *ParseYamlTestSuite) TestMissingConfig(t *testing.T) {
 
 	assert.Contains(t, output, "CONFIG_FILE_DETECTED=0")
 	assert.Contains(t, output, "YAML_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_CIDRS=''")
+	assert.Contains(t, output, "YAML_LISTEN=''")
+	assert.Contains(t, output, "YAML_ACTIVE_MODE=''")
+	assert.Contains(t, output, "YAML_LISTEN_ADDRESS=''")
 	assert.Contains(t, output, "YAML_MIN_PORT=''")
 	assert.Contains(t, output, "YAML_MAX_PORT=''")
 	assert.Contains(t, output, "YAML_TLS_CERT=''")
 	assert.Contains(t, output, "YAML_TLS_KEY=''")
+	assert.Contains(t, output, "YAML_ALLOW_CIDRS=''")
+	assert.Contains(t, output, "YAML_DENY_CIDRS=''")
+	assert.Contains(t, output, "YAML_MAX_CLIENTS=''")
+	assert.Contains(t, output, "YAML_MAX_PER_IP=''")
+	assert.Contains(t, output, "YAML_IDLE_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_DATA_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_MAX_USER_SESSIONS=''")
+	assert.Contains(t, output, "YAML_MAX_UPLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_MAX_DOWNLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_ANON_ENABLED=''")
+	assert.Contains(t, output, "YAML_USER_MODE=''")
+	assert.Contains(t, output, "YAML_LDAP_URL=''")
+	assert.Contains(t, output, "YAML_VAULT_ADDRESS=''")
+	assert.Contains(t, output, "YAML_AGE_KEY_FILE=''")
 	assert.Contains(t, output, "YAML_USER_COUNT=0")
+	assert.Contains(t, output, "YAML_GROUP_COUNT=0")
 }
 
 // Test 4: Invalid YAML Format
@@ -128,11 +295,31 @@ users:
 	// Invalid YAML should be treated as empty
 	assert.Contains(t, output, "CONFIG_FILE_DETECTED=1")
 	assert.Contains(t, output, "YAML_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_ADDRESS=''")
+	assert.Contains(t, output, "YAML_INTERNAL_CIDRS=''")
+	assert.Contains(t, output, "YAML_LISTEN=''")
+	assert.Contains(t, output, "YAML_ACTIVE_MODE=''")
+	assert.Contains(t, output, "YAML_LISTEN_ADDRESS=''")
 	assert.Contains(t, output, "YAML_MIN_PORT=''")
 	assert.Contains(t, output, "YAML_MAX_PORT=''")
 	assert.Contains(t, output, "YAML_TLS_CERT=''")
 	assert.Contains(t, output, "YAML_TLS_KEY=''")
+	assert.Contains(t, output, "YAML_ALLOW_CIDRS=''")
+	assert.Contains(t, output, "YAML_DENY_CIDRS=''")
+	assert.Contains(t, output, "YAML_MAX_CLIENTS=''")
+	assert.Contains(t, output, "YAML_MAX_PER_IP=''")
+	assert.Contains(t, output, "YAML_IDLE_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_DATA_TIMEOUT=''")
+	assert.Contains(t, output, "YAML_MAX_USER_SESSIONS=''")
+	assert.Contains(t, output, "YAML_MAX_UPLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_MAX_DOWNLOAD_RATE=''")
+	assert.Contains(t, output, "YAML_ANON_ENABLED=''")
+	assert.Contains(t, output, "YAML_USER_MODE=''")
+	assert.Contains(t, output, "YAML_LDAP_URL=''")
+	assert.Contains(t, output, "YAML_VAULT_ADDRESS=''")
+	assert.Contains(t, output, "YAML_AGE_KEY_FILE=''")
 	assert.Contains(t, output, "YAML_USER_COUNT=0")
+	assert.Contains(t, output, "YAML_GROUP_COUNT=0")
 }
 
 // Main test runner
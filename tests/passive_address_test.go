@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PassiveAddressTestSuite encapsulates test options and clients
+type PassiveAddressTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *PassiveAddressTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with separate internal and external passive addresses
+	config := "config-pasv-address.yaml"
+	suite.opts = TestOptions{
+		ComposeFile: "docker-compose.pasv-address.yaml",
+		ConfigFile:  &config,
+		UseSSL:      false,
+		Address:     "127.0.0.1",
+		Users: map[string]string{
+			"roamer": "Wm5rBx8KtF2n",
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// login opens a raw control connection as username and returns a function
+// that sends a command and reads its reply
+func (suite *PassiveAddressTestSuite) login(t *testing.T, username string) func(string) string {
+	address := net.JoinHostPort(suite.opts.Address, strconv.Itoa(suite.opts.Port))
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	require.NoError(t, err, "Failed to open control connection")
+	t.Cleanup(func() { conn.Close() })
+
+	reader := bufio.NewReader(conn)
+	send := func(command string) string {
+		if command != "" {
+			_, err := fmt.Fprintf(conn, "%s\r\n", command)
+			require.NoError(t, err, "Failed to send %s", strings.Fields(command)[0])
+		}
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err, "Failed to read reply")
+		return line
+	}
+
+	require.True(t, strings.HasPrefix(send(""), "220"), "Expected greeting")
+	require.True(t, strings.HasPrefix(send("USER "+username), "331"), "Expected password prompt")
+	require.True(t, strings.HasPrefix(send("PASS "+suite.opts.Users[username]), "230"), "Expected login")
+	return send
+}
+
+// TestInternalMapping checks that clients from internal ranges get the internal address
+func (suite *PassiveAddressTestSuite) TestInternalMapping(t *testing.T) {
+	reply := suite.login(t, "roamer")("PASV")
+	assert.Contains(t, reply, "(127,0,0,1,", "Internal clients should be sent the internal address")
+
+	// Transfers work because the internal address is reachable from the host
+	client := suite.clients["roamer"]
+	content := []byte("internal passive transfer")
+	require.NoError(t, client.Store("internal.txt", bytes.NewReader(content)))
+
+	var buf bytes.Buffer
+	require.NoError(t, client.Retrieve("internal.txt", &buf))
+	assert.Equal(t, string(content), buf.String())
+	require.NoError(t, client.Delete("internal.txt"))
+}
+
+// TestExternalMapping checks that other clients get the discovered public
+// address, without changing the address of the user's other sessions
+func (suite *PassiveAddressTestSuite) TestExternalMapping(t *testing.T) {
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Passive address for external clients: 203.0.113.10", "auto should use the discovery endpoint")
+	assert.Contains(t, logs, "Passive address for internal clients: 127.0.0.1")
+	assert.Contains(t, logs, "Refreshing passive addresses every 60 seconds")
+
+	internal := suite.login(t, "roamer")
+
+	// The harness cannot connect from outside the bridge, so drop the
+	// internal ranges from the map to make the next login an external one
+	container := suite.env.ContainerName("ftp")
+	cmd := []string{"sh", "-c", "cp /etc/vsftpd/pasv/map /tmp/pasv-map && sed -i '/^0.0.0.0\\/0 /!d' /etc/vsftpd/pasv/map"}
+	_, err := ExecCommandInContainer(t, container, cmd)
+	require.NoError(t, err, "Failed to edit passive address map")
+	t.Cleanup(func() {
+		ExecCommandInContainer(t, container, []string{"mv", "/tmp/pasv-map", "/etc/vsftpd/pasv/map"})
+	})
+
+	external := suite.login(t, "roamer")
+	assert.Contains(t, external("PASV"), "(203,0,113,10,", "External clients should be sent the public address")
+
+	// Sessions of the same user keep the address picked at their own login
+	assert.Contains(t, internal("PASV"), "(127,0,0,1,", "Internal session should keep the internal address")
+}
+
+// Main test runner
+func TestPassiveAddressTestSuite(t *testing.T) {
+	suite := &PassiveAddressTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestInternalMapping", suite.TestInternalMapping)
+	t.Run("TestExternalMapping", suite.TestExternalMapping)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PasvAddressWatchTestSuite encapsulates shared environment for passive address script tests
+type PasvAddressWatchTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// Isolated passive address state so tests do not touch the image defaults
+const pasvTestEnv = "export PASV_DIR=/tmp/pasv/map-dir && "
+
+// SetupSuite prepares the environment before tests run
+func (suite *PasvAddressWatchTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: Addresses, names and auto resolve to IPv4 addresses
+func (suite *PasvAddressWatchTestSuite) TestResolveAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"Literal", "resolve_address 203.0.113.10", "203.0.113.10"},
+		{"HostsFile", "resolve_address localhost", "127.0.0.1"},
+		{"DiscoveryURL", "echo ' 198.51.100.7 ' > /tmp/public-ip && ADDRESS_DISCOVERY_URL=file:///tmp/public-ip resolve_address auto", "198.51.100.7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", test.command})
+			require.NoError(t, err, "Failed to resolve address")
+			assert.Equal(t, test.want+"\n", output)
+		})
+	}
+
+	// Without a discovery endpoint, auto uses the default route interface
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "resolve_address auto"})
+	require.NoError(t, err, "Failed to resolve address from the default route")
+	assert.Regexp(t, `^\d+\.\d+\.\d+\.\d+\n$`, output)
+
+	// Bad values and failed lookups are errors
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "resolve_address 'not a host'"})
+	require.Error(t, err, "Expected error due to invalid address")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid passive address 'not a host'")
+
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", "ADDRESS_DISCOVERY_URL=file:///tmp/missing resolve_address auto"})
+	require.Error(t, err, "Expected error due to failed discovery")
+	assert.Contains(t, stripAnsiCodes(output), "did not return an IPv4 address")
+}
+
+// Test 2: Internal ranges are mapped before the external address
+func (suite *PasvAddressWatchTestSuite) TestAddressMap(t *testing.T) {
+	cmd := []string{"sh", "-c", pasvTestEnv +
+		"ADDRESS=203.0.113.10 INTERNAL_ADDRESS=192.168.1.10 INTERNAL_CIDRS='192.168.0.0/16,10.0.0.0/8' " +
+		"pasv_address_watch --once && cat /tmp/pasv/map-dir/map"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to build passive address map")
+
+	output = stripAnsiCodes(output)
+	assert.Contains(t, output, "Passive address for internal clients: 192.168.1.10")
+	assert.Contains(t, output, "Passive address for external clients: 203.0.113.10")
+	assert.Contains(t, output, "192.168.0.0/16 192.168.1.10\n10.0.0.0/8 192.168.1.10\n0.0.0.0/0 203.0.113.10\n")
+
+	// Invalid internal ranges stop startup
+	cmd = []string{"sh", "-c", pasvTestEnv + "INTERNAL_ADDRESS=192.168.1.10 INTERNAL_CIDRS=lan pasv_address_watch --once"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.Error(t, err, "Expected error due to invalid internal CIDR")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid CIDR 'lan' in internal_cidrs")
+}
+
+// Main test runner
+func TestPasvAddressWatchTestSuite(t *testing.T) {
+	suite := &PasvAddressWatchTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestResolveAddress", suite.TestResolveAddress)
+	t.Run("TestAddressMap", suite.TestAddressMap)
+}
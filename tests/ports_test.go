@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/shawn636/mini-ftp/internal/ftptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test 1: Every port in a picked range can be bound
+func TestFreePortRange(t *testing.T) {
+	minPort, maxPort := freePortRange(t, passivePortCount)
+	assert.Equal(t, passivePortCount-1, maxPort-minPort)
+
+	for port := minPort; port <= maxPort; port++ {
+		listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		require.NoError(t, err, "Port %d should be free", port)
+		listener.Close()
+	}
+}
+
+// Test 2: Bind failures reported by Docker are told apart from other errors
+func TestPortTaken(t *testing.T) {
+	assert.True(t, ftptest.PortTaken("Error response from daemon: driver failed programming external connectivity on endpoint ftp: "+
+		"Bind for 0.0.0.0:21003 failed: port is already allocated"))
+	assert.True(t, ftptest.PortTaken("Error starting userland proxy: listen tcp4 0.0.0.0:21003: bind: address already in use"))
+	assert.False(t, ftptest.PortTaken("Error response from daemon: No such image: mini-ftp-test:missing"))
+}
+
+// packageSource returns a Go file without its package comment
+func packageSource(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "Failed to read %s", path)
+
+	source := string(data)
+	index := strings.Index(source, "\npackage ")
+	require.NotEqual(t, -1, index, "%s has no package clause", path)
+	return source[index:]
+}
+
+// Test 3: The testcontainers module's copy of ftptest matches the original
+func TestFtptestCopy(t *testing.T) {
+	original := packageSource(t, "../internal/ftptest/ftptest.go")
+	copied := packageSource(t, "../testcontainers/miniftp/internal/ftptest/ftptest.go")
+	assert.Equal(t, original, copied, "Copy internal/ftptest/ftptest.go to testcontainers/miniftp/internal/ftptest after changing it")
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// QuotaGuardTestSuite encapsulates shared environment for quota script tests
+type QuotaGuardTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// Isolated quota state so tests do not touch the image defaults
+const quotaTestEnv = "export QUOTA_DIR=/tmp/qg/quotas FTP_ROOT=/tmp/qg/ftp GROUP_ROOT=/tmp/qg/groups " +
+	"USER_CONFIG_DIR=/tmp/qg/users QUOTA_METRICS_FILE=/tmp/qg/metrics.prom && "
+
+// SetupSuite prepares the environment before tests run
+func (suite *QuotaGuardTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: Quotas are validated and stored in bytes
+func (suite *QuotaGuardTestSuite) TestSetQuota(t *testing.T) {
+	cmd := []string{"sh", "-c", quotaTestEnv + "set_quota alice 10GiB && cat /tmp/qg/quotas/alice"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set quota")
+	assert.Contains(t, output, "10737418240")
+
+	// Group quotas never clash with a user of the same name
+	cmd = []string{"sh", "-c", quotaTestEnv + "set_quota --group alice 1GiB && cat /tmp/qg/quotas/.groups/alice /tmp/qg/quotas/alice"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set group quota")
+	assert.Contains(t, output, "1073741824\n10737418240\n")
+
+	cmd = []string{"sh", "-c", quotaTestEnv + "set_quota alice lots"}
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.Error(t, err, "Expected error due to invalid quota")
+	assert.Contains(t, stripAnsiCodes(output), "Invalid quota 'lots' for user 'alice'")
+}
+
+// Test 2: Usage is the sum of file sizes in the user's directory
+func (suite *QuotaGuardTestSuite) TestQuotaUsage(t *testing.T) {
+	cmd := []string{"sh", "-c", quotaTestEnv +
+		"mkdir -p /tmp/qg/ftp/usage/nested && " +
+		"head -c 1000 /dev/zero > /tmp/qg/ftp/usage/a && " +
+		"head -c 234 /dev/zero > /tmp/qg/ftp/usage/nested/b && " +
+		"quota_usage usage && quota_usage missing"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to compute usage")
+	assert.Equal(t, "1234\n0\n", output)
+}
+
+// Test 3: The guard publishes quota scopes and metrics
+func (suite *QuotaGuardTestSuite) TestQuotaGuard(t *testing.T) {
+	setup := quotaTestEnv +
+		"set_quota bob 1KiB >/dev/null && mkdir -p /tmp/qg/ftp/bob && " +
+		"head -c 2048 /dev/zero > /tmp/qg/ftp/bob/big && quota_guard --once"
+	_, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to run quota guard")
+
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, []string{"cat", "/tmp/qg/quotas/.scopes"})
+	require.NoError(t, err, "Expected scopes file to be written")
+	assert.Contains(t, output, "1024 /tmp/qg/ftp/bob\n")
+
+	// Group folders with a quota are listed first, so writes into them are charged to the group
+	setup = quotaTestEnv +
+		"set_quota --group team 3KiB >/dev/null && mkdir -p /tmp/qg/groups/team && " +
+		"head -c 512 /dev/zero > /tmp/qg/groups/team/plan && quota_guard --once && cat /tmp/qg/quotas/.scopes"
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", setup})
+	require.NoError(t, err, "Failed to run quota guard")
+	assert.True(t, strings.HasPrefix(output, "3072 /tmp/qg/groups/team\n"), "Unexpected scopes: %s", output)
+
+	// Sessions are left alone, enforcement happens inside vsftpd
+	_, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"cat", "/tmp/qg/users/bob"})
+	assert.Error(t, err, "Expected no per-user options to be written")
+
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"cat", "/tmp/qg/metrics.prom"})
+	require.NoError(t, err, "Expected metrics file to be written")
+	assert.Contains(t, output, "# TYPE mini_ftp_user_usage_bytes gauge")
+	assert.Contains(t, output, `mini_ftp_user_quota_bytes{user="bob"} 1024`)
+	assert.Contains(t, output, `mini_ftp_user_usage_bytes{user="bob"} 2048`)
+	assert.Contains(t, output, `mini_ftp_group_quota_bytes{group="team"} 3072`)
+	assert.Contains(t, output, `mini_ftp_group_usage_bytes{group="team"} 512`)
+
+	// Scopes are not listed as users
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"sh", "-c", quotaTestEnv + "mini-ftp user quota"})
+	require.NoError(t, err, "Failed to run mini-ftp user quota")
+	assert.NotContains(t, output, ".scopes")
+}
+
+// Test 4: The CLI reports quota and usage
+func (suite *QuotaGuardTestSuite) TestUserQuotaCommand(t *testing.T) {
+	cmd := []string{"sh", "-c", quotaTestEnv +
+		"set_quota carol 1MiB >/dev/null && mkdir -p /tmp/qg/ftp/carol && " +
+		"head -c 524288 /dev/zero > /tmp/qg/ftp/carol/half && mini-ftp user quota carol"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to run mini-ftp user quota")
+	assert.Contains(t, output, "USER")
+	assert.Regexp(t, `carol\s+1048576\s+524288\s+50\.0%`, output)
+
+	// Unknown commands print usage and fail
+	output, err = ExecCommandInContainer(t, suite.env.ContainerName, []string{"mini-ftp", "user", "frobnicate"})
+	require.Error(t, err, "Expected unknown command to fail")
+	assert.Contains(t, output, "Usage: mini-ftp")
+}
+
+// Main test runner
+func TestQuotaGuardTestSuite(t *testing.T) {
+	suite := &QuotaGuardTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestSetQuota", suite.TestSetQuota)
+	t.Run("TestQuotaUsage", suite.TestQuotaUsage)
+	t.Run("TestQuotaGuard", suite.TestQuotaGuard)
+	t.Run("TestUserQuotaCommand", suite.TestUserQuotaCommand)
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// QuotaTestSuite encapsulates test options and clients
+type QuotaTestSuite struct {
+	opts    TestOptions
+	clients map[string]*goftp.Client
+	env     *ComposeEnv
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *QuotaTestSuite) SetupSuite(t testing.TB) {
+	// Define test options with a 1MiB quota for one user
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"limited":   "Zp6cNv3RkW8t", // quota: 1MiB
+			"unlimited": "Gy2hMs7BxD4q",
+		},
+		Env: map[string]string{"QUOTA_INTERVAL": "1"},
+		Config: &FTPConfig{
+			Users: []UserConfig{
+				{Username: "limited", Extra: map[string]any{"quota": "1MiB"}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// freshClient dials a new session so that per-user vsftpd options are reloaded
+func (suite *QuotaTestSuite) freshClient(t *testing.T, username string) *goftp.Client {
+	client := setupFTPClients(t, TestOptions{
+		Address: suite.opts.Address,
+		Port:    suite.opts.Port,
+		Users:   map[string]string{username: suite.opts.Users[username]},
+	})[username]
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// rawStore uploads data on conn and returns the reply that ends the transfer.
+// Unlike Store it does not retry, so the reply to a failed upload is kept.
+func rawStore(t *testing.T, conn goftp.RawConn, name string, data []byte) (int, string) {
+	code, msg, err := conn.SendCommand("TYPE I")
+	require.NoError(t, err)
+	require.Equal(t, 200, code, "Unexpected reply: %s", msg)
+
+	dataConn, err := conn.PrepareDataConn()
+	require.NoError(t, err, "Failed to prepare data connection")
+
+	code, msg, err = conn.SendCommand("STOR %s", name)
+	require.NoError(t, err)
+	if code != 150 {
+		return code, msg
+	}
+
+	socket, err := dataConn()
+	require.NoError(t, err, "Failed to open data connection")
+	// The server may close the connection early when it rejects the upload
+	_, _ = socket.Write(data)
+	socket.Close()
+
+	code, msg, err = conn.ReadResponse()
+	require.NoError(t, err)
+	return code, msg
+}
+
+// TestUploadWithinQuota checks that uploads below the quota succeed
+func (suite *QuotaTestSuite) TestUploadWithinQuota(t *testing.T) {
+	client := suite.clients["limited"]
+	require.NoError(t, client.Store("first.bin", bytes.NewReader(make([]byte, 600*1024))))
+}
+
+// TestUploadPastQuota checks that uploads are stopped with 552 at the quota
+func (suite *QuotaTestSuite) TestUploadPastQuota(t *testing.T) {
+	conn, err := suite.freshClient(t, "limited").OpenRawConn()
+	require.NoError(t, err, "Failed to open raw connection")
+	t.Cleanup(func() { conn.Close() })
+
+	// The upload that would cross the limit is aborted during the transfer
+	code, msg := rawStore(t, conn, "second.bin", make([]byte, 600*1024))
+	assert.Equal(t, 552, code, "Unexpected reply: %s", msg)
+
+	// The session stays open, and the partial file fits within the quota
+	code, msg, err = conn.SendCommand("SIZE second.bin")
+	require.NoError(t, err, "Session should stay open after a rejected upload")
+	require.Equal(t, 213, code, "Unexpected reply: %s", msg)
+	stored, err := strconv.Atoi(strings.TrimSpace(msg))
+	require.NoError(t, err)
+	remaining := 1024*1024 - 600*1024 - stored
+	require.GreaterOrEqual(t, remaining, 0, "Partial upload exceeded the quota")
+
+	// Filling the quota exactly is allowed, anything more is refused up front
+	client := suite.freshClient(t, "limited")
+	require.NoError(t, client.Store("fill.bin", bytes.NewReader(make([]byte, remaining))))
+
+	err = client.Store("third.bin", bytes.NewReader([]byte("over quota")))
+	require.Error(t, err, "Upload past the quota should be rejected")
+
+	var ftpErr goftp.Error
+	require.True(t, errors.As(err, &ftpErr), "Expected an FTP error reply, got: %v", err)
+	assert.Equal(t, 552, ftpErr.Code(), "Unexpected reply: %s", ftpErr.Message())
+
+	// Other users are unaffected
+	unlimited := suite.clients["unlimited"]
+	require.NoError(t, unlimited.Store("big.bin", bytes.NewReader(make([]byte, 2*1024*1024))))
+	require.NoError(t, unlimited.Delete("big.bin"))
+}
+
+// TestQuotaReport checks usage reporting through the CLI and metrics file
+func (suite *QuotaTestSuite) TestQuotaReport(t *testing.T) {
+	container := suite.env.ContainerName("ftp")
+
+	output, err := ExecCommandInContainer(t, container, []string{"mini-ftp", "user", "quota", "limited"})
+	require.NoError(t, err, "Failed to run mini-ftp user quota")
+	assert.Contains(t, output, "limited")
+	assert.Contains(t, output, "1048576")
+	assert.Contains(t, output, "100.0%")
+
+	// The guard refreshes the metrics file every few seconds
+	require.Eventually(t, func() bool {
+		output, err = ExecCommandInContainer(t, container, []string{"cat", "/var/run/mini-ftp/metrics.prom"})
+		return err == nil && strings.Contains(output, `mini_ftp_user_usage_bytes{user="limited"} 1048576`)
+	}, 30*time.Second, time.Second, "Expected usage in metrics file")
+	assert.Contains(t, output, `mini_ftp_user_quota_bytes{user="limited"} 1048576`)
+}
+
+// TestUploadAfterCleanup checks that deleting files frees space immediately
+func (suite *QuotaTestSuite) TestUploadAfterCleanup(t *testing.T) {
+	client := suite.freshClient(t, "limited")
+	require.NoError(t, client.Delete("first.bin"))
+	require.NoError(t, client.Delete("second.bin"))
+	require.NoError(t, client.Delete("fill.bin"))
+
+	require.NoError(t, client.Store("after-cleanup.txt", bytes.NewReader([]byte("room again"))))
+	require.NoError(t, client.Delete("after-cleanup.txt"))
+}
+
+// largeTreeDirs and largeTreeFiles shape the tree created by BenchmarkStoreLargeTree
+const (
+	largeTreeDirs  = 100
+	largeTreeFiles = 200 // Per directory
+)
+
+// BenchmarkStoreLargeTree measures client.Store for a user whose quota scope
+// already holds many files. quota.so caches the scope's usage, so only the
+// first upload of a session walks the tree.
+func BenchmarkStoreLargeTree(b *testing.B) {
+	suite := &QuotaTestSuite{}
+	suite.SetupSuite(b)
+	container := suite.env.ContainerName("ftp")
+
+	// Empty files keep the quota free for the uploads
+	script := fmt.Sprintf(`cd /ftp/limited && mkdir tree && cd tree &&
+for dir in $(seq %d); do mkdir "$dir" && (cd "$dir" && seq %d | xargs touch); done &&
+chown -R "$(stat -c %%u:%%g /ftp/limited)" /ftp/limited/tree`, largeTreeDirs, largeTreeFiles)
+	output, err := ExecCommandInContainer(b, container, []string{"sh", "-c", script})
+	require.NoError(b, err, "Failed to create the tree: %s", output)
+
+	client := suite.clients["limited"]
+	payload := []byte("small upload")
+
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		require.NoError(b, client.Store(fmt.Sprintf("bench-%d.txt", i), bytes.NewReader(payload)))
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(largeTreeDirs*largeTreeFiles), "files")
+}
+
+// Main test runner
+func TestQuotaTestSuite(t *testing.T) {
+	suite := &QuotaTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestUploadWithinQuota", suite.TestUploadWithinQuota)
+	t.Run("TestUploadPastQuota", suite.TestUploadPastQuota)
+	t.Run("TestQuotaReport", suite.TestQuotaReport)
+	t.Run("TestUploadAfterCleanup", suite.TestUploadAfterCleanup)
+}
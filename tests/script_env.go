@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExecResult is the outcome of a command run with ScriptTestEnv.Run
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Run runs a command in the container with extra environment variables.
+// A non-zero exit code is not an error; the error is only set if the
+// command could not be run at all.
+func (env ScriptTestEnv) Run(command []string, vars map[string]string) (ExecResult, error) {
+	args := []string{"exec"}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-e", name+"="+vars[name])
+	}
+	args = append(args, env.ContainerName)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", append(args, command...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	result := ExecResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	return result, err
+}
+
+// WriteFile writes data to a file in the container, creating or replacing
+// it with the given permissions. The parent directory must exist.
+func (env ScriptTestEnv) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	archive, err := tarFile(path.Base(name), data, perm)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	cmd := exec.Command("docker", "cp", "-", env.ContainerName+":"+path.Dir(name))
+	cmd.Stdin = bytes.NewReader(archive)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: copyError(err, output)}
+	}
+	return nil
+}
+
+// ReadFile returns the contents of a file in the container
+func (env ScriptTestEnv) ReadFile(name string) ([]byte, error) {
+	header, data, err := env.copyOut(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("not a regular file")}
+	}
+	return data, nil
+}
+
+// Stat returns the mode, size and modification time of a file or directory
+// in the container. Symlinks are not followed.
+func (env ScriptTestEnv) Stat(name string) (fs.FileInfo, error) {
+	header, _, err := env.copyOut(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return header.FileInfo(), nil
+}
+
+// copyOut streams name out of the container as a tar archive and returns
+// its first entry. Directories are read in full, but only their header is
+// returned.
+func (env ScriptTestEnv) copyOut(name string) (*tar.Header, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", "cp", env.ContainerName+":"+name, "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, nil, copyError(err, stderr.Bytes())
+	}
+	return untarFile(&stdout)
+}
+
+// copyError turns a failed docker cp into fs.ErrNotExist when the path is
+// missing, so callers can use errors.Is
+func copyError(err error, output []byte) error {
+	message := strings.TrimSpace(string(output))
+	if strings.Contains(message, "Could not find the file") || strings.Contains(message, "No such container:path") {
+		return fs.ErrNotExist
+	}
+	return fmt.Errorf("docker cp: %w: %s", err, message)
+}
+
+// tarFile returns a tar archive holding a single file owned by root
+func tarFile(name string, data []byte, perm fs.FileMode) ([]byte, error) {
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(perm.Perm()),
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+		Format:   tar.FormatPAX,
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+// untarFile returns the first entry of a tar archive and its contents
+func untarFile(reader io.Reader) (*tar.Header, []byte, error) {
+	archive := tar.NewReader(reader)
+	header, err := archive.Next()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("empty archive")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, data, nil
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ScriptEnvTestSuite encapsulates shared environment for the container file API tests
+type ScriptEnvTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// SetupSuite prepares the environment before tests run
+func (suite *ScriptEnvTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: Text with quotes and binary data survive a round trip
+func (suite *ScriptEnvTestSuite) TestWriteReadFile(t *testing.T) {
+	tests := map[string][]byte{
+		"/tmp/quotes.txt": []byte("name: 'it''s' \"quoted\" $HOME `date`\n"),
+		"/tmp/binary.bin": {0x00, 0xff, '\'', '\n', 0x1b, 0x00},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, suite.env.WriteFile(name, data, 0600), "Failed to write file")
+
+			read, err := suite.env.ReadFile(name)
+			require.NoError(t, err, "Failed to read file")
+			assert.Equal(t, data, read)
+
+			// The shell sees the same bytes
+			result, err := suite.env.Run([]string{"wc", "-c", name}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(len(data)), strings.Fields(result.Stdout)[0])
+		})
+	}
+}
+
+// Test 2: Stat reports mode and size, and missing files are fs.ErrNotExist
+func (suite *ScriptEnvTestSuite) TestStat(t *testing.T) {
+	require.NoError(t, suite.env.WriteFile("/tmp/stat.sh", []byte("#!/bin/sh\n"), 0750))
+
+	info, err := suite.env.Stat("/tmp/stat.sh")
+	require.NoError(t, err)
+	assert.Equal(t, "stat.sh", info.Name())
+	assert.Equal(t, fs.FileMode(0750), info.Mode().Perm())
+	assert.Equal(t, int64(10), info.Size())
+	assert.False(t, info.IsDir())
+
+	info, err = suite.env.Stat("/tmp")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	_, err = suite.env.Stat("/tmp/missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "Expected fs.ErrNotExist, got %v", err)
+
+	_, err = suite.env.ReadFile("/tmp/missing")
+	assert.True(t, errors.Is(err, fs.ErrNotExist), "Expected fs.ErrNotExist, got %v", err)
+}
+
+// Test 3: Run keeps stdout, stderr and the exit code apart
+func (suite *ScriptEnvTestSuite) TestRun(t *testing.T) {
+	result, err := suite.env.Run([]string{"sh", "-c", `echo "out $GREETING"; echo err >&2; exit 3`}, map[string]string{"GREETING": "it's me"})
+	require.NoError(t, err)
+	assert.Equal(t, "out it's me\n", result.Stdout)
+	assert.Equal(t, "err\n", result.Stderr)
+	assert.Equal(t, 3, result.ExitCode)
+
+	result, err = suite.env.Run([]string{"true"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+}
+
+// TestScriptEnvTestSuite runs the test suite
+func TestScriptEnvTestSuite(t *testing.T) {
+	suite := &ScriptEnvTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestWriteReadFile", suite.TestWriteReadFile)
+	t.Run("TestStat", suite.TestStat)
+	t.Run("TestRun", suite.TestRun)
+}
+
+// TestTarFileRoundTrip checks the archives streamed through docker cp
+func TestTarFileRoundTrip(t *testing.T) {
+	data := []byte{0x00, '\'', 0xff}
+	archive, err := tarFile("config.yaml", data, 0640)
+	require.NoError(t, err)
+
+	header, read, err := untarFile(bytes.NewReader(archive))
+	require.NoError(t, err)
+	assert.Equal(t, "config.yaml", header.Name)
+	assert.Equal(t, fs.FileMode(0640), header.FileInfo().Mode().Perm())
+	assert.Equal(t, data, read)
+
+	_, _, err = untarFile(bytes.NewReader(nil))
+	assert.Error(t, err, "Expected an empty archive to be rejected")
+}
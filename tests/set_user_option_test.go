@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SetUserOptionTestSuite encapsulates shared environment for per-user option tests
+type SetUserOptionTestSuite struct {
+	env ScriptTestEnv // Shared environment
+}
+
+// SetupSuite prepares the environment before tests run
+func (suite *SetUserOptionTestSuite) SetupSuite(t *testing.T) {
+	// Setup script test environment
+	suite.env = SetupScriptTestEnv(t)
+}
+
+// Test 1: Options are replaced and removed
+func (suite *SetUserOptionTestSuite) TestSetOption(t *testing.T) {
+	cmd := []string{"sh", "-c", "export USER_CONFIG_DIR=/tmp/suo && " +
+		"set_user_option alice local_max_rate 1000 && set_user_option alice local_max_rate 2000 && " +
+		"set_user_option alice max_per_ip 3 && set_user_option alice max_per_ip && cat /tmp/suo/alice"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set user options")
+	assert.Equal(t, "local_max_rate=2000\n", output)
+}
+
+// Test 2: Concurrent writers to one user's file do not lose updates
+func (suite *SetUserOptionTestSuite) TestConcurrentWriters(t *testing.T) {
+	cmd := []string{"sh", "-c", "export USER_CONFIG_DIR=/tmp/suo-race && " +
+		"for o in a b c d e f g h i j k l m n o p; do set_user_option bob \"opt_$o\" 1 & done; wait && " +
+		"cat /tmp/suo-race/bob && ls -A /tmp/suo-race"}
+	output, err := ExecCommandInContainer(t, suite.env.ContainerName, cmd)
+	require.NoError(t, err, "Failed to set user options")
+
+	for _, option := range strings.Fields("a b c d e f g h i j k l m n o p") {
+		assert.Contains(t, output, "opt_"+option+"=1\n")
+	}
+	assert.NotContains(t, output, ".bob.", "Temporary files should be renamed into place")
+}
+
+// Main test runner
+func TestSetUserOptionTestSuite(t *testing.T) {
+	suite := &SetUserOptionTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestSetOption", suite.TestSetOption)
+	t.Run("TestConcurrentWriters", suite.TestConcurrentWriters)
+}
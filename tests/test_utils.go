@@ -1,21 +1,24 @@
 package tests
 
 import (
+	"crypto/rand"
 	"crypto/tls"
 	"fmt"
-	"io"
-	"net/http"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/conformance"
+	"github.com/shawn636/mini-ftp/internal/ftptest"
 	"github.com/stretchr/testify/require"
-	"gopkg.in/yaml.v3"
 )
 
 // TestOptions defines requirements for environment setup
@@ -24,186 +27,316 @@ type TestOptions struct {
 	ConfigFile   *string           // Optional config file
 	UseSSL       bool
 	Address      string            // Domain or IP address to connect to
-	Port         int               // Custom FTP port for this test
-	PassivePorts string            // Passive port range
+	Port         int               // FTP port on the host; a free one is picked by setupTestEnv if zero
+	PassivePorts string            // Passive port range; a free one is picked by setupTestEnv if empty
 	Users        map[string]string // Multiple username-password pairs
+	UserMode     string            // Optional user mode (system or virtual); defaults to $USER_MODE
+	ExtraFiles   []string          // Optional fixture files copied next to the compose file
+
+	// Without a ComposeFile the project is generated from these fields
+	Source  UserSource        // Whether users are passed as environment variables or in a config file
+	EnvUser string            // Optional config user that is also passed as FTP_USER to override it
+	TLS     TLSMode           // Optional TLS setup; TLSSelfSigned also sets UseSSL
+	Env     map[string]string // Extra environment variables for the ftp service
+	Volumes []string          // Extra volumes for the ftp service, e.g. ftp:/ftp
+	Config  *FTPConfig        // Optional config file contents for UsersFromConfig
+
+	CapAdd      []string // Optional capabilities for the ftp service, e.g. SYS_ADMIN
+	SecurityOpt []string // Optional security options for the ftp service
 }
 
 // ScriptTestEnv defines the environment for script tests
 type ScriptTestEnv struct {
-	TempDir       string
 	ContainerName string
-	ImageName     string
+	ImageName     string // Shared script image, see scriptImage
 }
 
-// Setup test environment based on test options
-func setupTestEnv(t *testing.T, opts TestOptions) string {
+// ComposeEnv is a docker compose project started by setupTestEnv
+type ComposeEnv struct {
+	Dir     string   // Temp directory holding the compose files
+	Project string   // Compose project name
+	vars    []string // Variables interpolated into the compose files
+}
+
+// passivePortCount is the size of the passive range picked for each suite
+const passivePortCount = 10
+
+// startAttempts is how often setupTestEnv picks new ports when they are taken
+// before Docker binds them
+const startAttempts = 5
+
+// Setup test environment based on test options. Free ports are written back to opts.
+// The containers run the images shared by the whole test run (see images.go).
+func setupTestEnv(t testing.TB, opts *TestOptions) *ComposeEnv {
 	projectRoot, err := filepath.Abs(filepath.Join(".."))
 	require.NoError(t, err, "Failed to determine project root")
 
-	alpineVersion := os.Getenv("ALPINE_VERSION")
-	if alpineVersion == "" {
-		alpineVersion = "latest"
-	}
-
 	tmpDir, err := os.MkdirTemp("", "test-env-*")
 	require.NoError(t, err, "Failed to create temp directory")
 
 	projectName := "test-" + strings.Replace(filepath.Base(tmpDir), "test-env-", "", 1)
+	env := &ComposeEnv{Dir: tmpDir, Project: projectName}
 
-	// Copy essential files
-	copyFiles(t, projectRoot, tmpDir, []string{"Dockerfile"})
-	err = copyDir(filepath.Join(projectRoot, "scripts"), filepath.Join(tmpDir, "scripts"))
-	require.NoError(t, err, "Failed to copy scripts directory")
-
-	configDir := filepath.Join(tmpDir, "config")
-	require.NoError(t, os.MkdirAll(configDir, 0755), "Failed to create config directory")
-	copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), configDir, []string{"vsftpd.conf"})
+	// Callers tear the project down once setupTestEnv returns. Until then a
+	// failure would leak the temp directory and any containers started so far.
+	started := false
+	t.Cleanup(func() {
+		if started {
+			return
+		}
+		if env.vars != nil {
+			env.compose("down", "--volumes", "--remove-orphans")
+		}
+		os.RemoveAll(tmpDir)
+	})
 
-	// SSL-specific configuration
-	if opts.UseSSL || opts.ConfigFile != nil {
-		copyFiles(t, projectRoot, tmpDir, []string{".env"})
-		copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), tmpDir, []string{"Dockerfile.ssl"})
-		copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), tmpDir, []string{"generate-cert.sh"})
+	// Images are referenced as ${FTP_IMAGE} and ${SSL_IMAGE} in the compose
+	// files, and IPv6 networks use ${IPV6_SUBNET} so parallel projects never
+	// share a subnet
+	envVars := []string{
+		fmt.Sprintf("FTP_IMAGE=%s", serverImage.Tag(t)),
+		fmt.Sprintf("IPV6_SUBNET=%s", uniqueIPv6Subnet(t)),
+	}
+	if opts.TLS == TLSSelfSigned {
+		opts.UseSSL = true
+		envVars = append(envVars, fmt.Sprintf("SSL_IMAGE=%s", sslImage.Tag(t)))
 	}
 
-	srcCompose := filepath.Join(projectRoot, "tests/fixtures", opts.ComposeFile)
-	destCompose := filepath.Join(tmpDir, "docker-compose.yaml")
-	data, err := os.ReadFile(srcCompose)
-	require.NoError(t, err, "Failed to read compose file")
-	require.NoError(t, os.WriteFile(destCompose, data, 0644), "Failed to write compose file")
+	if opts.ComposeFile == "" {
+		writeGeneratedProject(t, tmpDir, opts)
+	} else {
+		srcCompose := filepath.Join(projectRoot, "tests/fixtures", opts.ComposeFile)
+		destCompose := filepath.Join(tmpDir, "docker-compose.yaml")
+		data, err := os.ReadFile(srcCompose)
+		require.NoError(t, err, "Failed to read compose file")
+		require.NoError(t, os.WriteFile(destCompose, data, 0644), "Failed to write compose file")
+	}
 
 	if opts.ConfigFile != nil {
 		copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), tmpDir, []string{*opts.ConfigFile})
 	}
+	copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), tmpDir, opts.ExtraFiles)
 
 	// Dynamically set environment variables for all users
-	envVars := []string{
-		fmt.Sprintf("ALPINE_VERSION=%s", alpineVersion),
-	}
 	for username, password := range opts.Users {
 		envKey := fmt.Sprintf("%s_PASS", strings.ToUpper(username))
 		envVars = append(envVars, fmt.Sprintf("%s=%s", envKey, password))
 	}
 
-	cmd := exec.Command(
-		"docker",
-		"compose",
-		"--project-name", projectName,
-		"up", "--build", "-d", "--wait",
-	)
-	cmd.Dir = tmpDir
-	cmd.Env = append(os.Environ(), envVars...)
+	// Pick free ports so suites never collide with each other or the host.
+	// Another process can take them before Docker binds them, so picked
+	// ports are picked again when compose fails to publish them.
+	pickPort := opts.Port == 0
+	pickPassive := opts.PassivePorts == ""
+	for attempt := 1; ; attempt++ {
+		if pickPort {
+			opts.Port, _ = freePortRange(t, 1)
+		}
+		if pickPassive {
+			minPort, maxPort := freePortRange(t, passivePortCount)
+			opts.PassivePorts = fmt.Sprintf("%d-%d", minPort, maxPort)
+		}
+		env.vars = append(writePortOverride(t, tmpDir, opts), envVars...)
 
-	// Capture output
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		t.Logf("Error during 'docker compose up':\n%s", string(output))
+		output, err := env.compose("up", "-d", "--wait")
+		if err == nil {
+			break
+		}
+		if (pickPort || pickPassive) && attempt < startAttempts && ftptest.PortTaken(output) {
+			t.Logf("Ports were taken before Docker bound them, retrying with new ones:\n%s", output)
+			env.compose("down", "--volumes", "--remove-orphans")
+			continue
+		}
+		t.Logf("Error during 'docker compose up':\n%s", output)
 		t.Fatalf("Failed to start Docker Compose: %v", err)
 	}
 
-	// require.NoError(t, cmd.Run(), "Failed to start Docker Compose")
+	verifyAlpineVersion(t, env.ContainerName("ftp"), alpineVersion())
+
+	started = true
+	return env
+}
+
+// writePortOverride writes the compose override for the ports in opts and
+// returns the variables that publish them
+func writePortOverride(t testing.TB, dir string, opts *TestOptions) []string {
+	minPort, maxPort, found := strings.Cut(opts.PassivePorts, "-")
+	require.True(t, found, "Invalid passive port range: "+opts.PassivePorts)
 
-	containerName := projectName + "-ftp-1"
-	verifyAlpineVersion(t, containerName, alpineVersion)
+	// The server must advertise the same passive ports that are published.
+	// Compose merges the override file into docker-compose.yaml automatically.
+	override := fmt.Sprintf("services:\n  ftp:\n    environment:\n      - MIN_PORT=%s\n      - MAX_PORT=%s\n", minPort, maxPort)
 
-	return fmt.Sprintf("%s:%s", tmpDir, projectName)
+	// Run the suite under the requested user mode
+	userMode := opts.UserMode
+	if userMode == "" {
+		userMode = os.Getenv("USER_MODE")
+	}
+	if userMode != "" {
+		override += fmt.Sprintf("      - USER_MODE=%s\n", userMode)
+	}
+	destOverride := filepath.Join(dir, "docker-compose.override.yaml")
+	require.NoError(t, os.WriteFile(destOverride, []byte(override), 0644), "Failed to write compose override")
+
+	// The ports are interpolated into the ports section of the compose file
+	return []string{
+		fmt.Sprintf("FTP_PORT=%d", opts.Port),
+		fmt.Sprintf("PASV_MIN_PORT=%s", minPort),
+		fmt.Sprintf("PASV_MAX_PORT=%s", maxPort),
+	}
 }
 
-// Teardown test environment
-func teardownTestEnv(t *testing.T, tmpDirAndProject string) {
-	parts := strings.Split(tmpDirAndProject, ":")
-	tmpDir := parts[0]
-	projectName := parts[1]
+// uniqueIPv6Subnet returns a /64 from a randomly chosen unique local prefix (RFC 4193)
+func uniqueIPv6Subnet(t testing.TB) string {
+	globalID := make([]byte, 5)
+	_, err := rand.Read(globalID)
+	require.NoError(t, err, "Failed to generate an IPv6 subnet")
+	return fmt.Sprintf("fd%02x:%02x%02x:%02x%02x::/64", globalID[0], globalID[1], globalID[2], globalID[3], globalID[4])
+}
 
+// freePortRange finds count consecutive ports that are free on every interface
+func freePortRange(t testing.TB, count int) (int, int) {
+	minPort, maxPort, err := ftptest.FreePortRange("", count)
+	require.NoError(t, err, "Failed to find free ports")
+	return minPort, maxPort
+}
+
+// compose runs a docker compose command in the project and returns its combined output
+func (env *ComposeEnv) compose(args ...string) (string, error) {
+	cmd := exec.Command("docker", append([]string{"compose", "--project-name", env.Project}, args...)...)
+	cmd.Dir = env.Dir
+	cmd.Env = append(os.Environ(), env.vars...)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// Teardown removes the project's containers and volumes, and its temp directory.
+// The images are shared with other suites and removed by TestMain.
+func (env *ComposeEnv) Teardown(t testing.TB) {
 	// Check if logs should be printed
 	if os.Getenv("LOG_LEVEL") == "DEBUG" {
 		// Fetch logs for all containers in the project
-		cmd := exec.Command(
-			"docker",
-			"compose",
-			"--project-name", projectName,
-			"logs",
-		)
-		cmd.Dir = tmpDir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		_ = cmd.Run() // Print logs but continue even if this command fails
+		output, _ := env.compose("logs")
+		fmt.Print(output) // Print logs but continue even if this command fails
 	}
 
 	// Tear down Docker Compose stack completely
+	output, err := env.compose("down", "--volumes")
+	require.NoError(t, err, "Failed to tear down Docker Compose: %s", output)
+
+	// Remove temp directory
+	require.NoError(t, os.RemoveAll(env.Dir), "Failed to remove temp directory")
+}
+
+// ContainerName returns the container name of a service
+func (env *ComposeEnv) ContainerName(service string) string {
+	return fmt.Sprintf("%s-%s-1", env.Project, service)
+}
+
+// Exec runs a command in a service's container and returns its combined output
+func (env *ComposeEnv) Exec(service string, command ...string) (string, error) {
+	cmd := exec.Command("docker", append([]string{"exec", env.ContainerName(service)}, command...)...)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+// ServiceIP returns the service's address on its compose network
+func (env *ComposeEnv) ServiceIP(t testing.TB, service string) string {
+	container := env.ContainerName(service)
 	cmd := exec.Command(
-		"docker",
-		"compose",
-		"--project-name", projectName,
-		"down", "--volumes", "--rmi", "all",
+		"docker", "inspect",
+		"--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}",
+		container,
 	)
-	cmd.Dir = tmpDir
-	require.NoError(t, cmd.Run(), "Failed to tear down Docker Compose")
+	output, err := cmd.Output()
+	require.NoError(t, err, "Failed to inspect container: "+container)
 
-	// Remove temp directory
-	require.NoError(t, os.RemoveAll(tmpDir), "Failed to remove temp directory")
+	fields := strings.Fields(string(output))
+	require.NotEmpty(t, fields, "Container has no network address: "+container)
+	return fields[0]
 }
 
-// SetupScriptTestEnv creates a temporary test environment and starts a container for script tests
-func SetupScriptTestEnv(t *testing.T) ScriptTestEnv {
-	// Create a temporary directory
-	tmpDir, err := os.MkdirTemp("", "script-test-env-*")
-	require.NoError(t, err, "Failed to create temporary directory")
+// ServiceIPv6 returns the global IPv6 address of a service on its first
+// IPv6-enabled network, or an empty string when it has none
+func (env *ComposeEnv) ServiceIPv6(t testing.TB, service string) string {
+	container := env.ContainerName(service)
+	cmd := exec.Command(
+		"docker", "inspect",
+		"--format", "{{range .NetworkSettings.Networks}}{{.GlobalIPv6Address}} {{end}}",
+		container,
+	)
+	output, err := cmd.Output()
+	require.NoError(t, err, "Failed to inspect container: "+container)
 
-	// Get project root dynamically
-	projectRoot, err := filepath.Abs(filepath.Join(".."))
-	require.NoError(t, err, "Failed to determine project root")
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
 
-	// Copy the Dockerfile into the temp directory
-	copyFiles(t, filepath.Join(projectRoot, "tests/fixtures"), tmpDir, []string{"Dockerfile"})
+// Logs returns the logs of a service without color codes
+func (env *ComposeEnv) Logs(t testing.TB, service string) string {
+	output, err := env.compose("logs", "--no-color", service)
+	require.NoError(t, err, "Failed to fetch logs for service %s: %s", service, output)
 
-	// Copy the scripts directory into the temp directory
-	require.NoError(t, copyDir(filepath.Join(projectRoot, "scripts"), filepath.Join(tmpDir, "scripts")),
-		"Failed to copy scripts directory")
+	return stripAnsiCodes(output)
+}
 
-	// Generate unique names for the container and image
-	imageName := "script-test-image-" + filepath.Base(tmpDir)
-	containerName := "script-test-container-" + filepath.Base(tmpDir)
+// Stop stops a service, leaving the others running
+func (env *ComposeEnv) Stop(t testing.TB, service string) {
+	output, err := env.compose("stop", service)
+	require.NoError(t, err, "Failed to stop service %s: %s", service, output)
+}
 
-	// Build the Docker image with ALPINE_VERSION as a build argument (suppress output)
-	alpineVersion := os.Getenv("ALPINE_VERSION")
-	cmd := exec.Command("docker", "build", "--build-arg", fmt.Sprintf("ALPINE_VERSION=%s", alpineVersion), "-t", imageName, ".")
-	cmd.Dir = tmpDir
-	cmd.Stdout = nil // Suppress standard output
-	cmd.Stderr = nil // Suppress standard error
-	require.NoError(t, cmd.Run(), "Failed to build Docker image")
+// Restart restarts a service and waits for it to be healthy again
+func (env *ComposeEnv) Restart(t testing.TB, service string) {
+	output, err := env.compose("restart", service)
+	require.NoError(t, err, "Failed to restart service %s: %s", service, output)
+
+	output, err = env.compose("up", "-d", "--wait", "--no-build", "--no-recreate", service)
+	require.NoError(t, err, "Service %s did not become healthy after a restart: %s", service, output)
+}
+
+// CopyIn copies a file or directory from the host into a service's container
+func (env *ComposeEnv) CopyIn(t testing.TB, service, src, dest string) {
+	output, err := env.compose("cp", src, service+":"+dest)
+	require.NoError(t, err, "Failed to copy %s into %s: %s", src, service, output)
+}
+
+// CopyOut copies a file or directory from a service's container to the host
+func (env *ComposeEnv) CopyOut(t testing.TB, service, src, dest string) {
+	output, err := env.compose("cp", service+":"+src, dest)
+	require.NoError(t, err, "Failed to copy %s out of %s: %s", src, service, output)
+}
+
+// SetupScriptTestEnv starts a container from the shared script image for script tests
+func SetupScriptTestEnv(t *testing.T) ScriptTestEnv {
+	containerName := fmt.Sprintf("script-test-container-%d", time.Now().UnixNano())
+	imageName := scriptImage.Tag(t)
 
 	// Start the container (suppress output)
-	cmd = exec.Command("docker", "run", "--rm", "-d", "--name", containerName, imageName, "sleep", "infinity")
+	cmd := exec.Command("docker", "run", "--rm", "-d", "--name", containerName, imageName, "sleep", "infinity")
 	cmd.Stdout = nil // Suppress standard output
 	cmd.Stderr = nil // Suppress standard error
 	require.NoError(t, cmd.Run(), "Failed to start Docker container")
 
-	if alpineVersion == "" {
-		alpineVersion = "latest"
-	}
+	verifyAlpineVersion(t, containerName, alpineVersion())
 
-	verifyAlpineVersion(t, containerName, alpineVersion)
+	env := ScriptTestEnv{
+		ContainerName: containerName,
+		ImageName:     imageName,
+	}
 
 	// Cleanup after tests
-	t.Cleanup(func() {
-		TeardownScriptTestEnv(t, ScriptTestEnv{
-			TempDir:       tmpDir,
-			ContainerName: containerName,
-			ImageName:     imageName,
-		})
-	})
+	t.Cleanup(func() { TeardownScriptTestEnv(t, env) })
 
 	// Return environment info for tests
-	return ScriptTestEnv{
-		TempDir:       tmpDir,
-		ContainerName: containerName,
-		ImageName:     imageName,
-	}
+	return env
 }
 
-// TeardownScriptTestEnv shuts down the container and removes related resources
+// TeardownScriptTestEnv shuts down the container. The image is shared with
+// other suites and removed by TestMain.
 func TeardownScriptTestEnv(t *testing.T, env ScriptTestEnv) {
 	// Check if logs should be printed
 	if os.Getenv("LOG_LEVEL") == "DEBUG" {
@@ -219,26 +352,17 @@ func TeardownScriptTestEnv(t *testing.T, env ScriptTestEnv) {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	require.NoError(t, cmd.Run(), "Failed to remove container")
-
-	// Remove the Docker image
-	cmd = exec.Command("docker", "rmi", "-f", env.ImageName)
-	cmd.Stdout = nil // Suppress standard output
-	cmd.Stderr = nil // Suppress standard error
-	require.NoError(t, cmd.Run(), "Failed to remove Docker image")
-
-	// Remove the temporary directory
-	require.NoError(t, os.RemoveAll(env.TempDir), "Failed to remove temporary directory")
 }
 
 // ExecCommandInContainer runs a shell command inside the specified container
-func ExecCommandInContainer(t *testing.T, containerName string, command []string) (string, error) {
+func ExecCommandInContainer(t testing.TB, containerName string, command []string) (string, error) {
 	cmd := exec.Command("docker", append([]string{"exec", containerName}, command...)...)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
 
 // Copy files
-func copyFiles(t *testing.T, srcDir, destDir string, files []string) {
+func copyFiles(t testing.TB, srcDir, destDir string, files []string) {
 	for _, file := range files {
 		srcPath := filepath.Join(srcDir, file)
 		destPath := filepath.Join(destDir, file)
@@ -277,83 +401,63 @@ func copyDir(src string, dest string) error {
 }
 
 // setupFTPClients initializes FTP clients for all configured users
-func setupFTPClients(t *testing.T, opts TestOptions) map[string]*goftp.Client {
+func setupFTPClients(t testing.TB, opts TestOptions) map[string]*goftp.Client {
 	clients := make(map[string]*goftp.Client)
 
-	for username, password := range opts.Users {
-		config := goftp.Config{
-			User:     username,
-			Password: password,
-			Timeout:  10 * time.Second,
-		}
-
-		if opts.UseSSL {
-			config.TLSConfig = &tls.Config{
-				ServerName:         opts.Address,
-				InsecureSkipVerify: true,
-			}
-			config.TLSMode = goftp.TLSExplicit
-		}
-
-		address := fmt.Sprintf("%s:%d", opts.Address, opts.Port)
-		client, err := goftp.DialConfig(config, address)
-		require.NoError(t, err, fmt.Sprintf("Failed to connect to FTP server as user: %s", username))
-
-		clients[username] = client
+	for username := range opts.Users {
+		clients[username] = dialFTP(t, opts, username)
 	}
 
 	return clients
 }
 
-// stripAnsiCodes removes ANSI escape codes from a string
-func stripAnsiCodes(input string) string {
-	re := regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
-	return re.ReplaceAllString(input, "")
-}
+// dialFTP connects to the server as one of opts.Users
+func dialFTP(t testing.TB, opts TestOptions, username string) *goftp.Client {
+	config := goftp.Config{
+		User:     username,
+		Password: opts.Users[username],
+		Timeout:  10 * time.Second,
+	}
 
-func verifyAlpineVersion(t *testing.T, containerName, expectedVersion string) {
-    // Execute command to retrieve Alpine version inside the container
-    output, err := ExecCommandInContainer(t, containerName, []string{"cat", "/etc/alpine-release"})
-    require.NoError(t, err, "Failed to retrieve Alpine version from container")
+	if opts.UseSSL {
+		config.TLSConfig = &tls.Config{
+			ServerName:         opts.Address,
+			InsecureSkipVerify: true,
+		}
+		config.TLSMode = goftp.TLSExplicit
+	}
 
-    // Trim any surrounding whitespace or newline characters
-    actualVersion := strings.TrimSpace(output)
+	// JoinHostPort brackets IPv6 literals such as ::1
+	address := net.JoinHostPort(opts.Address, strconv.Itoa(opts.Port))
+	client, err := goftp.DialConfig(config, address)
+	require.NoError(t, err, fmt.Sprintf("Failed to connect to FTP server as user: %s", username))
 
-    // If expectedVersion is "latest", fetch the latest version online
-    if expectedVersion == "latest" {
-        expectedVersion = fetchLatestAlpineVersion(t)
-    }
+	return client
+}
 
-    // Compare the actual version with the expected version
-	require.True(t, strings.HasPrefix(actualVersion, expectedVersion), "Alpine version mismatch: expected prefix %s, got %s", expectedVersion, actualVersion)
+// runConformance runs the conformance checks once for every user in opts
+func runConformance(t *testing.T, opts TestOptions) {
+	usernames := make([]string, 0, len(opts.Users))
+	for username := range opts.Users {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	for _, username := range usernames {
+		t.Run(username, func(t *testing.T) {
+			conformance.Run(t, conformance.Options{
+				Dial: func(t testing.TB) *goftp.Client {
+					client := dialFTP(t, opts, username)
+					t.Cleanup(func() { client.Close() })
+					return client
+				},
+			})
+		})
+	}
 }
 
-type AlpineRelease struct {
-	Version string `yaml:"version"`
+// stripAnsiCodes removes ANSI escape codes from a string
+func stripAnsiCodes(input string) string {
+	re := regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	return re.ReplaceAllString(input, "")
 }
-func fetchLatestAlpineVersion(t *testing.T) string {
-	// URL for the Alpine release metadata
-	url := "https://dl-cdn.alpinelinux.org/alpine/latest-stable/releases/x86_64/latest-releases.yaml"
-
-	// Send an HTTP GET request
-	resp, err := http.Get(url)
-	require.NoError(t, err, "Failed to fetch Alpine releases metadata")
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	require.NoError(t, err, "Failed to read Alpine releases metadata")
-
-	// Parse the YAML response
-	var releases []AlpineRelease
-	err = yaml.Unmarshal(body, &releases)
-	require.NoError(t, err, "Failed to parse Alpine releases metadata")
-
-	// Check that at least one release was found
-	require.NotEmpty(t, releases, "No releases found in metadata")
-
-	// Extract and return only the major and minor version (e.g., "3.14" from "3.14.2")
-	versionParts := strings.Split(releases[0].Version, ".")
-	require.True(t, len(versionParts) >= 2, "Invalid version format in metadata")
-	return fmt.Sprintf("%s.%s", versionParts[0], versionParts[1])
-}
\ No newline at end of file
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/conformance"
+	"github.com/shawn636/mini-ftp/testcontainers/miniftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// TestcontainersTestSuite runs the file operation scenarios against the testcontainers module
+type TestcontainersTestSuite struct {
+	container *miniftp.Container
+	client    *goftp.Client
+}
+
+// SetupSuite starts the container and logs in
+func (suite *TestcontainersTestSuite) SetupSuite(t *testing.T, image string, opts ...testcontainers.ContainerCustomizer) {
+	ctx := context.Background()
+
+	opts = append(opts,
+		miniftp.WithUser("user", "Gk5wRn8TzC3v"),
+		miniftp.WithUser("other", "Bd9mQs4XhL7j"),
+		miniftp.WithConfigYAML("server:\n  max_clients: 20\n"),
+	)
+	if userMode := os.Getenv("USER_MODE"); userMode != "" {
+		opts = append(opts, testcontainers.WithEnv(map[string]string{"USER_MODE": userMode}))
+	}
+
+	container, err := miniftp.Run(ctx, image, opts...)
+	testcontainers.CleanupContainer(t, container)
+	require.NoError(t, err, "Failed to start mini-ftp")
+	suite.container = container
+
+	suite.client = suite.dial(t, "user")
+}
+
+// dial connects as username and closes the client when t completes
+func (suite *TestcontainersTestSuite) dial(t testing.TB, username string) *goftp.Client {
+	client, err := suite.container.Client(context.Background(), username)
+	require.NoError(t, err, "Failed to connect to FTP server as user: %s", username)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestConformance runs the shared FTP conformance checks
+func (suite *TestcontainersTestSuite) TestConformance(t *testing.T) {
+	conformance.Run(t, conformance.Options{
+		Dial: func(t testing.TB) *goftp.Client { return suite.dial(t, "user") },
+	})
+}
+
+// TestUserIsolation ensures users stay inside their own home
+func (suite *TestcontainersTestSuite) TestUserIsolation(t *testing.T) {
+	require.NoError(t, suite.client.Store("private.txt", bytes.NewReader([]byte("private"))))
+	t.Cleanup(func() { suite.client.Delete("private.txt") })
+
+	_, err := suite.dial(t, "other").Stat("private.txt")
+	assert.Error(t, err, "Other users should not see the file")
+}
+
+// TestPassivePorts checks that the passive range is published on the same host ports
+func (suite *TestcontainersTestSuite) TestPassivePorts(t *testing.T) {
+	minPort, maxPort := suite.container.PassiveRange()
+	assert.Equal(t, 9, maxPort-minPort)
+
+	logs, err := suite.container.LogsString(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, logs, "Gk5wRn8TzC3v", "Passwords should never be logged")
+}
+
+// Main test runner
+func TestTestcontainersTestSuite(t *testing.T) {
+	// The shared image already contains the test-only vsftpd.conf
+	image := serverImage.Tag(t)
+
+	variants := map[string][]testcontainers.ContainerCustomizer{
+		"Plain": nil,
+		"TLS":   {miniftp.WithTLSSelfSigned()},
+	}
+	for name, opts := range variants {
+		t.Run(name, func(t *testing.T) {
+			suite := &TestcontainersTestSuite{}
+			suite.SetupSuite(t, image, opts...)
+
+			t.Run("TestConformance", suite.TestConformance)
+			t.Run("TestUserIsolation", suite.TestUserIsolation)
+			t.Run("TestPassivePorts", suite.TestPassivePorts)
+		})
+	}
+}
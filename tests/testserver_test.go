@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/shawn636/mini-ftp/testserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestServer starts a server from the shared test image, which contains the test-only vsftpd.conf,
+// unless opts names another image
+func startTestServer(t *testing.T, opts testserver.Options) *testserver.Server {
+	if opts.Image == "" {
+		opts.Image = serverImage.Tag(t)
+	}
+	if opts.UserMode == "" {
+		opts.UserMode = os.Getenv("USER_MODE")
+	}
+	return testserver.Start(t, opts)
+}
+
+// TestServerPackage checks that the testserver package starts a usable server
+func TestServerPackage(t *testing.T) {
+	srv := startTestServer(t, testserver.Options{
+		Users: map[string]string{
+			"alice": "Rm4xTq8WbN2k",
+			"bob":   "Jc7vLs3PzH9d",
+		},
+	})
+
+	alice := srv.Client("alice")
+	require.NoError(t, alice.Store("hello.txt", bytes.NewReader([]byte("hello from alice"))))
+
+	var buf bytes.Buffer
+	require.NoError(t, alice.Retrieve("hello.txt", &buf))
+	assert.Equal(t, "hello from alice", buf.String())
+
+	// Each user gets their own home
+	entries, err := srv.Client("bob").ReadDir("/")
+	require.NoError(t, err)
+	assert.Empty(t, entries, "bob should not see alice's files")
+
+	logs := srv.Logs()
+	assert.Contains(t, logs, "alice")
+	assert.NotContains(t, logs, "Rm4xTq8WbN2k", "Passwords should never be logged")
+}
+
+// TestServerPackageTLS checks that the testserver package serves FTPS with a generated certificate. It
+// uses the production vsftpd.conf, which requires TLS session reuse unless Start turns it off.
+func TestServerPackageTLS(t *testing.T) {
+	srv := startTestServer(t, testserver.Options{
+		Image: productionImage.Tag(t),
+		Users: map[string]string{"carol": "Wv2nKd6SfT8p"},
+		TLS:   true,
+	})
+
+	carol := srv.Client("carol")
+	require.NoError(t, carol.Store("secure.txt", bytes.NewReader([]byte("encrypted"))))
+
+	output, err := srv.Exec("cat", "/ftp/carol/secure.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "encrypted", output)
+	assert.Contains(t, srv.Logs(), "TLS session reuse is not required")
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// UserImportTestSuite encapsulates test options and clients
+type UserImportTestSuite struct {
+	opts      TestOptions
+	env       *ComposeEnv
+	container string
+	userMode  string
+}
+
+// SetupSuite initializes environment before tests run
+func (suite *UserImportTestSuite) SetupSuite(t *testing.T) {
+	// Define test options without users; every user comes from the import files
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users:   map[string]string{},
+		Volumes: []string{
+			"./users-import.csv:/import/users.csv:ro",
+			"./users-import.htpasswd:/import/users.htpasswd:ro",
+		},
+		ExtraFiles: []string{"users-import.csv", "users-import.htpasswd"},
+	}
+
+	suite.userMode = os.Getenv("USER_MODE")
+	if suite.userMode == "" {
+		suite.userMode = "system"
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+}
+
+// dial opens a new session for the given user
+func (suite *UserImportTestSuite) dial(t *testing.T, username, password string) *goftp.Client {
+	config := goftp.Config{
+		User:     username,
+		Password: password,
+		Timeout:  10 * time.Second,
+	}
+	client, err := goftp.DialConfig(config, fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// login opens a new session and returns the error of the first command
+func (suite *UserImportTestSuite) login(t *testing.T, username, password string) error {
+	_, err := suite.dial(t, username, password).ReadDir("/")
+	return err
+}
+
+// TestDryRun checks that a dry run reports every row without creating users
+func (suite *UserImportTestSuite) TestDryRun(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "user", "import", "--dry-run", "/import/users.csv"})
+	require.Error(t, err, "Rows with errors should fail the dry run")
+	output = stripAnsiCodes(output)
+
+	assert.Contains(t, output, "Would add")
+	assert.Contains(t, output, "line 4 (bad user!): Invalid username")
+	assert.Contains(t, output, "line 5 (alice): Duplicate of line 2")
+	assert.Contains(t, output, "line 6 (carol): Unsupported password hash")
+	assert.Contains(t, output, "line 7 (dan): Home /ftp/robert is also used on line 3")
+	assert.Contains(t, output, "Dry run: 2 users would be imported. Rows with errors: 4. Nothing was changed.")
+
+	assert.Error(t, suite.login(t, "alice", "Tq8mZr3WxK5n"), "A dry run should not create users")
+}
+
+// TestImportCSV checks that valid CSV rows are imported with their hashes, homes and quotas
+func (suite *UserImportTestSuite) TestImportCSV(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "user", "import", "/import/users.csv"})
+	require.Error(t, err, "Rows with errors should fail the import")
+	assert.Contains(t, stripAnsiCodes(output), "Imported 2 users. Rows with errors: 4.")
+
+	require.NoError(t, suite.login(t, "alice", "Tq8mZr3WxK5n"), "alice should log in with her imported SHA-512 hash")
+	assert.Error(t, suite.login(t, "alice", "not-the-password"), "A wrong password should be rejected")
+
+	// bob's home keeps the last component of /srv/ftp/robert
+	bob := suite.dial(t, "bob", "Lc4vNj7PbR2s")
+	require.NoError(t, bob.Store("hello.txt", bytes.NewReader([]byte("imported"))), "bob should log in with his imported MD5 hash")
+	_, err = ExecCommandInContainer(t, suite.container, []string{"test", "-f", "/ftp/robert/hello.txt"})
+	assert.NoError(t, err, "bob's home should be /ftp/robert")
+
+	if suite.userMode == "system" {
+		output, err = ExecCommandInContainer(t, suite.container, []string{"id", "-u", "alice"})
+		require.NoError(t, err)
+		assert.Equal(t, "5001\n", output, "alice should keep her UID")
+	}
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "user", "quota", "alice"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "1073741824", "alice's quota should be imported")
+
+	// Importing again reports the existing users instead of changing them
+	output, err = ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "user", "import", "/import/users.csv"})
+	require.Error(t, err)
+	assert.Contains(t, stripAnsiCodes(output), "line 2 (alice): User 'alice' already exists")
+}
+
+// TestImportHtpasswd checks htpasswd files and the hash formats each user mode supports
+func (suite *UserImportTestSuite) TestImportHtpasswd(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "user", "import", "/import/users.htpasswd"})
+	require.Error(t, err, "The bcrypt row should fail the import")
+	output = stripAnsiCodes(output)
+	assert.Contains(t, output, "line 4 (frank): Unsupported password hash")
+
+	assert.NoError(t, suite.login(t, "erin", "Ny3pGt8LcF6r"), "erin should log in with her imported SHA-256 hash")
+
+	if suite.userMode == "virtual" {
+		assert.NoError(t, suite.login(t, "dave", "Hv6kSx2MdQ9w"), "Virtual users should accept Apache MD5 hashes")
+	} else {
+		assert.Contains(t, output, "line 2 (dave): Unsupported password hash")
+	}
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"mini-ftp", "help"})
+	require.NoError(t, err)
+	assert.Contains(t, output, "$apr1$ (htpasswd -m)        REJECTED in system mode", "The help should warn about Apache MD5")
+	assert.Contains(t, output, "$2y$ (bcrypt, htpasswd -B)  REJECTED in both user modes", "The help should warn about bcrypt")
+}
+
+// Main test runner
+func TestUserImportTestSuite(t *testing.T) {
+	suite := &UserImportTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestDryRun", suite.TestDryRun)
+	t.Run("TestImportCSV", suite.TestImportCSV)
+	t.Run("TestImportHtpasswd", suite.TestImportHtpasswd)
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// VaultTestSuite encapsulates test options and clients
+type VaultTestSuite struct {
+	opts      TestOptions
+	clients   map[string]*goftp.Client
+	env       *ComposeEnv
+	container string
+	vault     string
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *VaultTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with two Vault users and one env user
+	config := "config-vault.yaml"
+	suite.opts = TestOptions{
+		ComposeFile: "docker-compose.vault.yaml",
+		ConfigFile:  &config,
+		UseSSL:      false,
+		Address:     "127.0.0.1",
+		Users: map[string]string{
+			"alice": "Qp7wLs2NcV9h",
+			"carol": "Yb3gFn6JzR4t",
+			"bob":   "Hs5dMq9TkW2p",
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+	suite.vault = suite.env.ContainerName("vault")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// login opens a new session and returns the error of the first command
+func (suite *VaultTestSuite) login(t *testing.T, username, password string) error {
+	config := goftp.Config{
+		User:     username,
+		Password: password,
+		Timeout:  10 * time.Second,
+	}
+	client, err := goftp.DialConfig(config, fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.ReadDir("/")
+	return err
+}
+
+// TestVaultPasswords checks that users log in with the passwords stored in Vault
+func (suite *VaultTestSuite) TestVaultPasswords(t *testing.T) {
+	for username, client := range suite.clients {
+		t.Run(username, func(t *testing.T) {
+			_, err := client.ReadDir("/")
+			assert.NoError(t, err, "%s should log in", username)
+		})
+	}
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Vault: http://vault:8200 (AppRole at auth/approle")
+	for _, password := range suite.opts.Users {
+		assert.NotContains(t, logs, password, "Passwords should never be logged")
+	}
+}
+
+// TestTokenAuth checks that a token can be used instead of AppRole
+func (suite *VaultTestSuite) TestTokenAuth(t *testing.T) {
+	output, err := ExecCommandInContainer(t, suite.container, []string{
+		"env", "VAULT_ADDR=http://vault:8200", "VAULT_TOKEN=Lw4zRt8YbN6c",
+		"vault_read", "secret/data/ftp/carol", "ftp_password",
+	})
+	require.NoError(t, err, "Reading with a token should succeed")
+	assert.Equal(t, "Yb3gFn6JzR4t\n", output)
+
+	_, err = ExecCommandInContainer(t, suite.container, []string{
+		"env", "VAULT_ADDR=http://vault:8200", "VAULT_TOKEN=not-a-token",
+		"vault_read", "secret/data/ftp/carol", "ftp_password",
+	})
+	assert.Error(t, err, "An invalid token should be rejected")
+}
+
+// TestPasswordRotation checks that a new password in Vault applies without a restart
+func (suite *VaultTestSuite) TestPasswordRotation(t *testing.T) {
+	_, err := ExecCommandInContainer(t, suite.vault, []string{"vault", "kv", "put", "secret/ftp/alice", "password=Nd6vHc2QxL8s"})
+	require.NoError(t, err, "Failed to rotate the password in Vault")
+
+	require.Eventually(t, func() bool {
+		return suite.login(t, "alice", "Nd6vHc2QxL8s") == nil
+	}, 30*time.Second, time.Second, "The rotated password should be accepted")
+
+	assert.Error(t, suite.login(t, "alice", "Qp7wLs2NcV9h"), "The old password should be rejected")
+	assert.NoError(t, suite.login(t, "bob", "Hs5dMq9TkW2p"), "Other users should keep their passwords")
+
+	logs := suite.env.Logs(t, "ftp")
+	assert.Contains(t, logs, "Password for user 'alice' updated from Vault")
+	assert.NotContains(t, logs, "Password for user 'carol' updated from Vault")
+}
+
+// Main test runner
+func TestVaultTestSuite(t *testing.T) {
+	suite := &VaultTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestVaultPasswords", suite.TestVaultPasswords)
+	t.Run("TestTokenAuth", suite.TestTokenAuth)
+	t.Run("TestPasswordRotation", suite.TestPasswordRotation)
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// VirtualUsersTestSuite encapsulates test options and clients
+type VirtualUsersTestSuite struct {
+	opts      TestOptions
+	clients   map[string]*goftp.Client
+	env       *ComposeEnv
+	container string
+}
+
+// SetupSuite initializes environment and clients before tests run
+func (suite *VirtualUsersTestSuite) SetupSuite(t *testing.T) {
+	// Define test options with e-mail style usernames and two service accounts
+	suite.opts = TestOptions{
+		Source:  UsersFromConfig,
+		UseSSL:  false,
+		Address: "127.0.0.1",
+		Users: map[string]string{
+			"alice@example.com":      "Wm4rKp8ZtN3c",
+			"bob":                    "Jd7xQv2HsL9f",
+			"partner@vendor.example": "Bn5tYc3RwK8m",
+		},
+		UserMode: "virtual",
+		Config: &FTPConfig{
+			Server: ServerConfig{Extra: map[string]any{"user_mode": "virtual"}},
+			Users: []UserConfig{
+				// Runs as its own service account instead of the shared vftp account
+				{Username: "partner@vendor.example", Extra: map[string]any{"service_account": "partners"}},
+			},
+		},
+	}
+
+	// Setup environment
+	suite.env = setupTestEnv(t, &suite.opts)
+	t.Cleanup(func() { suite.env.Teardown(t) })
+	suite.container = suite.env.ContainerName("ftp")
+
+	// Setup FTP clients for all users
+	suite.clients = setupFTPClients(t, suite.opts)
+}
+
+// TestFileOperations checks that every virtual user can upload and download
+func (suite *VirtualUsersTestSuite) TestFileOperations(t *testing.T) {
+	for username, client := range suite.clients {
+		t.Run(username, func(t *testing.T) {
+			content := []byte("uploaded by " + username)
+			require.NoError(t, client.Store("hello.txt", bytes.NewReader(content)))
+
+			var buf bytes.Buffer
+			require.NoError(t, client.Retrieve("hello.txt", &buf))
+			assert.Equal(t, string(content), buf.String())
+		})
+	}
+}
+
+// TestIsolation checks that virtual users sharing an account stay in their own homes
+func (suite *VirtualUsersTestSuite) TestIsolation(t *testing.T) {
+	require.NoError(t, suite.clients["bob"].Store("private.txt", bytes.NewReader([]byte("bob only"))))
+
+	entries, err := suite.clients["alice@example.com"].ReadDir("/")
+	require.NoError(t, err, "Listing should succeed")
+	for _, entry := range entries {
+		assert.NotEqual(t, "private.txt", entry.Name(), "alice should not see bob's files")
+	}
+
+	err = suite.clients["alice@example.com"].Retrieve("../bob/private.txt", &bytes.Buffer{})
+	assert.Error(t, err, "alice should not read bob's files")
+}
+
+// TestWrongPassword checks that the password database rejects bad passwords
+func (suite *VirtualUsersTestSuite) TestWrongPassword(t *testing.T) {
+	config := goftp.Config{
+		User:     "alice@example.com",
+		Password: "not-the-password",
+		Timeout:  10 * time.Second,
+	}
+	client, err := goftp.DialConfig(config, fmt.Sprintf("%s:%d", suite.opts.Address, suite.opts.Port))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.ReadDir("/")
+	assert.Error(t, err, "Login with a wrong password should fail")
+}
+
+// TestNoSystemAccounts checks that users are not created as Linux accounts
+func (suite *VirtualUsersTestSuite) TestNoSystemAccounts(t *testing.T) {
+	for username := range suite.opts.Users {
+		_, err := ExecCommandInContainer(t, suite.container, []string{"id", username})
+		assert.Error(t, err, "Virtual user %s should not have a Linux account", username)
+	}
+
+	output, err := ExecCommandInContainer(t, suite.container, []string{"cat", "/etc/vsftpd/virtual_users"})
+	require.NoError(t, err, "Failed to read the user database")
+	for _, password := range suite.opts.Users {
+		assert.NotContains(t, output, password, "Passwords should only be stored hashed")
+	}
+
+	output, err = ExecCommandInContainer(t, suite.container, []string{"stat", "-c", "%a %U", "/etc/vsftpd/virtual_users"})
+	require.NoError(t, err)
+	assert.Equal(t, "600 root\n", output, "The user database should only be readable by root")
+}
+
+// TestServiceAccounts checks that files are owned by each user's service account
+func (suite *VirtualUsersTestSuite) TestServiceAccounts(t *testing.T) {
+	owners := map[string]string{
+		"alice@example.com":      "vftp",
+		"bob":                    "vftp",
+		"partner@vendor.example": "partners",
+	}
+
+	uids := map[string]string{}
+	for username, account := range owners {
+		require.NoError(t, suite.clients[username].Store("owned.txt", bytes.NewReader([]byte(username))))
+
+		output, err := ExecCommandInContainer(t, suite.container, []string{"stat", "-c", "%U %u", "/ftp/" + username + "/owned.txt"})
+		require.NoError(t, err, "Uploaded file should exist for "+username)
+
+		fields := strings.Fields(output)
+		require.Len(t, fields, 2)
+		assert.Equal(t, account, fields[0], "Files of %s should be owned by %s", username, account)
+		uids[account] = fields[1]
+	}
+
+	assert.NotEqual(t, uids["vftp"], uids["partners"], "Service accounts should have their own UIDs")
+}
+
+// Main test runner
+func TestVirtualUsersTestSuite(t *testing.T) {
+	suite := &VirtualUsersTestSuite{}
+	suite.SetupSuite(t)
+
+	t.Run("TestFileOperations", suite.TestFileOperations)
+	t.Run("TestIsolation", suite.TestIsolation)
+	t.Run("TestWrongPassword", suite.TestWrongPassword)
+	t.Run("TestNoSystemAccounts", suite.TestNoSystemAccounts)
+	t.Run("TestServiceAccounts", suite.TestServiceAccounts)
+}
@@ -0,0 +1,352 @@
+// Package testserver starts a real mini-ftp server in Docker for integration
+// tests.
+//
+// A single call to Start builds or pulls the image, starts a container with
+// the requested users on free ports and removes it when the test finishes:
+//
+//	srv := testserver.Start(t, testserver.Options{
+//		Users: map[string]string{"alice": "secret"},
+//	})
+//	client := srv.Client("alice")
+//	err := client.Store("report.csv", bytes.NewReader(data))
+//
+// Docker must be available on the host running the tests.
+package testserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/secsy/goftp"
+	"github.com/shawn636/mini-ftp/internal/ftptest"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultImage is the image started when Options.Image is empty
+const DefaultImage = "shawn636/mini-ftp:latest"
+
+// passivePortCount is the size of the passive port range given to each server
+const passivePortCount = 10
+
+// startAttempts is how often Start picks new passive ports when they are
+// taken before the container binds them
+const startAttempts = 5
+
+// Options configures the server started by Start
+type Options struct {
+	Image         string            // Image to run; defaults to DefaultImage, or a local tag when BuildContext is set
+	BuildContext  string            // Optional directory to build the image from instead of pulling it
+	AlpineVersion string            // Alpine version passed to the build; defaults to latest
+	Users         map[string]string // Username-password pairs
+	UserMode      string            // Optional user mode (system or virtual)
+	TLS           bool              // Serve explicit FTPS with a self-signed certificate
+	Host          string            // Address the ports are published on; defaults to 127.0.0.1
+	Env           map[string]string // Extra environment variables for the container
+	Mounts        map[string]string // Extra bind mounts, host path to container path
+	StartTimeout  time.Duration     // How long to wait for the server to become ready; defaults to 2 minutes
+}
+
+// Server is a running mini-ftp container
+type Server struct {
+	t         testing.TB
+	opts      Options
+	container string
+	port      int
+}
+
+// Start builds or pulls the image and starts a server that is removed when
+// the test and its subtests complete. Failures stop the test with t.Fatal.
+func Start(t testing.TB, opts Options) *Server {
+	t.Helper()
+
+	if opts.Host == "" {
+		opts.Host = "127.0.0.1"
+	}
+	if opts.AlpineVersion == "" {
+		opts.AlpineVersion = "latest"
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = 2 * time.Minute
+	}
+	opts.Image = prepareImage(t, opts)
+
+	dir := t.TempDir()
+
+	// Users are passed through a config file so that any number of them can
+	// be created. Passwords stay in the environment.
+	env := map[string]string{
+		"CONFIG_FILE": "/etc/ftp/testserver.yaml",
+		"ADDRESS":     opts.Host,
+	}
+	if opts.UserMode != "" {
+		env["USER_MODE"] = opts.UserMode
+	}
+	users := []map[string]string{}
+	for i, username := range sortedKeys(opts.Users) {
+		passwordEnv := fmt.Sprintf("TESTSERVER_PASS_%d", i)
+		users = append(users, map[string]string{"username": username, "password_env": passwordEnv})
+		env[passwordEnv] = opts.Users[username]
+	}
+	config, err := yaml.Marshal(map[string]any{"users": users})
+	if err != nil {
+		t.Fatalf("Failed to generate config: %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "testserver.yaml"), config)
+
+	mounts := map[string]string{filepath.Join(dir, "testserver.yaml"): "/etc/ftp/testserver.yaml"}
+	if opts.TLS {
+		cert, key, err := ftptest.SelfSignedCert(opts.Host)
+		if err != nil {
+			t.Fatalf("Failed to generate TLS certificate: %v", err)
+		}
+		writeFile(t, filepath.Join(dir, "cert.pem"), cert)
+		writeFile(t, filepath.Join(dir, "key.pem"), key)
+		mounts[filepath.Join(dir, "cert.pem")] = "/etc/ftp/tls/cert.pem"
+		mounts[filepath.Join(dir, "key.pem")] = "/etc/ftp/tls/key.pem"
+		env["TLS_CERT"] = "/etc/ftp/tls/cert.pem"
+		env["TLS_KEY"] = "/etc/ftp/tls/key.pem"
+		// Go clients cannot resume the control session on data connections
+		env["TLS_REQUIRE_REUSE"] = "false"
+	}
+	for host, container := range opts.Mounts {
+		mounts[host] = container
+	}
+
+	s := &Server{t: t, opts: opts}
+	for attempt := 1; s.container == ""; attempt++ {
+		s.container = s.run(t, env, mounts, attempt < startAttempts)
+	}
+	t.Cleanup(s.remove)
+
+	s.port = s.publishedPort(t)
+	s.waitReady(t)
+	return s
+}
+
+// run creates and starts the container on newly picked passive ports. It
+// returns an empty ID when retry is set and the ports were taken before
+// Docker could bind them.
+func (s *Server) run(t testing.TB, env, mounts map[string]string, retry bool) string {
+	minPort, maxPort, err := ftptest.FreePortRange(s.opts.Host, passivePortCount)
+	if err != nil {
+		t.Fatalf("Failed to pick passive ports: %v", err)
+	}
+	env["MIN_PORT"] = strconv.Itoa(minPort)
+	env["MAX_PORT"] = strconv.Itoa(maxPort)
+	for key, value := range s.opts.Env {
+		env[key] = value
+	}
+
+	args := []string{
+		"create",
+		"--label", "mini-ftp.testserver=" + t.Name(),
+		"-p", fmt.Sprintf("%s::21", s.opts.Host),
+		"-p", fmt.Sprintf("%s:%d-%d:%d-%d", s.opts.Host, minPort, maxPort, minPort, maxPort),
+	}
+	for _, key := range sortedKeys(env) {
+		args = append(args, "-e", key+"="+env[key])
+	}
+	for _, host := range sortedKeys(mounts) {
+		args = append(args, "-v", host+":"+mounts[host]+":ro")
+	}
+	args = append(args, s.opts.Image)
+
+	output, err := docker(args...)
+	if err != nil {
+		t.Fatalf("Failed to create mini-ftp container: %v\n%s", err, output)
+	}
+	container := strings.TrimSpace(output)
+
+	// Ports are bound on start, so a race with another process shows up here
+	if output, err := docker("start", container); err != nil {
+		docker("rm", "-f", "-v", container)
+		if retry && ftptest.PortTaken(output) {
+			t.Logf("Passive ports %d-%d were taken, retrying with new ones", minPort, maxPort)
+			return ""
+		}
+		t.Fatalf("Failed to start mini-ftp: %v\n%s", err, output)
+	}
+	return container
+}
+
+// Addr returns the host:port of the control connection
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.opts.Host, strconv.Itoa(s.port))
+}
+
+// Client returns a client logged in as the given user. It is closed when
+// the test completes.
+func (s *Server) Client(username string) *goftp.Client {
+	s.t.Helper()
+
+	password, ok := s.opts.Users[username]
+	if !ok {
+		s.t.Fatalf("Unknown user: %s", username)
+	}
+
+	config := goftp.Config{
+		User:     username,
+		Password: password,
+		Timeout:  10 * time.Second,
+	}
+	if s.opts.TLS {
+		config.TLSConfig = &tls.Config{
+			ServerName:         s.opts.Host,
+			InsecureSkipVerify: true,
+		}
+		config.TLSMode = goftp.TLSExplicit
+	}
+
+	client, err := goftp.DialConfig(config, s.Addr())
+	if err != nil {
+		s.t.Fatalf("Failed to connect to FTP server as user %s: %v", username, err)
+	}
+	s.t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// Logs returns the container's logs without color codes
+func (s *Server) Logs() string {
+	s.t.Helper()
+
+	output, err := docker("logs", s.container)
+	if err != nil {
+		s.t.Fatalf("Failed to fetch logs: %v\n%s", err, output)
+	}
+	return stripAnsiCodes(output)
+}
+
+// Exec runs a command inside the container and returns its combined output
+func (s *Server) Exec(command ...string) (string, error) {
+	return docker(append([]string{"exec", s.container}, command...)...)
+}
+
+// ContainerName returns the ID of the server's container
+func (s *Server) ContainerName() string {
+	return s.container
+}
+
+// remove deletes the container and its anonymous volumes
+func (s *Server) remove() {
+	if os.Getenv("LOG_LEVEL") == "DEBUG" {
+		s.t.Logf("mini-ftp logs:\n%s", s.Logs())
+	}
+	if output, err := docker("rm", "-f", "-v", s.container); err != nil {
+		s.t.Errorf("Failed to remove container: %v\n%s", err, output)
+	}
+}
+
+// publishedPort returns the host port Docker picked for the control port
+func (s *Server) publishedPort(t testing.TB) int {
+	output, err := docker("port", s.container, "21/tcp")
+	if err != nil {
+		t.Fatalf("Failed to read published port: %v\n%s", err, output)
+	}
+
+	// docker port prints one line per binding, e.g. 127.0.0.1:49153
+	line := strings.TrimSpace(strings.Split(output, "\n")[0])
+	_, port, err := net.SplitHostPort(line)
+	if err != nil {
+		t.Fatalf("Unexpected docker port output: %q", output)
+	}
+	value, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("Unexpected docker port output: %q", output)
+	}
+	return value
+}
+
+// waitReady waits until the entrypoint has finished and the control port answers
+func (s *Server) waitReady(t testing.TB) {
+	deadline := time.Now().Add(s.opts.StartTimeout)
+	for time.Now().Before(deadline) {
+		running, _ := docker("inspect", "--format", "{{.State.Running}}", s.container)
+		if strings.TrimSpace(running) != "true" {
+			t.Fatalf("mini-ftp exited during startup:\n%s", s.Logs())
+		}
+
+		if _, err := s.Exec("test", "-f", "/var/run/ftp-ready"); err == nil {
+			conn, err := net.DialTimeout("tcp", s.Addr(), time.Second)
+			if err == nil {
+				conn.Close()
+				return
+			}
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	t.Fatalf("mini-ftp was not ready after %s:\n%s", s.opts.StartTimeout, s.Logs())
+}
+
+// prepareImage builds or pulls the image and returns its name
+func prepareImage(t testing.TB, opts Options) string {
+	t.Helper()
+
+	if opts.BuildContext != "" {
+		image := opts.Image
+		if image == "" {
+			image = "mini-ftp-testserver:" + opts.AlpineVersion
+		}
+		output, err := docker("build", "--build-arg", "ALPINE_VERSION="+opts.AlpineVersion, "-t", image, opts.BuildContext)
+		if err != nil {
+			t.Fatalf("Failed to build image from %s: %v\n%s", opts.BuildContext, err, output)
+		}
+		return image
+	}
+
+	image := opts.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	if _, err := docker("image", "inspect", image); err != nil {
+		if output, err := docker("pull", image); err != nil {
+			t.Fatalf("Failed to pull image %s: %v\n%s", image, err, output)
+		}
+	}
+	return image
+}
+
+// writeFile writes a file the container can read
+func writeFile(t testing.TB, path string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// docker runs a docker command and returns its combined output
+func docker(args ...string) (string, error) {
+	var output bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return output.String(), err
+}
+
+// sortedKeys returns the keys of m in order, so containers start the same way every run
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stripAnsiCodes removes ANSI escape codes from a string
+func stripAnsiCodes(input string) string {
+	re := regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	return re.ReplaceAllString(input, "")
+}